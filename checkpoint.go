@@ -0,0 +1,524 @@
+package neurvolve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const checkpointFilename = "checkpoint.json"
+const checkpointDirPrefix = "generation-"
+
+// The PopulationTrainer settings which are saved in a checkpoint.  Settings
+// which are functions or interfaces, such as the CortexMutator and the
+// Selector, cannot be saved and are taken from the trainer which resumes
+// the run.  The run's budget and how it is executed, which are the
+// FitnessThreshold, MaxGenerations, NumWorkers and CheckpointInterval,
+// are only used when the resuming trainer leaves them at 0.
+type PopulationTrainerConfig struct {
+	FitnessThreshold       float64
+	MaxGenerations         int
+	NumOpponents           int
+	NumWorkers             int
+	OffspringPerParent     int
+	NumElite               int
+	NumHallOfFameOpponents int
+	HallOfFameSize         int
+	CheckpointInterval     int
+	PopulationSize         int
+	CrossoverProbability   float64
+	MultiObjective         bool
+	RatingAsFitness        bool
+}
+
+// An EvaluatedCortex as stored in a checkpoint, with the cortex itself
+// saved to a separate json file.
+type CheckpointedCortex struct {
+	CortexFile          string
+	Fitness             float64
+	ParentId            string
+	CreatedInGeneration int
+	StepSize            float64
+}
+
+// A Species as stored in a checkpoint, without its members, which are
+// filled in again when the population is next speciated.
+type CheckpointedSpecies struct {
+	Id                  int
+	RepresentativeFile  string
+	BestFitness         float64
+	LastImproved        int
+	CreatedInGeneration int
+}
+
+// The progress of a StagnationCriterion
+type CheckpointedStagnation struct {
+	Best             float64
+	LastImproved     int
+	Started          bool
+	GenerationsBelow int
+	ExtraMutations   int
+}
+
+// The parent's fitness and the MutationPolicy operators which produced an
+// offspring that has yet to be evaluated
+type CheckpointedMutationCredit struct {
+	ParentFitness float64
+	Operators     []int
+}
+
+// Everything needed to continue a population training run.  The
+// FitnessCache is not saved, so a resumed run evaluates any cached
+// genotypes again, which gives them the same fitness on a deterministic
+// scape.  Lineage is passed on to the recorder as each generation is
+// bred, so there is none waiting to be recorded when a checkpoint is
+// taken.
+type PopulationCheckpoint struct {
+
+	// The generation the resumed run will start with
+	Generation int
+
+	Config PopulationTrainerConfig
+
+	// The seed of the trainer's random source and the number of values
+	// drawn from it so far.  The resumed run continues from the same
+	// point, so it makes the same random choices as the original.
+	RandomSeed  int64
+	RandomDraws int64
+
+	Population []CheckpointedCortex
+	HallOfFame []CheckpointedCortex
+
+	// The rating of every cortex, by uuid, if the trainer had Ratings
+	Ratings map[string]Rating
+
+	// The species, if the trainer had a Speciation, along with its
+	// adjusted threshold and the id of the next new species
+	Species                []CheckpointedSpecies
+	NextSpeciesId          int
+	CompatibilityThreshold float64
+
+	// The behaviors in the archive, if the trainer had a NoveltySearch
+	NoveltyArchive [][]float64
+
+	// The progress of each of the StagnationCriteria, and the initial
+	// population which is used for reseeding
+	Stagnation []CheckpointedStagnation
+	Seeds      []CheckpointedCortex
+
+	// The stats and quality estimates of the MutationPolicy, if the
+	// trainer had one, and the operators which produced each offspring in
+	// the population, by uuid
+	MutationStats   []MutationStats
+	MutationQuality []float64
+	MutationCredits map[string]CheckpointedMutationCredit
+}
+
+// Continue a run from the most recent checkpoint in checkpointDir.  The
+// trainer must have its CortexMutator (and Selector, Ratings, Speciation,
+// NoveltySearch, StagnationCriteria, MutationPolicy and SelfAdaptation,
+// if any) set, since those are not stored in the checkpoint.  Their state,
+// such as the ratings, species and mutation stats, is restored from the
+// checkpoint, along with the random source, so that the resumed run makes
+// the same choices as one which was never interrupted.
+//
+// The settings which define the population, such as the PopulationSize,
+// OffspringPerParent and NumElite, are taken from the checkpoint.  The
+// trainer's FitnessThreshold, MaxGenerations, NumWorkers and
+// CheckpointInterval are kept if they are set, so that a run can be
+// extended or moved to another machine, and are otherwise taken from the
+// checkpoint too.
+func (pt *PopulationTrainer) ResumeFromCheckpoint(checkpointDir string, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, succeeded bool, err error) {
+	trainedPopulation, stopReason, err := pt.ResumeFromCheckpointContext(context.Background(), checkpointDir, scape, recorder)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
+}
+
+// Same as ResumeFromCheckpoint, but stops at the next fitness evaluation
+// once ctx is done.
+func (pt *PopulationTrainer) ResumeFromCheckpointContext(ctx context.Context, checkpointDir string, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, stopReason StopReason, err error) {
+
+	latestDir, err := LatestCheckpoint(checkpointDir)
+	if err != nil {
+		return
+	}
+
+	checkpoint, evaldCortexes, hallOfFame, err := LoadCheckpoint(latestDir)
+	if err != nil {
+		return
+	}
+
+	pt.CheckpointDir = checkpointDir
+	pt.applyConfig(checkpoint.Config)
+	if checkpoint.Config.HallOfFameSize > 0 || len(hallOfFame) > 0 {
+		pt.HallOfFame = NewHallOfFame(checkpoint.Config.HallOfFameSize)
+		pt.HallOfFame.Add(hallOfFame)
+	}
+	if pt.Ratings != nil && checkpoint.Ratings != nil {
+		pt.Ratings.SetRatings(checkpoint.Ratings)
+	}
+	if err = pt.restoreCheckpointState(latestDir, checkpoint); err != nil {
+		return
+	}
+	pt.seedRandom(checkpoint.RandomSeed, checkpoint.RandomDraws)
+
+	logg.LogTo("NEURVOLVE", "Resuming from checkpoint %v at generation %v", latestDir, checkpoint.Generation)
+
+	if err = pt.validate(len(evaldCortexes), scape); err != nil {
+		return
+	}
+
+	trainedPopulation, stopReason, err = pt.train(ctx, evaldCortexes, checkpoint.Generation, scape, recorder)
+	return
+
+}
+
+// Restore the state of the trainer's species, novelty archive, stagnation
+// criteria and mutation policy.  State saved for any of them which the
+// trainer no longer has is ignored.
+func (pt *PopulationTrainer) restoreCheckpointState(dir string, checkpoint *PopulationCheckpoint) (err error) {
+
+	if pt.Speciation != nil {
+		species := make([]*Species, 0)
+		for _, checkpointedSpecies := range checkpoint.Species {
+			var representative *ng.Cortex
+			representative, err = loadCortexFromFile(filepath.Join(dir, checkpointedSpecies.RepresentativeFile))
+			if err != nil {
+				return
+			}
+			species = append(species, &Species{
+				Id:                  checkpointedSpecies.Id,
+				Representative:      representative,
+				Members:             make([]EvaluatedCortex, 0),
+				BestFitness:         checkpointedSpecies.BestFitness,
+				LastImproved:        checkpointedSpecies.LastImproved,
+				CreatedInGeneration: checkpointedSpecies.CreatedInGeneration,
+			})
+		}
+		pt.Speciation.restore(species, checkpoint.NextSpeciesId, checkpoint.CompatibilityThreshold)
+	}
+
+	if pt.NoveltySearch != nil {
+		pt.NoveltySearch.restoreArchive(checkpoint.NoveltyArchive)
+	}
+
+	pt.stagnation = nil
+	if len(checkpoint.Stagnation) == len(pt.StagnationCriteria) {
+		for _, saved := range checkpoint.Stagnation {
+			pt.stagnation = append(pt.stagnation, stagnationState{
+				best:             saved.Best,
+				lastImproved:     saved.LastImproved,
+				started:          saved.Started,
+				generationsBelow: saved.GenerationsBelow,
+				extraMutations:   saved.ExtraMutations,
+			})
+		}
+	}
+	pt.pendingReseed = 0
+	pt.seeds = nil
+	var seeds []EvaluatedCortex
+	if seeds, err = loadCheckpointedCortexes(dir, checkpoint.Seeds); err != nil {
+		return
+	}
+	for _, seed := range seeds {
+		pt.seeds = append(pt.seeds, seed.Cortex)
+	}
+
+	pt.mutationCredits = nil
+	if pt.MutationPolicy != nil {
+		pt.MutationPolicy.restore(checkpoint.MutationStats, checkpoint.MutationQuality)
+		for uuid, credit := range checkpoint.MutationCredits {
+			if pt.mutationCredits == nil {
+				pt.mutationCredits = make(map[string]mutationCredit)
+			}
+			pt.mutationCredits[uuid] = mutationCredit{
+				parentFitness: credit.ParentFitness,
+				operators:     credit.Operators,
+			}
+		}
+	}
+
+	return
+
+}
+
+// Find the most recent checkpoint saved in checkpointDir
+func LatestCheckpoint(checkpointDir string) (latestDir string, err error) {
+
+	entries, err := ioutil.ReadDir(checkpointDir)
+	if err != nil {
+		return
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), checkpointDirPrefix) {
+			continue
+		}
+		// a checkpoint is only complete once its checkpoint file is written
+		checkpointPath := filepath.Join(checkpointDir, entry.Name(), checkpointFilename)
+		if _, statErr := os.Stat(checkpointPath); statErr != nil {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+
+	if len(names) == 0 {
+		err = fmt.Errorf("No checkpoints found in %v", checkpointDir)
+		return
+	}
+
+	// the generation numbers are zero padded, so they sort as strings
+	sort.Strings(names)
+	latestDir = filepath.Join(checkpointDir, names[len(names)-1])
+	return
+
+}
+
+// Load a single checkpoint, as saved by the PopulationTrainer
+func LoadCheckpoint(dir string) (checkpoint *PopulationCheckpoint, population []EvaluatedCortex, hallOfFame []EvaluatedCortex, err error) {
+
+	jsonBytes, err := ioutil.ReadFile(filepath.Join(dir, checkpointFilename))
+	if err != nil {
+		return
+	}
+
+	checkpoint = &PopulationCheckpoint{}
+	if err = json.Unmarshal(jsonBytes, checkpoint); err != nil {
+		return
+	}
+
+	if population, err = loadCheckpointedCortexes(dir, checkpoint.Population); err != nil {
+		return
+	}
+	hallOfFame, err = loadCheckpointedCortexes(dir, checkpoint.HallOfFame)
+	return
+
+}
+
+func (pt *PopulationTrainer) shouldCheckpoint(generation int) bool {
+	return pt.CheckpointInterval > 0 && generation%pt.CheckpointInterval == 0
+}
+
+// Save the population which will be evaluated in the given generation,
+// along with everything else needed to resume the run from that point.
+func (pt *PopulationTrainer) saveCheckpoint(generation int, population []EvaluatedCortex) (err error) {
+
+	dirName := fmt.Sprintf("%v%08d", checkpointDirPrefix, generation)
+	dir := filepath.Join(pt.CheckpointDir, dirName)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	// make sure the random source has been set up, without drawing from it
+	pt.randomSource()
+
+	checkpoint := PopulationCheckpoint{
+		Generation:  generation,
+		Config:      pt.config(),
+		RandomSeed:  pt.source.seed,
+		RandomDraws: pt.source.draws,
+		HallOfFame:  []CheckpointedCortex{},
+	}
+	if checkpoint.Population, err = saveCheckpointedCortexes(dir, "cortex", population); err != nil {
+		return
+	}
+	if pt.HallOfFame != nil {
+		if checkpoint.HallOfFame, err = saveCheckpointedCortexes(dir, "halloffame", pt.HallOfFame.Members()); err != nil {
+			return
+		}
+	}
+	if pt.Ratings != nil {
+		checkpoint.Ratings = pt.Ratings.Ratings()
+	}
+	if err = pt.saveCheckpointState(dir, &checkpoint); err != nil {
+		return
+	}
+
+	jsonBytes, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return
+	}
+
+	// the checkpoint file is written last, since its presence is
+	// what marks the checkpoint as complete
+	err = ioutil.WriteFile(filepath.Join(dir, checkpointFilename), jsonBytes, 0644)
+	if err == nil {
+		logg.LogTo("NEURVOLVE", "Saved checkpoint for generation %v to %v", generation, dir)
+	}
+	return
+
+}
+
+// Add the state of the trainer's species, novelty archive, stagnation
+// criteria and mutation policy to the checkpoint
+func (pt *PopulationTrainer) saveCheckpointState(dir string, checkpoint *PopulationCheckpoint) (err error) {
+
+	if pt.Speciation != nil {
+		for _, species := range pt.Speciation.Species() {
+			var filename string
+			if filename, err = saveCortexFile(dir, "species", species.Representative); err != nil {
+				return
+			}
+			checkpoint.Species = append(checkpoint.Species, CheckpointedSpecies{
+				Id:                  species.Id,
+				RepresentativeFile:  filename,
+				BestFitness:         species.BestFitness,
+				LastImproved:        species.LastImproved,
+				CreatedInGeneration: species.CreatedInGeneration,
+			})
+		}
+		checkpoint.NextSpeciesId, checkpoint.CompatibilityThreshold = pt.Speciation.state()
+	}
+
+	if pt.NoveltySearch != nil {
+		checkpoint.NoveltyArchive = pt.NoveltySearch.Archive()
+	}
+
+	for _, state := range pt.stagnation {
+		checkpoint.Stagnation = append(checkpoint.Stagnation, CheckpointedStagnation{
+			Best:             state.best,
+			LastImproved:     state.lastImproved,
+			Started:          state.started,
+			GenerationsBelow: state.generationsBelow,
+			ExtraMutations:   state.extraMutations,
+		})
+	}
+	seeds := make([]EvaluatedCortex, 0)
+	for _, seed := range pt.seeds {
+		seeds = append(seeds, EvaluatedCortex{Cortex: seed, ParentId: seed.NodeId.UUID})
+	}
+	if checkpoint.Seeds, err = saveCheckpointedCortexes(dir, "seed", seeds); err != nil {
+		return
+	}
+
+	if pt.MutationPolicy != nil {
+		checkpoint.MutationStats, checkpoint.MutationQuality = pt.MutationPolicy.state()
+		checkpoint.MutationCredits = make(map[string]CheckpointedMutationCredit)
+		for uuid, credit := range pt.mutationCredits {
+			checkpoint.MutationCredits[uuid] = CheckpointedMutationCredit{
+				ParentFitness: credit.parentFitness,
+				Operators:     credit.operators,
+			}
+		}
+	}
+
+	return
+
+}
+
+func (pt *PopulationTrainer) config() PopulationTrainerConfig {
+	config := PopulationTrainerConfig{
+		FitnessThreshold:       pt.FitnessThreshold,
+		MaxGenerations:         pt.MaxGenerations,
+		NumOpponents:           pt.NumOpponents,
+		NumWorkers:             pt.NumWorkers,
+		OffspringPerParent:     pt.OffspringPerParent,
+		NumElite:               pt.NumElite,
+		NumHallOfFameOpponents: pt.NumHallOfFameOpponents,
+		CheckpointInterval:     pt.CheckpointInterval,
+		PopulationSize:         pt.populationSize,
+		CrossoverProbability:   pt.CrossoverProbability,
+		MultiObjective:         pt.MultiObjective,
+		RatingAsFitness:        pt.RatingAsFitness,
+	}
+	if pt.HallOfFame != nil {
+		config.HallOfFameSize = pt.HallOfFame.MaxSize
+	}
+	return config
+}
+
+// Apply the settings which define the population, and fill in the run's
+// budget and execution settings which the trainer has left at 0, so that
+// a run can be extended, or moved to another machine, when it is resumed
+func (pt *PopulationTrainer) applyConfig(config PopulationTrainerConfig) {
+	if pt.FitnessThreshold == 0 {
+		pt.FitnessThreshold = config.FitnessThreshold
+	}
+	if pt.MaxGenerations == 0 {
+		pt.MaxGenerations = config.MaxGenerations
+	}
+	if pt.NumWorkers == 0 {
+		pt.NumWorkers = config.NumWorkers
+	}
+	if pt.CheckpointInterval == 0 {
+		pt.CheckpointInterval = config.CheckpointInterval
+	}
+	pt.NumOpponents = config.NumOpponents
+	pt.OffspringPerParent = config.OffspringPerParent
+	pt.NumElite = config.NumElite
+	pt.NumHallOfFameOpponents = config.NumHallOfFameOpponents
+	pt.populationSize = config.PopulationSize
+	pt.CrossoverProbability = config.CrossoverProbability
+	pt.MultiObjective = config.MultiObjective
+	pt.RatingAsFitness = config.RatingAsFitness
+}
+
+func saveCheckpointedCortexes(dir string, prefix string, evaldCortexes []EvaluatedCortex) (checkpointedCortexes []CheckpointedCortex, err error) {
+
+	checkpointedCortexes = make([]CheckpointedCortex, 0)
+	for _, evaldCortex := range evaldCortexes {
+
+		var filename string
+		if filename, err = saveCortexFile(dir, prefix, evaldCortex.Cortex); err != nil {
+			return
+		}
+
+		checkpointedCortex := CheckpointedCortex{
+			CortexFile:          filename,
+			Fitness:             evaldCortex.Fitness,
+			ParentId:            evaldCortex.ParentId,
+			CreatedInGeneration: evaldCortex.CreatedInGeneration,
+			StepSize:            evaldCortex.StepSize,
+		}
+		checkpointedCortexes = append(checkpointedCortexes, checkpointedCortex)
+	}
+	return
+
+}
+
+// Save the cortex to a json file in dir, named after its uuid
+func saveCortexFile(dir string, prefix string, cortex *ng.Cortex) (filename string, err error) {
+	filename = fmt.Sprintf("%v-%v.json", prefix, cortex.NodeId.UUID)
+	err = cortex.MarshalJSONToFile(filepath.Join(dir, filename))
+	return
+}
+
+func loadCheckpointedCortexes(dir string, checkpointedCortexes []CheckpointedCortex) (evaldCortexes []EvaluatedCortex, err error) {
+
+	evaldCortexes = make([]EvaluatedCortex, 0)
+	for _, checkpointedCortex := range checkpointedCortexes {
+
+		cortex, loadErr := loadCortexFromFile(filepath.Join(dir, checkpointedCortex.CortexFile))
+		if loadErr != nil {
+			err = loadErr
+			return
+		}
+
+		evaldCortex := EvaluatedCortex{
+			Cortex:              cortex,
+			Fitness:             checkpointedCortex.Fitness,
+			ParentId:            checkpointedCortex.ParentId,
+			CreatedInGeneration: checkpointedCortex.CreatedInGeneration,
+			StepSize:            checkpointedCortex.StepSize,
+		}
+		evaldCortexes = append(evaldCortexes, evaldCortex)
+	}
+	return
+
+}
+
+func loadCortexFromFile(filename string) (cortex *ng.Cortex, err error) {
+	jsonBytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	return unmarshalCortex(jsonBytes)
+}
@@ -0,0 +1,233 @@
+package neurvolve
+
+import (
+	"encoding/json"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveAndLoadCheckpoint(t *testing.T) {
+
+	checkpointDir, err := ioutil.TempDir("", "neurvolve-checkpoint")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(checkpointDir)
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:   1000,
+		MaxGenerations:     50,
+		NumElite:           1,
+		HallOfFame:         NewHallOfFame(1),
+		CheckpointDir:      checkpointDir,
+		CheckpointInterval: 5,
+		populationSize:     2,
+	}
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 2.0, ParentId: "cortex1"},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 0.0, ParentId: "cortex1", CreatedInGeneration: 9, StepSize: 0.25},
+	}
+	pt.HallOfFame.Add(population)
+
+	assert.True(t, pt.shouldCheckpoint(5))
+	assert.False(t, pt.shouldCheckpoint(6))
+
+	assert.True(t, pt.saveCheckpoint(5, population) == nil)
+	assert.True(t, pt.saveCheckpoint(10, population) == nil)
+
+	latestDir, err := LatestCheckpoint(checkpointDir)
+	assert.True(t, err == nil)
+	assert.Equals(t, latestDir, filepath.Join(checkpointDir, "generation-00000010"))
+
+	checkpoint, loadedPopulation, hallOfFame, err := LoadCheckpoint(latestDir)
+	assert.True(t, err == nil)
+	assert.Equals(t, checkpoint.Generation, 10)
+	assert.Equals(t, checkpoint.Config.MaxGenerations, 50)
+	assert.Equals(t, checkpoint.Config.HallOfFameSize, 1)
+	assert.Equals(t, checkpoint.Config.PopulationSize, 2)
+
+	assert.Equals(t, len(loadedPopulation), 2)
+	assert.Equals(t, loadedPopulation[1].Cortex.NodeId.UUID, "cortex2")
+	assert.Equals(t, loadedPopulation[1].ParentId, "cortex1")
+	assert.Equals(t, loadedPopulation[1].CreatedInGeneration, 9)
+	assert.Equals(t, loadedPopulation[1].StepSize, 0.25)
+	assert.Equals(t, loadedPopulation[0].Fitness, 2.0)
+
+	assert.Equals(t, len(hallOfFame), 1)
+	assert.Equals(t, hallOfFame[0].Cortex.NodeId.UUID, "cortex1")
+
+}
+
+func TestLatestCheckpointMissing(t *testing.T) {
+
+	checkpointDir, err := ioutil.TempDir("", "neurvolve-checkpoint")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(checkpointDir)
+
+	// an incomplete checkpoint, with no checkpoint file, is ignored
+	err = os.MkdirAll(filepath.Join(checkpointDir, "generation-00000005"), 0755)
+	assert.True(t, err == nil)
+
+	_, err = LatestCheckpoint(checkpointDir)
+	assert.True(t, err != nil)
+
+}
+
+// A trainer with state that has to be carried over from a checkpoint:
+// species, stagnation criteria and an adaptive mutation policy
+func newResumableTrainer(checkpointDir string) *PopulationTrainer {
+	policy := NewMutationPolicy([]CortexMutator{AddBias, RemoveBias, MutateWeights, AddNeuronNonRecurrent})
+	policy.Adaptive = true
+	return &PopulationTrainer{
+		FitnessThreshold:   1000,
+		MaxGenerations:     8,
+		CheckpointDir:      checkpointDir,
+		CheckpointInterval: 4,
+		Speciation:         NewSpeciation(1.0),
+		MutationPolicy:     policy,
+		StagnationCriteria: []StagnationCriterion{
+			{Measure: BestFitnessStagnation, Generations: 2, Action: ReseedOnStagnation},
+			{Measure: MeanFitnessStagnation, Generations: 2, Action: IncreaseMutationOnStagnation},
+		},
+	}
+}
+
+func marshalTrainedPopulation(t *testing.T, trainedPopulation []EvaluatedCortex) string {
+	cortexes := []*ng.Cortex{}
+	for _, evaldCortex := range trainedPopulation {
+		cortexes = append(cortexes, evaldCortex.Cortex)
+	}
+	bytes, err := json.Marshal(cortexes)
+	assert.True(t, err == nil)
+	return string(bytes)
+}
+
+func TestResumeFromCheckpointMatchesUninterruptedRun(t *testing.T) {
+
+	checkpointDir, err := ioutil.TempDir("", "neurvolve-checkpoint")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(checkpointDir)
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		SingleNeuronCortex("cortex3"),
+		SingleNeuronCortex("cortex4"),
+		SingleNeuronCortex("cortex5"),
+		SingleNeuronCortex("cortex6"),
+	}
+
+	pt := newResumableTrainer(checkpointDir)
+	pt.Rand = rand.New(rand.NewSource(42))
+	uninterrupted, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	// pretend the run was interrupted after the checkpoint halfway through
+	err = os.RemoveAll(filepath.Join(checkpointDir, "generation-00000008"))
+	assert.True(t, err == nil)
+
+	// a new trainer, without a Rand, picks up where the run left off
+	resumed, _, err := newResumableTrainer(checkpointDir).ResumeFromCheckpoint(checkpointDir, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	assert.Equals(t, marshalTrainedPopulation(t, resumed), marshalTrainedPopulation(t, uninterrupted))
+
+}
+
+func TestCheckpointedCortexesCanBeRunAndMutated(t *testing.T) {
+
+	checkpointDir, err := ioutil.TempDir("", "neurvolve-checkpoint")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(checkpointDir)
+
+	examples := ng.XnorTrainingSamples()
+	scape := &TrainingSampleScape{examples: examples}
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:   1000,
+		MaxGenerations:     2,
+		CortexMutator:      NoOpMutator,
+		CheckpointDir:      checkpointDir,
+		CheckpointInterval: 1,
+	}
+	population := []*ng.Cortex{ng.XnorCortex(), ng.XnorCortexUntrained()}
+	fitnesses := map[float64]bool{}
+	for _, cortex := range population {
+		fitnesses[cortex.Fitness(examples)] = true
+	}
+	_, _, err = pt.Train(population, scape, NullRecorder{})
+	assert.True(t, err == nil)
+
+	latestDir, err := LatestCheckpoint(checkpointDir)
+	assert.True(t, err == nil)
+	_, loaded, _, err := LoadCheckpoint(latestDir)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(loaded), 2)
+
+	random := newRandom()
+	for _, evaldCortex := range loaded {
+
+		cortex := evaldCortex.Cortex
+		for _, neuron := range cortex.Neurons {
+			assert.True(t, neuron.Cortex == cortex)
+		}
+
+		// the mutator changes nothing, so the loaded cortex runs just as
+		// one of the originals did
+		assert.True(t, fitnesses[cortex.Fitness(examples)])
+
+		// and can be mutated and run again
+		cortex.Init()
+		_, err = ApplyMutator(random, cortex, AddNeuronNonRecurrent)
+		assert.True(t, err == nil)
+		assert.True(t, cortex.Validate())
+		cortex.Fitness(examples)
+	}
+
+}
+
+func TestResumeFromCheckpointExtendsRun(t *testing.T) {
+
+	checkpointDir, err := ioutil.TempDir("", "neurvolve-checkpoint")
+	assert.True(t, err == nil)
+	defer os.RemoveAll(checkpointDir)
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:   1000,
+		MaxGenerations:     4,
+		NumWorkers:         2,
+		NumElite:           1,
+		CortexMutator:      NoOpMutator,
+		CheckpointDir:      checkpointDir,
+		CheckpointInterval: 4,
+	}
+	_, _, err = pt.Train(biasPopulation("cortex", 1, 2, 3), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	// settings the resuming trainer leaves at 0 come from the checkpoint
+	resumed := &PopulationTrainer{CortexMutator: NoOpMutator}
+	_, _, err = resumed.ResumeFromCheckpoint(checkpointDir, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, resumed.MaxGenerations, 4)
+	assert.Equals(t, resumed.NumWorkers, 2)
+	assert.Equals(t, resumed.CheckpointInterval, 4)
+	assert.Equals(t, resumed.NumElite, 1)
+
+	// but the run can be given more generations, and run on one worker
+	extended := &PopulationTrainer{
+		MaxGenerations: 6,
+		NumWorkers:     1,
+		CortexMutator:  NoOpMutator,
+	}
+	_, _, err = extended.ResumeFromCheckpoint(checkpointDir, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, extended.MaxGenerations, 6)
+	assert.Equals(t, extended.NumWorkers, 1)
+	assert.Equals(t, extended.FitnessThreshold, 1000.0)
+	assert.Equals(t, extended.CurrentGeneration, 5)
+
+}
@@ -0,0 +1,321 @@
+package neurvolve
+
+import (
+	"context"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+)
+
+// The island ids used when passing generations and fitness scores to a
+// Recorder which implements IslandRecorder
+const (
+	HostPopulationId     = 0
+	ParasitePopulationId = 1
+)
+
+// Competitive co-evolution of two populations, hosts and parasites, in
+// the style of Rosin and Belew.  Each host is scored against a sample of
+// the parasites and each parasite against a sample of the hosts, so each
+// population is always being tested against an improving opponent.
+//
+// Each population is bred by its own PopulationTrainer, which supplies
+// the CortexMutator, Selector, HallOfFame and so on.  Their NumOpponents
+// and MaxGenerations are ignored, and they cannot use MultiObjective or
+// NoveltySearch.  Training stops when a host exceeds the FitnessThreshold
+// of the Hosts trainer.
+type CoevolutionTrainer struct {
+	Hosts          *PopulationTrainer
+	Parasites      *PopulationTrainer
+	MaxGenerations int
+
+	// The number of members of the other population each cortex faces
+	SampleSize int
+
+	// If set, the sample is chosen by shared sampling: opponents from the
+	// previous generation which beat many cortexes, especially cortexes
+	// that few other opponents beat, are preferred over random ones.
+	SharedSampling bool
+
+	// If set, beating an opponent is worth 1 divided by the number of
+	// cortexes in the population that beat it, and the fitness of a cortex
+	// is the total for the opponents it beat.  This rewards cortexes that
+	// can beat opponents that others can't.  Otherwise the fitness of a
+	// cortex is its average score.
+	CompetitiveFitnessSharing bool
+
+	// A score above this counts as a win.  Used by shared sampling and
+	// competitive fitness sharing.
+	WinThreshold float64
+
+	// The number of members of the other population's hall of fame each
+	// cortex also faces, if the other trainer has a HallOfFame
+	NumHallOfFameOpponents int
+
+	// If set, the samples are drawn from Rand, along with the random
+	// choices of the Hosts and Parasites trainers if they have no Rand of
+	// their own, so that runs with the same seed are reproducible.
+	Rand *rand.Rand
+}
+
+// The scores of each member of a population against its opponents
+type matchResults struct {
+	population []EvaluatedCortex
+	opponents  [][]*ng.Cortex
+	scores     [][]float64
+}
+
+func (ct *CoevolutionTrainer) Train(hosts []*ng.Cortex, parasites []*ng.Cortex, scape Scape, recorder Recorder) (trainedHosts []EvaluatedCortex, trainedParasites []EvaluatedCortex, succeeded bool, err error) {
+
+	trainedHosts, trainedParasites, stopReason, err := ct.TrainContext(context.Background(), hosts, parasites, scape, recorder)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
+
+}
+
+// Same as Train, but stops at the next round of matches once ctx is done.
+// Returns the most recently evaluated hosts and parasites, fittest first.
+func (ct *CoevolutionTrainer) TrainContext(ctx context.Context, hosts []*ng.Cortex, parasites []*ng.Cortex, scape Scape, recorder Recorder) (trainedHosts []EvaluatedCortex, trainedParasites []EvaluatedCortex, stopReason StopReason, err error) {
+
+	if err = ct.validate(scape); err != nil {
+		return
+	}
+
+	random := randomOrNew(ct.Rand)
+
+	hostRecorder := islandRecorder{recorder, HostPopulationId}
+	parasiteRecorder := islandRecorder{recorder, ParasitePopulationId}
+
+	hostPopulation, err := ct.Hosts.start(hosts, trainerRandom(random, ct.Hosts.Rand), scape, hostRecorder)
+	if err != nil {
+		err = fmt.Errorf("hosts: %w", err)
+		return
+	}
+	parasitePopulation, err := ct.Parasites.start(parasites, trainerRandom(random, ct.Parasites.Rand), scape, parasiteRecorder)
+	if err != nil {
+		err = fmt.Errorf("parasites: %w", err)
+		return
+	}
+	trainedHosts, trainedParasites = hostPopulation, parasitePopulation
+
+	var hostResults, parasiteResults *matchResults
+	for generation := 0; generation < ct.MaxGenerations; generation++ {
+
+		ct.Hosts.CurrentGeneration = generation
+		ct.Parasites.CurrentGeneration = generation
+		ct.Hosts.publishSnapshot(hostPopulation)
+		ct.Parasites.publishSnapshot(parasitePopulation)
+
+		// both samples are based on the previous generation's results
+		hostSample := ct.chooseSample(random, parasitePopulation, parasiteResults)
+		parasiteSample := ct.chooseSample(random, hostPopulation, hostResults)
+
+		hostResults, err = ct.playMatches(ctx, random, ct.Hosts, hostPopulation, hostSample, ct.Parasites.HallOfFame, scape, hostRecorder)
+		if err == nil {
+			parasiteResults, err = ct.playMatches(ctx, random, ct.Parasites, parasitePopulation, parasiteSample, ct.Hosts.HallOfFame, scape, parasiteRecorder)
+		}
+		if err != nil {
+			if stopReason = contextStopReason(ctx); stopReason != 0 {
+				logg.LogTo("NEURVOLVE", "Stopping in generation %v: %v", generation, stopReason)
+				err = nil
+			}
+			return
+		}
+
+		trainedHosts = ct.Hosts.sortByFitness(ct.scoreResults(hostResults))
+		trainedParasites = ct.Parasites.sortByFitness(ct.scoreResults(parasiteResults))
+
+		if ct.Hosts.HallOfFame != nil {
+			ct.Hosts.HallOfFame.Add(trainedHosts)
+		}
+		if ct.Parasites.HallOfFame != nil {
+			ct.Parasites.HallOfFame.Add(trainedParasites)
+		}
+
+		if ct.Hosts.exceededFitnessThreshold(trainedHosts) {
+			stopReason = StopReasonThresholdReached
+			return
+		}
+
+		hostPopulation, err = ct.Hosts.breedNextGeneration(trainedHosts, generation, hostRecorder)
+		if err != nil {
+			err = fmt.Errorf("hosts: %w", err)
+			return
+		}
+		parasitePopulation, err = ct.Parasites.breedNextGeneration(trainedParasites, generation, parasiteRecorder)
+		if err != nil {
+			err = fmt.Errorf("parasites: %w", err)
+			return
+		}
+	}
+
+	stopReason = StopReasonBudgetExhausted
+	return
+
+}
+
+// Choose the sample of opponents from the opponent population.  With
+// shared sampling and results from the previous generation, the opponents
+// are chosen from the previous generation.  Otherwise they are chosen at
+// random from the current one.
+func (ct *CoevolutionTrainer) chooseSample(random *rand.Rand, opponentPopulation []EvaluatedCortex, previousResults *matchResults) (sample []*ng.Cortex) {
+
+	if ct.SharedSampling && previousResults != nil {
+		return ct.sharedSample(previousResults)
+	}
+
+	sampleSize := ct.SampleSize
+	if sampleSize > len(opponentPopulation) {
+		sampleSize = len(opponentPopulation)
+	}
+
+	sample = make([]*ng.Cortex, 0)
+	for _, i := range random.Perm(len(opponentPopulation))[:sampleSize] {
+		sample = append(sample, opponentPopulation[i].Cortex)
+	}
+	return
+
+}
+
+// Greedily choose the opponents whose wins are worth the most, where a
+// win against a cortex is worth less for every opponent already in the
+// sample that beat it too.  previousResults are the opponents' results,
+// so their "opponents" are the cortexes they have to beat.
+func (ct *CoevolutionTrainer) sharedSample(previousResults *matchResults) (sample []*ng.Cortex) {
+
+	sampleSize := ct.SampleSize
+	if sampleSize > len(previousResults.population) {
+		sampleSize = len(previousResults.population)
+	}
+
+	timesBeaten := make(map[string]int)
+	chosen := make([]bool, len(previousResults.population))
+	sample = make([]*ng.Cortex, 0)
+
+	for len(sample) < sampleSize {
+
+		best := -1
+		bestValue := -1.0
+		for i := range previousResults.population {
+			if chosen[i] {
+				continue
+			}
+			value := 0.0
+			for j, beaten := range previousResults.opponents[i] {
+				if previousResults.scores[i][j] > ct.WinThreshold {
+					value += 1.0 / float64(1+timesBeaten[beaten.NodeId.UUID])
+				}
+			}
+			if value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+
+		chosen[best] = true
+		sample = append(sample, previousResults.population[best].Cortex)
+		for j, beaten := range previousResults.opponents[best] {
+			if previousResults.scores[best][j] > ct.WinThreshold {
+				timesBeaten[beaten.NodeId.UUID] += 1
+			}
+		}
+	}
+	return
+
+}
+
+// Play every member of the population against the sample, along with
+// opponents from the other population's hall of fame
+func (ct *CoevolutionTrainer) playMatches(ctx context.Context, random *rand.Rand, pt *PopulationTrainer, population []EvaluatedCortex, sample []*ng.Cortex, hallOfFame *HallOfFame, scape Scape, recorder Recorder) (results *matchResults, err error) {
+
+	jobs := make([]*fitnessJob, len(population))
+	for i, evaldCortex := range population {
+		opponents := append([]*ng.Cortex{}, sample...)
+		if hallOfFame != nil && ct.NumHallOfFameOpponents > 0 {
+			opponents = append(opponents, hallOfFame.chooseRandomOpponents(random, evaldCortex.Cortex, ct.NumHallOfFameOpponents)...)
+		}
+		jobs[i] = &fitnessJob{
+			cortex:    evaldCortex.Cortex,
+			opponents: opponents,
+		}
+	}
+
+	if err = pt.runFitnessJobs(ctx, jobs, scape); err != nil {
+		return
+	}
+
+	results = &matchResults{
+		population: population,
+		opponents:  make([][]*ng.Cortex, len(jobs)),
+		scores:     make([][]float64, len(jobs)),
+	}
+	for i, job := range jobs {
+		for j, opponent := range job.opponents {
+			recorder.AddFitnessScore(job.scores[j], job.cortex, opponent)
+		}
+		results.opponents[i] = job.opponents
+		results.scores[i] = job.scores
+	}
+	return
+
+}
+
+// The population with the fitness of each member based on its results
+func (ct *CoevolutionTrainer) scoreResults(results *matchResults) (evaluated []EvaluatedCortex) {
+
+	// how many members of the population beat each opponent.  Opponents
+	// from a hall of fame are copies, so they are counted by uuid.
+	numWinners := make(map[string]int)
+	for i := range results.population {
+		for j, opponent := range results.opponents[i] {
+			if results.scores[i][j] > ct.WinThreshold {
+				numWinners[opponent.NodeId.UUID] += 1
+			}
+		}
+	}
+
+	evaluated = make([]EvaluatedCortex, len(results.population))
+	for i, evaldCortex := range results.population {
+
+		fitness := 0.0
+		if ct.CompetitiveFitnessSharing {
+			for j, opponent := range results.opponents[i] {
+				if results.scores[i][j] > ct.WinThreshold {
+					fitness += 1.0 / float64(numWinners[opponent.NodeId.UUID])
+				}
+			}
+		} else if len(results.scores[i]) > 0 {
+			fitness = ng.Average(results.scores[i])
+		}
+
+		evaluated[i] = EvaluatedCortex{
+			Cortex:              evaldCortex.Cortex,
+			ParentId:            evaldCortex.ParentId,
+			CreatedInGeneration: evaldCortex.CreatedInGeneration,
+			Fitness:             fitness,
+			StepSize:            evaldCortex.StepSize,
+		}
+	}
+	return
+
+}
+
+func (ct *CoevolutionTrainer) validate(scape Scape) error {
+
+	if ct.Hosts == nil || ct.Parasites == nil {
+		return fmt.Errorf("%w: need both a Hosts and a Parasites trainer", ErrInvalidConfig)
+	}
+	if ct.SampleSize < 1 {
+		return fmt.Errorf("%w: SampleSize must be at least 1", ErrInvalidConfig)
+	}
+	if !supportsFitnessAgainst(scape) {
+		return ErrFitnessAgainstUnsupported
+	}
+	for _, pt := range []*PopulationTrainer{ct.Hosts, ct.Parasites} {
+		if pt.MultiObjective || pt.NoveltySearch != nil {
+			return fmt.Errorf("%w: co-evolution cannot use MultiObjective or NoveltySearch", ErrInvalidConfig)
+		}
+	}
+	return nil
+
+}
@@ -0,0 +1,133 @@
+package neurvolve
+
+import (
+	"errors"
+	"fmt"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func biasPopulation(name string, biases ...float64) []*ng.Cortex {
+	population := []*ng.Cortex{}
+	for i, bias := range biases {
+		cortex := SingleNeuronCortex(fmt.Sprintf("%v-%v", name, i))
+		cortex.Neurons[0].Bias = bias
+		population = append(population, cortex)
+	}
+	return population
+}
+
+func newCoevolutionTrainer() *CoevolutionTrainer {
+	return &CoevolutionTrainer{
+		Hosts: &PopulationTrainer{
+			FitnessThreshold: 1000,
+			CortexMutator:    NoOpMutator,
+		},
+		Parasites: &PopulationTrainer{
+			FitnessThreshold: 1000,
+			CortexMutator:    NoOpMutator,
+		},
+		MaxGenerations: 3,
+		SampleSize:     2,
+	}
+}
+
+func TestCompetitiveFitnessSharing(t *testing.T) {
+
+	ct := newCoevolutionTrainer()
+	ct.CompetitiveFitnessSharing = true
+
+	hosts := (&PopulationTrainer{}).addEmptyFitnessScores(biasPopulation("host", 0, 1))
+	parasites := biasPopulation("parasite", 0, 1)
+
+	// host 1 beats both parasites, host 0 beats neither
+	results := &matchResults{
+		population: hosts,
+		opponents:  [][]*ng.Cortex{parasites, parasites},
+		scores:     [][]float64{{-1, -1}, {1, 1}},
+	}
+	evaluated := ct.scoreResults(results)
+	assert.Equals(t, evaluated[0].Fitness, 0.0)
+	assert.Equals(t, evaluated[1].Fitness, 2.0)
+
+	// now both hosts beat parasite 0, and only host 1 beats parasite 1
+	results.scores = [][]float64{{1, -1}, {1, 1}}
+	evaluated = ct.scoreResults(results)
+	assert.Equals(t, evaluated[0].Fitness, 0.5)
+	assert.Equals(t, evaluated[1].Fitness, 1.5)
+
+}
+
+func TestCompetitiveFitnessSharingCopiedOpponents(t *testing.T) {
+
+	ct := newCoevolutionTrainer()
+	ct.CompetitiveFitnessSharing = true
+
+	hosts := (&PopulationTrainer{}).addEmptyFitnessScores(biasPopulation("host", 0, 1))
+	parasite := biasPopulation("parasite", 0)[0]
+
+	// each host plays its own copy of the parasite, as with hall of fame
+	// opponents, and both win
+	results := &matchResults{
+		population: hosts,
+		opponents:  [][]*ng.Cortex{{parasite.Copy()}, {parasite.Copy()}},
+		scores:     [][]float64{{1}, {1}},
+	}
+	evaluated := ct.scoreResults(results)
+	assert.Equals(t, evaluated[0].Fitness, 0.5)
+	assert.Equals(t, evaluated[1].Fitness, 0.5)
+
+}
+
+func TestSharedSampling(t *testing.T) {
+
+	random := newRandom()
+
+	ct := newCoevolutionTrainer()
+	ct.SharedSampling = true
+	ct.SampleSize = 2
+
+	parasites := (&PopulationTrainer{}).addEmptyFitnessScores(biasPopulation("parasite", 0, 1, 2))
+	hosts := biasPopulation("host", 0, 1, 2, 3)
+
+	// parasites 0 and 1 beat the same two hosts, parasite 2 beats the
+	// other two.  parasite 2 complements parasite 0 better.
+	results := &matchResults{
+		population: parasites,
+		opponents:  [][]*ng.Cortex{hosts, hosts, hosts},
+		scores:     [][]float64{{1, 1, -1, -1}, {1, 1, -1, -1}, {-1, -1, 1, 1}},
+	}
+	sample := ct.chooseSample(random, nil, results)
+	assert.Equals(t, len(sample), 2)
+	assert.Equals(t, sample[0], parasites[0].Cortex)
+	assert.Equals(t, sample[1], parasites[2].Cortex)
+
+}
+
+func TestCoevolutionTrainer(t *testing.T) {
+
+	ct := newCoevolutionTrainer()
+	ct.SharedSampling = true
+	ct.CompetitiveFitnessSharing = true
+	ct.NumHallOfFameOpponents = 1
+	ct.Hosts.HallOfFame = NewHallOfFame(2)
+	ct.Parasites.HallOfFame = NewHallOfFame(2)
+
+	hosts := biasPopulation("host", 0, 1, 2, 3)
+	parasites := biasPopulation("parasite", 0, 1, 2, 3)
+
+	recorder := &FakeIslandRecorder{generations: make(map[int]int)}
+	trainedHosts, trainedParasites, succeeded, err := ct.Train(hosts, parasites, FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.False(t, succeeded)
+	assert.Equals(t, len(trainedHosts), 4)
+	assert.Equals(t, len(trainedParasites), 4)
+	assert.Equals(t, recorder.generations[HostPopulationId], 4)
+	assert.Equals(t, recorder.generations[ParasitePopulationId], 4)
+	assert.Equals(t, ct.Hosts.HallOfFame.Len(), 2)
+
+	_, _, _, err = ct.Train(hosts, parasites, TrainingSampleScape{}, recorder)
+	assert.True(t, errors.Is(err, ErrFitnessAgainstUnsupported))
+
+}
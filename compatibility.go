@@ -0,0 +1,124 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math"
+)
+
+// How much each kind of difference between two genotypes contributes
+// to their compatibility distance
+type CompatibilityCoefficients struct {
+
+	// Multiplies the fraction of connections which only one of the
+	// two cortexes has
+	Disjoint float64
+
+	// Multiplies the mean absolute weight difference of the
+	// connections which both cortexes have
+	Weight float64
+
+	// Multiplies the fraction of shared neurons which have a different
+	// activation function in each cortex
+	Activation float64
+}
+
+var DefaultCompatibilityCoefficients = CompatibilityCoefficients{
+	Disjoint:   1.0,
+	Weight:     0.4,
+	Activation: 1.0,
+}
+
+// The compatibility distance between two cortexes, in the style of NEAT.
+// Offspring keep the node ids of their parents, so node ids play the role
+// of NEAT's innovation numbers: a connection is identified by the ids of
+// the nodes at either end, and a neuron by its own id.  Identical cortexes
+// have a distance of 0.
+func CompatibilityDistance(cortex, other *ng.Cortex, coefficients CompatibilityCoefficients) float64 {
+
+	connections := inboundWeightsByConnection(cortex)
+	otherConnections := inboundWeightsByConnection(other)
+
+	numDisjoint := 0
+	numMatching := 0
+	totalWeightDifference := 0.0
+	for key, weights := range connections {
+		otherWeights, ok := otherConnections[key]
+		if !ok {
+			numDisjoint += 1
+			continue
+		}
+		numMatching += 1
+		totalWeightDifference += meanWeightDifference(weights, otherWeights)
+	}
+	for key := range otherConnections {
+		if _, ok := connections[key]; !ok {
+			numDisjoint += 1
+		}
+	}
+
+	numConnections := math.Max(float64(len(connections)), float64(len(otherConnections)))
+	numConnections = math.Max(numConnections, 1)
+
+	weightDifference := 0.0
+	if numMatching > 0 {
+		weightDifference = totalWeightDifference / float64(numMatching)
+	}
+
+	numShared := 0
+	numDifferentActivations := 0
+	for _, neuron := range cortex.Neurons {
+		otherNeuron := other.FindNeuron(neuron.NodeId)
+		if otherNeuron == nil {
+			continue
+		}
+		numShared += 1
+		if activationName(neuron) != activationName(otherNeuron) {
+			numDifferentActivations += 1
+		}
+	}
+
+	activationDifference := 0.0
+	if numShared > 0 {
+		activationDifference = float64(numDifferentActivations) / float64(numShared)
+	}
+
+	return coefficients.Disjoint*float64(numDisjoint)/numConnections +
+		coefficients.Weight*weightDifference +
+		coefficients.Activation*activationDifference
+
+}
+
+// The weights of every inbound connection to a neuron in the cortex,
+// keyed by the ids of the nodes at either end of the connection
+func inboundWeightsByConnection(cortex *ng.Cortex) map[string][]float64 {
+	connections := make(map[string][]float64)
+	for _, neuron := range cortex.Neurons {
+		for _, inbound := range neuron.Inbound {
+			key := inbound.NodeId.UUID + "->" + neuron.NodeId.UUID
+			connections[key] = inbound.Weights
+		}
+	}
+	return connections
+}
+
+func meanWeightDifference(weights, otherWeights []float64) float64 {
+	length := len(weights)
+	if len(otherWeights) < length {
+		length = len(otherWeights)
+	}
+	if length == 0 {
+		return 0
+	}
+	total := 0.0
+	for i := 0; i < length; i++ {
+		total += math.Abs(weights[i] - otherWeights[i])
+	}
+	return total / float64(length)
+}
+
+func activationName(neuron *ng.Neuron) string {
+	if neuron.ActivationFunction == nil {
+		return ""
+	}
+	return neuron.ActivationFunction.Name
+}
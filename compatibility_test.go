@@ -0,0 +1,62 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestCompatibilityDistanceIdentical(t *testing.T) {
+
+	cortex := BasicCortex()
+	distance := CompatibilityDistance(cortex, cortex.Copy(), DefaultCompatibilityCoefficients)
+	assert.Equals(t, distance, 0.0)
+
+}
+
+func TestCompatibilityDistanceWeights(t *testing.T) {
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	for _, neuron := range other.Neurons {
+		if neuron.NodeId.UUID == "hidden-neuron2" {
+			neuron.Inbound[0].Weights = []float64{2}
+		}
+	}
+
+	// one of the four connections differs by 1
+	coefficients := CompatibilityCoefficients{Weight: 1.0}
+	distance := CompatibilityDistance(cortex, other, coefficients)
+	assert.Equals(t, distance, 0.25)
+
+}
+
+func TestCompatibilityDistanceActivation(t *testing.T) {
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	other.Neurons[0].ActivationFunction = ng.EncodableTanh()
+
+	coefficients := CompatibilityCoefficients{Activation: 1.0}
+	distance := CompatibilityDistance(cortex, other, coefficients)
+	assert.Equals(t, distance, 0.25)
+
+}
+
+func TestCompatibilityDistanceDisjoint(t *testing.T) {
+
+	random := newRandom()
+
+	// no connections in common: 4 in one and 1 in the other
+	coefficients := CompatibilityCoefficients{Disjoint: 1.0}
+	distance := CompatibilityDistance(BasicCortex(), SingleNeuronCortex("single"), coefficients)
+	assert.Equals(t, distance, 1.25)
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	ok, _ := AddNeuronNonRecurrent(random, other)
+	assert.True(t, ok)
+	distance = CompatibilityDistance(cortex, other, coefficients)
+	assert.True(t, distance > 0)
+
+}
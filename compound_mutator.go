@@ -0,0 +1,126 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"math/rand"
+)
+
+// Draws the number of mutations a CompoundMutator applies to a cortex
+type MutationCount func(random *rand.Rand, cortex *ng.Cortex) int
+
+// A number of mutations drawn uniformly from 1 to the number of neurons
+// raised to the exponent, rounded.  An exponent of 0.5 gives the square
+// root of the number of neurons, as in DXNN, so bigger networks get
+// proportionally more change.
+func NeuronPowerMutationCount(exponent float64) MutationCount {
+	return func(random *rand.Rand, cortex *ng.Cortex) int {
+		max := int(math.Round(math.Pow(float64(len(cortex.Neurons)), exponent)))
+		if max < 1 {
+			max = 1
+		}
+		return RandomIntInRange(random, 1, max+1)
+	}
+}
+
+// Always the same number of mutations
+func FixedMutationCount(n int) MutationCount {
+	return func(random *rand.Rand, cortex *ng.Cortex) int {
+		return n
+	}
+}
+
+// Applies a random number of mutations to a cortex, each by an operator
+// chosen by the Policy.  Each mutation is made to a scratch copy of the
+// cortex, which has to validate before the next one is made, and the
+// cortex itself is only changed once they have all been made.  Each
+// attempt is a single operator, and operators which fail, panic or leave
+// the cortex invalid are retried with another operator, up to MaxAttempts
+// attempts in all.  The Policy's own MaxAttempts is not used, and an
+// operator that leaves the cortex invalid is recorded as failing in its
+// stats.  The Policy must be set.
+//
+// Its Mutate method is a CortexMutator whose MutationRecord lists the
+// record of each mutation it made, in order, as its Mutations.
+type CompoundMutator struct {
+	Policy *MutationPolicy
+
+	// Defaults to NeuronPowerMutationCount(0.5)
+	NumMutations MutationCount
+
+	// 0 means DEFAULT_MUTATION_ATTEMPTS
+	MaxAttempts int
+}
+
+// A DXNN style compound mutator, which applies between 1 and the square
+// root of the number of neurons mutations chosen by the policy
+func NewCompoundMutator(policy *MutationPolicy) *CompoundMutator {
+	return &CompoundMutator{
+		Policy:       policy,
+		NumMutations: NeuronPowerMutationCount(0.5),
+	}
+}
+
+// Apply the mutations to the cortex.  Fails, leaving the cortex
+// unchanged, if not a single mutation could be made.
+func (m *CompoundMutator) Mutate(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+
+	if m.Policy == nil || len(m.Policy.Operators) == 0 {
+		logg.LogTo("NEURVOLVE", "CompoundMutator has no Policy operators, unable to mutate")
+		return false, nil
+	}
+
+	numMutations := m.numMutations(random, cortex)
+	record := newMutationRecord("CompoundMutator")
+	scratch := cortex.Copy()
+
+	for attempts := 0; len(record.Mutations) < numMutations && attempts < m.maxAttempts(); attempts++ {
+
+		attempt := scratch.Copy()
+
+		// before we mutate the cortex, we need to init it,
+		// otherwise things like Outsplice will fail because
+		// there are no DataChan's.
+		attempt.Init()
+
+		_, mutation, err := m.Policy.attempt(random, attempt)
+		if err != nil {
+			logg.LogTo("NEURVOLVE", "Compound mutation failed, retrying... %v", err)
+			continue
+		}
+
+		scratch = attempt
+		record.addMutation(mutation)
+
+	}
+
+	if len(record.Mutations) == 0 {
+		return false, nil
+	}
+	if len(record.Mutations) < numMutations {
+		logg.LogTo("NEURVOLVE", "Only made %d of %d mutations", len(record.Mutations), numMutations)
+	}
+
+	cortex.SetSensors(scratch.Sensors)
+	cortex.SetNeurons(scratch.Neurons)
+	cortex.SetActuators(scratch.Actuators)
+
+	return true, record
+
+}
+
+func (m *CompoundMutator) numMutations(random *rand.Rand, cortex *ng.Cortex) int {
+	numMutations := NeuronPowerMutationCount(0.5)
+	if m.NumMutations != nil {
+		numMutations = m.NumMutations
+	}
+	return numMutations(random, cortex)
+}
+
+func (m *CompoundMutator) maxAttempts() int {
+	if m.MaxAttempts <= 0 {
+		return DEFAULT_MUTATION_ATTEMPTS
+	}
+	return m.MaxAttempts
+}
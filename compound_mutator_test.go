@@ -0,0 +1,127 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"testing"
+)
+
+func panickingMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	panic("broken mutator")
+}
+
+func TestNeuronPowerMutationCount(t *testing.T) {
+
+	random := newRandom()
+
+	// BasicCortex has 4 neurons, so sqrt gives at most 2 mutations
+	cortex := BasicCortex()
+	mutationCount := NeuronPowerMutationCount(0.5)
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		n := mutationCount(random, cortex)
+		assert.True(t, n >= 1 && n <= 2)
+		seen[n] = true
+	}
+	assert.Equals(t, len(seen), 2)
+
+	// a cortex without neurons still gets a mutation
+	empty := &ng.Cortex{}
+	assert.Equals(t, mutationCount(random, empty), 1)
+
+	assert.Equals(t, FixedMutationCount(3)(random, cortex), 3)
+
+}
+
+func TestCompoundMutator(t *testing.T) {
+
+	random := newRandom()
+
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "increase", Mutator: addToBiases(1), Probability: 1},
+			{Name: "panic", Mutator: panickingMutator, Probability: 1},
+		},
+		MaxAttempts: 1,
+	}
+	mutator := NewCompoundMutator(policy)
+	mutator.NumMutations = FixedMutationCount(3)
+
+	cortex := BasicCortex()
+	ok, mutateResult := mutator.Mutate(random, cortex)
+	assert.True(t, ok)
+	assert.True(t, cortex.Validate())
+
+	// the panics were retried, so the biases went up 3 times
+	assert.Equals(t, mutateResult.Operator, "CompoundMutator")
+	assert.Equals(t, len(mutateResult.Mutations), 3)
+	for _, mutation := range mutateResult.Mutations {
+		assert.Equals(t, mutation.Operator, "addToBiases")
+	}
+	assert.True(t, cortex.Neurons[0].Bias > 3.1499 && cortex.Neurons[0].Bias < 3.1501)
+	for _, neuron := range cortex.Neurons {
+		assert.True(t, neuron.Cortex == cortex)
+	}
+
+}
+
+func TestCompoundMutatorFailure(t *testing.T) {
+
+	random := newRandom()
+
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "fail", Mutator: failingMutator, Probability: 1},
+		},
+		MaxAttempts: 1,
+	}
+	mutator := &CompoundMutator{Policy: policy, MaxAttempts: 5}
+
+	cortex := BasicCortex()
+	neurons := cortex.Neurons
+	ok, _ := mutator.Mutate(random, cortex)
+	assert.False(t, ok)
+
+	// the cortex is left as it was
+	assert.Equals(t, len(cortex.Neurons), len(neurons))
+	assert.True(t, cortex.Neurons[0] == neurons[0])
+	assert.Equals(t, policy.Stats()[0].Attempts, 5)
+
+}
+
+// Leaves the cortex invalid, with connections to a neuron that has lost
+// its inbound connections
+func invalidatingMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	cortex.Neurons[0].Inbound = nil
+	return true, newMutationRecord("invalidatingMutator")
+}
+
+func TestCompoundMutatorInvalidCortex(t *testing.T) {
+
+	random := newRandom()
+
+	// the policy's own MaxAttempts doesn't multiply the attempts
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "invalidate", Mutator: invalidatingMutator, Probability: 1},
+		},
+	}
+	mutator := &CompoundMutator{Policy: policy, MaxAttempts: 5}
+
+	cortex := BasicCortex()
+	ok, _ := mutator.Mutate(random, cortex)
+	assert.False(t, ok)
+	assert.True(t, cortex.Validate())
+
+	// the operator is not credited with mutations that left the cortex
+	// invalid
+	stats := policy.Stats()
+	assert.Equals(t, stats[0].Attempts, 5)
+	assert.Equals(t, stats[0].Successes, 0)
+
+	mutator.Policy = nil
+	ok, _ = mutator.Mutate(random, cortex)
+	assert.False(t, ok)
+
+}
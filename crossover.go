@@ -0,0 +1,142 @@
+package neurvolve
+
+import (
+	"fmt"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+)
+
+// Combines two parent cortexes into a new child cortex, leaving the
+// parents unchanged.  Returns ErrIncompatibleParents if the parents
+// cannot be combined, or ErrInvalidCortex if the child did not validate.
+type CortexCrossover func(random *rand.Rand, parent, otherParent *ng.Cortex) (child *ng.Cortex, err error)
+
+// Crossover for parents with identical topologies.  Each bias, activation
+// function and weight vector of the child is taken from either parent
+// with equal probability.
+func UniformWeightCrossover(random *rand.Rand, parent, otherParent *ng.Cortex) (child *ng.Cortex, err error) {
+
+	if !sameTopology(parent, otherParent) {
+		err = fmt.Errorf("%w: %v and %v have different topologies", ErrIncompatibleParents, parent.NodeId.UUID, otherParent.NodeId.UUID)
+		return
+	}
+
+	return crossover(random, parent, otherParent)
+
+}
+
+// Crossover for parents with differing topologies, in the style of NEAT.
+// Neurons and connections are aligned by their node ids.  The child has
+// the topology of the first parent, which should be the fitter of the
+// two, and the parameters of the genes found in both parents are taken
+// from either one with equal probability.  The parents must have the
+// same sensors and actuators.
+func NEATCrossover(random *rand.Rand, fitterParent, otherParent *ng.Cortex) (child *ng.Cortex, err error) {
+
+	if !sameSensorsAndActuators(fitterParent, otherParent) {
+		err = fmt.Errorf("%w: %v and %v have different sensors or actuators", ErrIncompatibleParents, fitterParent.NodeId.UUID, otherParent.NodeId.UUID)
+		return
+	}
+
+	return crossover(random, fitterParent, otherParent)
+
+}
+
+// Copy parent, then replace the parameters of each neuron and connection
+// that is also in otherParent with those of otherParent, half of the time.
+func crossover(random *rand.Rand, parent, otherParent *ng.Cortex) (child *ng.Cortex, err error) {
+
+	child = parent.Copy()
+
+	for _, neuron := range child.Neurons {
+
+		otherNeuron := otherParent.FindNeuron(neuron.NodeId)
+		if otherNeuron == nil {
+			continue
+		}
+
+		if coinFlip(random) {
+			neuron.Bias = otherNeuron.Bias
+		}
+		if coinFlip(random) && otherNeuron.ActivationFunction != nil {
+			neuron.ActivationFunction = otherNeuron.ActivationFunction
+		}
+
+		for _, inbound := range neuron.Inbound {
+			otherInbound := findInboundConnection(otherNeuron, inbound.NodeId)
+			if otherInbound == nil || len(otherInbound.Weights) != len(inbound.Weights) {
+				continue
+			}
+			if coinFlip(random) {
+				inbound.Weights = append([]float64{}, otherInbound.Weights...)
+			}
+		}
+
+	}
+
+	if !child.Validate() {
+		err = fmt.Errorf("%w: crossover of %v and %v", ErrInvalidCortex, parent.NodeId.UUID, otherParent.NodeId.UUID)
+		child = nil
+	}
+	return
+
+}
+
+func coinFlip(random *rand.Rand) bool {
+	return random.Float64() < 0.5
+}
+
+func findInboundConnection(neuron *ng.Neuron, nodeId *ng.NodeId) *ng.InboundConnection {
+	for _, inbound := range neuron.Inbound {
+		if inbound.NodeId.UUID == nodeId.UUID {
+			return inbound
+		}
+	}
+	return nil
+}
+
+// True if both cortexes have the same neurons, with the same inbound
+// connections carrying the same number of weights
+func sameTopology(cortex, other *ng.Cortex) bool {
+
+	if !sameSensorsAndActuators(cortex, other) {
+		return false
+	}
+	if len(cortex.Neurons) != len(other.Neurons) {
+		return false
+	}
+
+	for _, neuron := range cortex.Neurons {
+		otherNeuron := other.FindNeuron(neuron.NodeId)
+		if otherNeuron == nil || len(otherNeuron.Inbound) != len(neuron.Inbound) {
+			return false
+		}
+		for _, inbound := range neuron.Inbound {
+			otherInbound := findInboundConnection(otherNeuron, inbound.NodeId)
+			if otherInbound == nil || len(otherInbound.Weights) != len(inbound.Weights) {
+				return false
+			}
+		}
+	}
+	return true
+
+}
+
+func sameSensorsAndActuators(cortex, other *ng.Cortex) bool {
+
+	if len(cortex.Sensors) != len(other.Sensors) || len(cortex.Actuators) != len(other.Actuators) {
+		return false
+	}
+	for _, sensor := range cortex.Sensors {
+		if other.FindSensor(sensor.NodeId) == nil {
+			return false
+		}
+	}
+	for _, actuator := range cortex.Actuators {
+		if other.FindActuator(actuator.NodeId) == nil {
+			return false
+		}
+	}
+	return true
+
+}
@@ -0,0 +1,93 @@
+package neurvolve
+
+import (
+	"errors"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestUniformWeightCrossover(t *testing.T) {
+
+	random := newRandom()
+
+	parent := BasicCortex()
+	otherParent := BasicCortex()
+	for _, neuron := range otherParent.Neurons {
+		neuron.Bias = 100
+	}
+
+	child, err := UniformWeightCrossover(random, parent, otherParent)
+	assert.True(t, err == nil)
+	assert.True(t, child.Validate())
+	assert.Equals(t, len(child.Neurons), len(parent.Neurons))
+	for i, neuron := range child.Neurons {
+		assert.True(t, neuron.Bias == parent.Neurons[i].Bias || neuron.Bias == 100)
+	}
+
+	// the parents are unchanged
+	for _, neuron := range otherParent.Neurons {
+		assert.Equals(t, neuron.Bias, 100.0)
+	}
+
+}
+
+func TestUniformWeightCrossoverDifferentTopologies(t *testing.T) {
+
+	random := newRandom()
+
+	parent := BasicCortex()
+	otherParent := parent.Copy()
+	ok, _ := AddNeuronNonRecurrent(random, otherParent)
+	assert.True(t, ok)
+
+	_, err := UniformWeightCrossover(random, parent, otherParent)
+	assert.True(t, errors.Is(err, ErrIncompatibleParents))
+
+}
+
+func TestNEATCrossover(t *testing.T) {
+
+	random := newRandom()
+
+	fitterParent := BasicCortex()
+	ok, _ := AddNeuronNonRecurrent(random, fitterParent)
+	assert.True(t, ok)
+	otherParent := BasicCortex()
+
+	child, err := NEATCrossover(random, fitterParent, otherParent)
+	assert.True(t, err == nil)
+	assert.True(t, child.Validate())
+	assert.Equals(t, len(child.Neurons), len(fitterParent.Neurons))
+
+	_, err = NEATCrossover(random, fitterParent, SingleNeuronCortex("single"))
+	assert.True(t, errors.Is(err, ErrIncompatibleParents))
+
+}
+
+func TestTrainWithCrossover(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:     1000,
+		MaxGenerations:       5,
+		CortexMutator:        NoOpMutator,
+		Crossover:            NEATCrossover,
+		CrossoverProbability: 1.0,
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		SingleNeuronCortex("cortex3"),
+		SingleNeuronCortex("cortex4"),
+	}
+
+	trainedPopulation, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, len(trainedPopulation), 4)
+
+	pt.CrossoverProbability = 2
+	_, _, err = pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+}
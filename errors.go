@@ -0,0 +1,39 @@
+package neurvolve
+
+import (
+	"errors"
+	"fmt"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+)
+
+// Errors returned by the trainers and mutators.  They are usually wrapped
+// with more details, so use errors.Is to check for them.
+var (
+	ErrPopulationSize            = errors.New("invalid population size")
+	ErrInsufficientOpponents     = errors.New("not enough members of population to choose opponents")
+	ErrMutationFailed            = errors.New("unable to mutate cortex")
+	ErrInvalidCortex             = errors.New("cortex did not validate")
+	ErrInvalidConfig             = errors.New("invalid trainer configuration")
+	ErrFitnessAgainstUnsupported = errors.New("scape cannot calculate fitness against an opponent")
+	ErrIncompatibleParents       = errors.New("parents cannot be crossed over")
+)
+
+// Apply the mutator to the cortex.  Returns ErrMutationFailed if the mutator
+// was unable to mutate the cortex, or if it panicked while trying.
+func ApplyMutator(random *rand.Rand, cortex *ng.Cortex, mutator CortexMutator) (result MutateResult, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = nil
+			err = fmt.Errorf("%w: %v: %v", ErrMutationFailed, cortex.NodeId.UUID, r)
+		}
+	}()
+
+	ok, result := mutator(random, cortex)
+	if !ok {
+		err = fmt.Errorf("%w: %v", ErrMutationFailed, cortex.NodeId.UUID)
+	}
+	return
+
+}
@@ -11,6 +11,24 @@ type EvaluatedCortex struct {
 	Fitness             float64
 	ParentId            string
 	CreatedInGeneration int
+
+	// Only set when training with a MultiObjectiveScape
+	Objectives       []float64
+	ParetoRank       int
+	CrowdingDistance float64
+
+	// Only set when training with Ratings
+	Rating float64
+
+	// Only set when training with NoveltySearch.  Once the novelty is
+	// scored, Fitness is the blend of novelty and fitness, and RawFitness
+	// is the fitness the scape gave the cortex.
+	Behavior   []float64
+	Novelty    float64
+	RawFitness float64
+
+	// Only set when training with SelfAdaptation
+	StepSize float64
 }
 
 type EvaluatedCortexes []EvaluatedCortex
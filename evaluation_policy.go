@@ -0,0 +1,142 @@
+package neurvolve
+
+import (
+	"fmt"
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"sort"
+)
+
+// How several fitness samples of the same cortex are combined into one
+type Aggregation int
+
+const (
+	MeanAggregation Aggregation = iota
+
+	// Less sensitive than the mean to the occasional wild sample
+	MedianAggregation
+)
+
+// How cortexes are evaluated by a noisy scape, whose fitness differs from
+// one call to the next.  Without a policy, each evaluation is a single
+// call to the scape, so a lucky sample is taken at face value.
+type EvaluationPolicy struct {
+
+	// The number of times the scape is called for each evaluation.
+	// Values of 0 or 1 mean a single call.
+	Samples     int
+	Aggregation Aggregation
+
+	// If set, the StochasticHillClimber evaluates its incumbent again,
+	// with fresh samples, each time it compares a candidate against it,
+	// rather than relying on the samples it was accepted with.
+	ReevaluateIncumbent bool
+
+	// If greater than 0, the StochasticHillClimber only accepts a
+	// candidate whose mean fitness is higher than the incumbent's with
+	// this confidence, for example 0.95, according to a one sided test on
+	// the difference of the means.  Needs at least 2 Samples.
+	ConfidenceLevel float64
+}
+
+// The fitness samples of the cortex on its own
+func (p EvaluationPolicy) sample(scape Scape, cortex *ng.Cortex) (samples []float64, err error) {
+	samples = make([]float64, p.numSamples())
+	for i := range samples {
+		if samples[i], err = tryFitness(scape, cortex); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// The aggregated fitness of the cortex against the opponent
+func (p EvaluationPolicy) fitnessAgainst(scape Scape, cortex *ng.Cortex, opponent *ng.Cortex) (fitness float64, err error) {
+	samples := make([]float64, p.numSamples())
+	for i := range samples {
+		if samples[i], err = tryFitnessAgainst(scape, cortex, opponent); err != nil {
+			return
+		}
+	}
+	fitness = p.aggregate(samples)
+	return
+}
+
+func (p EvaluationPolicy) numSamples() int {
+	if p.Samples < 1 {
+		return 1
+	}
+	return p.Samples
+}
+
+func (p EvaluationPolicy) aggregate(samples []float64) float64 {
+
+	if len(samples) == 1 {
+		return samples[0]
+	}
+
+	if p.Aggregation == MedianAggregation {
+		sorted := append([]float64{}, samples...)
+		sort.Float64s(sorted)
+		middle := len(sorted) / 2
+		if len(sorted)%2 == 1 {
+			return sorted[middle]
+		}
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	mean, _ := meanAndVariance(samples)
+	return mean
+
+}
+
+// True if the candidate's samples show it to be fitter than the
+// incumbent's
+func (p EvaluationPolicy) better(candidate []float64, incumbent []float64) bool {
+
+	if p.ConfidenceLevel <= 0 {
+		return p.aggregate(candidate) > p.aggregate(incumbent)
+	}
+
+	candidateMean, candidateVariance := meanAndVariance(candidate)
+	incumbentMean, incumbentVariance := meanAndVariance(incumbent)
+	standardError := math.Sqrt(candidateVariance/float64(len(candidate)) + incumbentVariance/float64(len(incumbent)))
+
+	// the lower bound of the one sided confidence interval of the
+	// difference must be above 0
+	z := math.Sqrt2 * math.Erfinv(2*p.ConfidenceLevel-1)
+	return candidateMean-incumbentMean > z*standardError
+
+}
+
+func (p EvaluationPolicy) validate() error {
+	if p.Samples < 0 {
+		return fmt.Errorf("%w: Samples cannot be negative", ErrInvalidConfig)
+	}
+	if p.ConfidenceLevel < 0 || p.ConfidenceLevel >= 1 {
+		return fmt.Errorf("%w: ConfidenceLevel must be at least 0 and less than 1", ErrInvalidConfig)
+	}
+	if p.ConfidenceLevel > 0 && p.Samples < 2 {
+		return fmt.Errorf("%w: ConfidenceLevel needs at least 2 Samples", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// The mean and the unbiased sample variance
+func meanAndVariance(samples []float64) (mean float64, variance float64) {
+
+	for _, sample := range samples {
+		mean += sample
+	}
+	mean /= float64(len(samples))
+
+	if len(samples) < 2 {
+		return
+	}
+	for _, sample := range samples {
+		variance += (sample - mean) * (sample - mean)
+	}
+	variance /= float64(len(samples) - 1)
+	return
+
+}
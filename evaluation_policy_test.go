@@ -0,0 +1,68 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"testing"
+)
+
+func TestEvaluationPolicyAggregate(t *testing.T) {
+
+	samples := []float64{1, 9, 2, 100}
+
+	mean := EvaluationPolicy{Aggregation: MeanAggregation}
+	assert.Equals(t, mean.aggregate(samples), 28.0)
+
+	median := EvaluationPolicy{Aggregation: MedianAggregation}
+	assert.Equals(t, median.aggregate(samples), 5.5)
+	assert.Equals(t, median.aggregate([]float64{3, 1, 2}), 2.0)
+
+	// the samples themselves are left alone
+	assert.DeepEquals(t, samples, []float64{1, 9, 2, 100})
+
+}
+
+func TestEvaluationPolicyConfidence(t *testing.T) {
+
+	policy := EvaluationPolicy{Samples: 4, ConfidenceLevel: 0.95}
+	incumbent := []float64{1.0, 1.1, 0.9, 1.0}
+
+	// a higher mean, but too noisy to be sure of
+	noisy := []float64{5.0, -3.0, 4.0, -1.0}
+	assert.False(t, policy.better(noisy, incumbent))
+
+	// consistently higher
+	steady := []float64{1.5, 1.6, 1.4, 1.5}
+	assert.True(t, policy.better(steady, incumbent))
+
+	// without a confidence level the means are simply compared
+	policy.ConfidenceLevel = 0
+	assert.True(t, policy.better(noisy, incumbent))
+
+}
+
+func TestEvaluationPolicySamples(t *testing.T) {
+
+	numEvaluations := 0
+	scape := FakeCountingScape{numEvaluations: &numEvaluations}
+	cortex := SingleNeuronCortex("cortex")
+	cortex.Neurons[0].Bias = 2
+
+	job := &fitnessJob{
+		cortex: cortex,
+		policy: EvaluationPolicy{Samples: 5},
+	}
+	job.run(scape)
+	assert.Equals(t, numEvaluations, 5)
+	assert.DeepEquals(t, job.scores, []float64{2.0})
+
+}
+
+func TestEvaluationPolicyValidate(t *testing.T) {
+
+	assert.True(t, EvaluationPolicy{}.validate() == nil)
+	assert.True(t, EvaluationPolicy{Samples: -1}.validate() != nil)
+	assert.True(t, EvaluationPolicy{Samples: 1, ConfidenceLevel: 0.9}.validate() != nil)
+	assert.True(t, EvaluationPolicy{Samples: 3, ConfidenceLevel: 1}.validate() != nil)
+	assert.True(t, EvaluationPolicy{Samples: 3, ConfidenceLevel: 0.9}.validate() == nil)
+
+}
@@ -6,6 +6,7 @@ import (
 	ng "github.com/tleyden/neurgo"
 	nv "github.com/tleyden/neurvolve"
 	"math"
+	"math/rand"
 	"time"
 )
 
@@ -20,7 +21,7 @@ func RunPopulationTrainerLoop(maxIterations int) bool {
 		// CortexMutator: RandomNeuronMutator,
 		// CortexMutator:       nv.TopologyOrWeightMutator,
 		NumOpponents:        5,
-		SnapshotRequestChan: make(chan chan nv.EvaluatedCortexes),
+		SnapshotRequestChan: make(chan chan nv.PopulationSnapshot),
 	}
 	nv.RegisterHandlers(pt)
 
@@ -43,7 +44,13 @@ func RunPopulationTrainer(pt *nv.PopulationTrainer) bool {
 	population := getInitialPopulation()
 	scape := getScape()
 
-	fitPopulation, succeeded := pt.Train(population, scape)
+	recorder := nv.NewNullRecorder()
+
+	fitPopulation, succeeded, err := pt.Train(population, scape, recorder)
+	if err != nil {
+		logg.LogTo("MAIN", "Error training population: %v", err)
+		return false
+	}
 
 	if succeeded {
 		logg.LogTo("MAIN", "Successfully trained!")
@@ -73,13 +80,13 @@ func RunPopulationTrainer(pt *nv.PopulationTrainer) bool {
 
 }
 
-func RandomNeuronMutator(cortex *ng.Cortex) (success bool, result nv.MutateResult) {
+func RandomNeuronMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result nv.MutateResult) {
 	// -6pi <-> 6pi or anything lower doesn't work ..
 	// -8pi <-> 8pi works but gets stuck sometimes
 	saturationBounds := []float64{-100 * math.Pi, 100 * math.Pi}
-	nv.PerturbParameters(cortex, saturationBounds)
+	nv.PerturbParameters(random, cortex, saturationBounds)
 	success = true
-	result = "nothing"
+	result = &nv.MutationRecord{Operator: "RandomNeuronMutator"}
 	return
 }
 
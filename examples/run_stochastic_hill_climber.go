@@ -29,7 +29,10 @@ func RunStochasticHillClimber() {
 		MaxAttempts:                2000,
 		WeightSaturationRange:      []float64{-100 * math.Pi, 100 * math.Pi},
 	}
-	cortexTrained, succeeded := shc.TrainExamples(cortex, examples)
+	cortexTrained, succeeded, err := shc.TrainExamples(cortex, examples)
+	if err != nil {
+		panic(err)
+	}
 	if !succeeded {
 		panic("could not train neural net")
 	}
@@ -34,7 +34,11 @@ func RunTopologyMutatingTrainer() bool {
 		MaxIterationsBeforeRestart: 5,
 		StochasticHillClimber:      shc,
 	}
-	cortexTrained, succeeded := tmt.TrainExamples(cortex, examples)
+	cortexTrained, succeeded, err := tmt.TrainExamples(cortex, examples)
+	if err != nil {
+		logg.LogTo("MAIN", "Error training neural net: %v", err)
+		return false
+	}
 	if succeeded {
 		logg.LogTo("MAIN", "Successfully trained net: %v", ng.JsonString(cortexTrained))
 
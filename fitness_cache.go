@@ -0,0 +1,164 @@
+package neurvolve
+
+import (
+	"sync"
+)
+
+// Scapes which always give the same fitness to the same cortex, such as
+// those based on a fixed set of training samples, can implement this to
+// report it.  Only the fitness of cortexes evaluated against a
+// deterministic scape is cached.
+type DeterminismReporter interface {
+	Deterministic() bool
+}
+
+func isDeterministic(scape Scape) bool {
+	if reporter, ok := scape.(DeterminismReporter); ok {
+		return reporter.Deterministic()
+	}
+	return false
+}
+
+// Remembers the results of fitness evaluations by GenotypeHash, so that
+// parents which survive into the next generation, and offspring which the
+// mutator left unchanged, are not evaluated again.  Set it as the
+// FitnessCache of a PopulationTrainer to use it.  It is only used when the
+// scape is deterministic and cortexes are not evaluated against opponents.
+// The zero value is an empty cache with no MaxSize.
+type FitnessCache struct {
+
+	// When the cache holds more than this many results, the oldest are
+	// dropped.  0 means the cache can grow without limit.
+	MaxSize int
+
+	entries map[string]fitnessCacheEntry
+	order   []string
+	hits    int
+	misses  int
+	mutex   sync.Mutex
+}
+
+// Everything measured about a cortex in a single evaluation
+type fitnessCacheEntry struct {
+	fitness    float64
+	objectives []float64
+	behavior   []float64
+}
+
+func NewFitnessCache(maxSize int) *FitnessCache {
+	return &FitnessCache{
+		MaxSize: maxSize,
+		entries: make(map[string]fitnessCacheEntry),
+		order:   make([]string, 0),
+	}
+}
+
+// The number of evaluations that were found in the cache, and the number
+// that were not
+func (c *FitnessCache) Stats() (hits int, misses int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.hits, c.misses
+}
+
+// The number of results in the cache
+func (c *FitnessCache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+func (c *FitnessCache) get(hash string) (entry fitnessCacheEntry, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok = c.entries[hash]
+	if ok {
+		c.hits += 1
+	} else {
+		c.misses += 1
+	}
+	return
+}
+
+func (c *FitnessCache) put(hash string, entry fitnessCacheEntry) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[hash]; ok {
+		return
+	}
+	if c.entries == nil {
+		c.entries = make(map[string]fitnessCacheEntry)
+	}
+	c.entries[hash] = entry
+	c.order = append(c.order, hash)
+
+	for c.MaxSize > 0 && len(c.order) > c.MaxSize {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+
+}
+
+// Fill in the results of jobs which are in the cache, and return the
+// jobs which still have to be run along with the hashes of their
+// cortexes.  Identical cortexes in the same generation are only run once.
+func (c *FitnessCache) lookup(jobs []*fitnessJob) (pending []*fitnessJob, hashes []string, duplicates map[*fitnessJob]*fitnessJob) {
+
+	pending = make([]*fitnessJob, 0)
+	hashes = make([]string, 0)
+	duplicates = make(map[*fitnessJob]*fitnessJob)
+	pendingByHash := make(map[string]*fitnessJob)
+
+	for _, job := range jobs {
+		hash := GenotypeHash(job.cortex)
+		if entry, ok := c.get(hash); ok {
+			job.setCachedResult(entry)
+			continue
+		}
+		if original, ok := pendingByHash[hash]; ok {
+			duplicates[job] = original
+			continue
+		}
+		pendingByHash[hash] = job
+		pending = append(pending, job)
+		hashes = append(hashes, hash)
+	}
+	return
+
+}
+
+// Add the results of the jobs which were run, and copy them to the
+// duplicates of those jobs.  Jobs which failed, or were never run because
+// an earlier job failed, have no result to cache.
+func (c *FitnessCache) store(pending []*fitnessJob, hashes []string, duplicates map[*fitnessJob]*fitnessJob) {
+	for i, job := range pending {
+		if job.succeeded() {
+			c.put(hashes[i], job.cachedResult())
+		}
+	}
+	for duplicate, original := range duplicates {
+		if original.succeeded() {
+			duplicate.setCachedResult(original.cachedResult())
+		}
+	}
+}
+
+func (job *fitnessJob) succeeded() bool {
+	return job.err == nil && len(job.scores) > 0
+}
+
+func (job *fitnessJob) cachedResult() fitnessCacheEntry {
+	return fitnessCacheEntry{
+		fitness:    job.scores[0],
+		objectives: job.objectives,
+		behavior:   job.behavior,
+	}
+}
+
+func (job *fitnessJob) setCachedResult(entry fitnessCacheEntry) {
+	job.scores = []float64{entry.fitness}
+	job.objectives = append([]float64{}, entry.objectives...)
+	job.behavior = append([]float64{}, entry.behavior...)
+}
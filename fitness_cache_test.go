@@ -0,0 +1,110 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+// A deterministic scape which counts how many times it is called
+type FakeCountingScape struct {
+	FakeScapeBiasSum
+	numEvaluations *int
+}
+
+func (scape FakeCountingScape) Fitness(cortex *ng.Cortex) float64 {
+	*scape.numEvaluations += 1
+	return scape.FakeScapeBiasSum.Fitness(cortex)
+}
+
+func (scape FakeCountingScape) Deterministic() bool {
+	return true
+}
+
+func TestFitnessCacheSkipsUnchangedCortexes(t *testing.T) {
+
+	numEvaluations := 0
+	scape := FakeCountingScape{numEvaluations: &numEvaluations}
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   5,
+		CortexMutator:    NoOpMutator,
+		FitnessCache:     NewFitnessCache(0),
+	}
+
+	population := biasPopulation("cortex", 1, 2, 3, 4)
+	trainedPopulation, _, err := pt.Train(population, scape, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, trainedPopulation[0].Fitness, 4.0)
+
+	// the mutator never changes anything, so only the initial
+	// population is ever evaluated
+	assert.Equals(t, numEvaluations, 4)
+	hits, misses := pt.FitnessCache.Stats()
+	assert.Equals(t, misses, 4)
+	assert.Equals(t, hits, 4*5-4)
+
+}
+
+func TestFitnessCacheNondeterministicScape(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		FitnessCache:     NewFitnessCache(0),
+	}
+
+	population := biasPopulation("cortex", 1, 2, 3, 4)
+	_, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, pt.FitnessCache.Len(), 0)
+
+}
+
+func TestFitnessCacheMaxSize(t *testing.T) {
+
+	cache := NewFitnessCache(2)
+	cache.put("a", fitnessCacheEntry{fitness: 1})
+	cache.put("b", fitnessCacheEntry{fitness: 2})
+	cache.put("c", fitnessCacheEntry{fitness: 3})
+	assert.Equals(t, cache.Len(), 2)
+
+	_, ok := cache.get("a")
+	assert.False(t, ok)
+	entry, ok := cache.get("c")
+	assert.True(t, ok)
+	assert.Equals(t, entry.fitness, 3.0)
+
+}
+
+func TestFitnessCacheZeroValue(t *testing.T) {
+
+	cache := &FitnessCache{}
+	cache.put("a", fitnessCacheEntry{fitness: 1})
+	entry, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equals(t, entry.fitness, 1.0)
+
+}
+
+func TestFitnessCacheSkipsFailedJobs(t *testing.T) {
+
+	failed := &fitnessJob{cortex: SingleNeuronCortex("failed"), err: ErrFitnessJobFailed, scores: []float64{0}}
+	unrun := &fitnessJob{cortex: SingleNeuronCortex("unrun")}
+	succeeded := &fitnessJob{cortex: SingleNeuronCortex("succeeded"), scores: []float64{2}}
+	duplicate := &fitnessJob{cortex: SingleNeuronCortex("duplicate")}
+
+	cache := NewFitnessCache(0)
+	pending := []*fitnessJob{failed, unrun, succeeded}
+	hashes := []string{"failed", "unrun", "succeeded"}
+	cache.store(pending, hashes, map[*fitnessJob]*fitnessJob{duplicate: failed})
+
+	assert.Equals(t, cache.Len(), 1)
+	entry, ok := cache.get("succeeded")
+	assert.True(t, ok)
+	assert.Equals(t, entry.fitness, 2.0)
+	assert.True(t, duplicate.scores == nil)
+
+}
@@ -0,0 +1,378 @@
+package neurvolve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// The name the coordinator is registered under with net/rpc
+const fitnessCoordinatorService = "FitnessCoordinator"
+
+var ErrCoordinatorClosed = errors.New("fitness coordinator is closed")
+
+// Returned when workers have failed a job MaxAttempts times
+var ErrFitnessJobFailed = errors.New("fitness job failed")
+
+// A fitness evaluation handed out to a worker.  The cortexes are sent in
+// the same json format used to save them to files.
+type RemoteFitnessJob struct {
+	JobId    int64
+	Cortex   []byte
+	Opponent []byte // empty unless the fitness is against an opponent
+
+	// Set when no job became available before the poll timeout, in which
+	// case the worker should ask again
+	NoJob bool
+}
+
+// The outcome of a RemoteFitnessJob, sent back by the worker
+type RemoteFitnessResult struct {
+	JobId    int64
+	WorkerId string
+	Fitness  float64
+	Error    string
+}
+
+// A Scape which farms out every fitness evaluation to remote workers, so
+// that a PopulationTrainer with NumWorkers set to roughly the number of
+// workers can evaluate a whole generation in parallel across machines.
+//
+// The coordinator is a FallibleScape, so trainers stop with an error when
+// a job cannot be evaluated.  Fitness and FitnessAgainst log the error and
+// return a fitness of 0 instead.
+//
+// Workers pull jobs from the coordinator, so they can join or leave at
+// any time.  A job that a worker has not finished within JobTimeout is
+// handed out again, and whichever result comes back first is used.
+type FitnessCoordinator struct {
+
+	// How long a worker has to return the result of a job before the job
+	// is given to another worker.  Defaults to a minute.
+	JobTimeout time.Duration
+
+	// How long a worker asking for a job waits for one before being told
+	// to ask again.  Defaults to 10 seconds.
+	PollTimeout time.Duration
+
+	// How many times a job is attempted when workers report errors.  After
+	// that the evaluation fails with ErrFitnessJobFailed.  Defaults to 3.
+	MaxAttempts int
+
+	queue     chan *remoteJob
+	jobs      map[int64]*remoteJob
+	nextJobId int64
+	closed    chan struct{}
+	closeOnce sync.Once
+	startOnce sync.Once
+	mutex     sync.Mutex
+}
+
+// A job waiting for its result
+type remoteJob struct {
+	request  RemoteFitnessJob
+	deadline time.Time
+	leased   bool
+	attempts int
+	result   chan remoteJobResult
+}
+
+// The fitness of a finished job, or the reason it failed
+type remoteJobResult struct {
+	fitness float64
+	err     error
+}
+
+func NewFitnessCoordinator() *FitnessCoordinator {
+	coordinator := &FitnessCoordinator{
+		JobTimeout:  time.Minute,
+		PollTimeout: 10 * time.Second,
+		MaxAttempts: 3,
+		queue:       make(chan *remoteJob),
+		jobs:        make(map[int64]*remoteJob),
+		closed:      make(chan struct{}),
+	}
+	return coordinator
+}
+
+// Serve the coordinator to workers connecting on the listener.  Returns
+// once the listener is closed.
+func (c *FitnessCoordinator) Serve(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(fitnessCoordinatorService, &coordinatorService{c}); err != nil {
+		return err
+	}
+	server.Accept(listener)
+	return nil
+}
+
+// Stop handing out jobs.  Evaluations still waiting for a worker fail
+// with ErrCoordinatorClosed.
+func (c *FitnessCoordinator) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+}
+
+func (c *FitnessCoordinator) Fitness(cortex *ng.Cortex) float64 {
+	return c.logFailure(c.TryFitness(cortex))
+}
+
+func (c *FitnessCoordinator) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return c.logFailure(c.TryFitnessAgainst(cortex, opponent))
+}
+
+func (c *FitnessCoordinator) TryFitness(cortex *ng.Cortex) (float64, error) {
+	return c.evaluate(cortex, nil)
+}
+
+func (c *FitnessCoordinator) TryFitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) (float64, error) {
+	return c.evaluate(cortex, opponent)
+}
+
+func (c *FitnessCoordinator) logFailure(fitness float64, err error) float64 {
+	if err != nil {
+		logg.LogTo("NEURVOLVE", "Fitness evaluation failed: %v", err)
+	}
+	return fitness
+}
+
+// Queue a job for the cortex and wait for a worker to return its fitness
+func (c *FitnessCoordinator) evaluate(cortex *ng.Cortex, opponent *ng.Cortex) (fitness float64, err error) {
+
+	request := RemoteFitnessJob{}
+	if request.Cortex, err = json.Marshal(cortex); err != nil {
+		err = fmt.Errorf("unable to marshal cortex %v: %w", cortex.NodeId.UUID, err)
+		return
+	}
+	if opponent != nil {
+		if request.Opponent, err = json.Marshal(opponent); err != nil {
+			err = fmt.Errorf("unable to marshal opponent %v: %w", opponent.NodeId.UUID, err)
+			return
+		}
+	}
+
+	// started here rather than in NewFitnessCoordinator, so that it sees
+	// any change to JobTimeout
+	c.startOnce.Do(func() {
+		go c.redispatchExpiredJobs()
+	})
+
+	c.mutex.Lock()
+	c.nextJobId += 1
+	request.JobId = c.nextJobId
+	job := &remoteJob{
+		request: request,
+		result:  make(chan remoteJobResult, 1),
+	}
+	c.jobs[request.JobId] = job
+	c.mutex.Unlock()
+
+	c.enqueue(job)
+
+	select {
+	case result := <-job.result:
+		return result.fitness, result.err
+	case <-c.closed:
+		err = fmt.Errorf("%w before job %v finished", ErrCoordinatorClosed, request.JobId)
+		return
+	}
+
+}
+
+// Make the job available to the next worker that asks for one
+func (c *FitnessCoordinator) enqueue(job *remoteJob) {
+	go func() {
+		select {
+		case c.queue <- job:
+		case <-c.closed:
+		}
+	}()
+}
+
+// Hand out the next job, or a job with NoJob set if none turns up before
+// the poll timeout.  The job is leased to the worker until JobTimeout.
+func (c *FitnessCoordinator) nextJob(workerId string) (request RemoteFitnessJob, err error) {
+
+	pollTimeout := time.After(c.PollTimeout)
+	for {
+		select {
+		case job := <-c.queue:
+			c.mutex.Lock()
+			if _, waiting := c.jobs[job.request.JobId]; !waiting {
+				// finished by another worker while it was queued
+				c.mutex.Unlock()
+				continue
+			}
+			job.leased = true
+			job.deadline = time.Now().Add(c.JobTimeout)
+			job.attempts += 1
+			c.mutex.Unlock()
+			logg.LogTo("DEBUG", "Job %v leased to worker %v", job.request.JobId, workerId)
+			request = job.request
+			return
+		case <-pollTimeout:
+			request.NoJob = true
+			return
+		case <-c.closed:
+			err = ErrCoordinatorClosed
+			return
+		}
+	}
+
+}
+
+// Record the result of a job.  Results for jobs which are already
+// finished are ignored.  A job whose worker reported an error is handed
+// out again, up to MaxAttempts times.
+func (c *FitnessCoordinator) submitResult(result RemoteFitnessResult) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	job, waiting := c.jobs[result.JobId]
+	if !waiting {
+		return
+	}
+
+	if result.Error != "" {
+		logg.LogTo("NEURVOLVE", "Worker %v failed job %v: %v", result.WorkerId, result.JobId, result.Error)
+		if job.attempts < c.MaxAttempts {
+			job.leased = false
+			c.enqueue(job)
+			return
+		}
+		delete(c.jobs, result.JobId)
+		job.result <- remoteJobResult{
+			err: fmt.Errorf("%w: job %v after %v attempts, last by worker %v: %v", ErrFitnessJobFailed, result.JobId, job.attempts, result.WorkerId, result.Error),
+		}
+		return
+	}
+
+	delete(c.jobs, result.JobId)
+	job.result <- remoteJobResult{fitness: result.Fitness}
+
+}
+
+// Put any job whose lease has expired back in the queue, on the
+// assumption that its worker has died
+func (c *FitnessCoordinator) redispatchExpiredJobs() {
+
+	interval := c.JobTimeout / 2
+	if interval <= 0 || interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.closed:
+			return
+		}
+
+		now := time.Now()
+		c.mutex.Lock()
+		for jobId, job := range c.jobs {
+			if job.leased && now.After(job.deadline) {
+				logg.LogTo("NEURVOLVE", "Job %v timed out, dispatching it again", jobId)
+				job.leased = false
+				c.enqueue(job)
+			}
+		}
+		c.mutex.Unlock()
+	}
+
+}
+
+// The methods exposed to workers over net/rpc
+type coordinatorService struct {
+	coordinator *FitnessCoordinator
+}
+
+func (s *coordinatorService) NextJob(workerId string, job *RemoteFitnessJob) (err error) {
+	*job, err = s.coordinator.nextJob(workerId)
+	return
+}
+
+func (s *coordinatorService) SubmitResult(result RemoteFitnessResult, accepted *bool) error {
+	s.coordinator.submitResult(result)
+	*accepted = true
+	return nil
+}
+
+// Evaluates jobs from a FitnessCoordinator using a local Scape
+type FitnessWorker struct {
+	Id    string
+	Scape Scape
+}
+
+// Connect to the coordinator at the given address and work until the
+// coordinator is closed or the connection is lost
+func (w *FitnessWorker) DialAndRun(address string) error {
+	client, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return w.Run(client)
+}
+
+// Work on jobs from the coordinator at the other end of the client until
+// the coordinator is closed or the connection is lost.  Returns nil if
+// the coordinator was closed.
+func (w *FitnessWorker) Run(client *rpc.Client) error {
+
+	for {
+		job := RemoteFitnessJob{}
+		if err := client.Call(fitnessCoordinatorService+".NextJob", w.Id, &job); err != nil {
+			if err.Error() == ErrCoordinatorClosed.Error() {
+				return nil
+			}
+			return err
+		}
+		if job.NoJob {
+			continue
+		}
+
+		result := w.evaluate(job)
+		accepted := false
+		if err := client.Call(fitnessCoordinatorService+".SubmitResult", result, &accepted); err != nil {
+			return err
+		}
+	}
+
+}
+
+func (w *FitnessWorker) evaluate(job RemoteFitnessJob) (result RemoteFitnessResult) {
+
+	result.JobId = job.JobId
+	result.WorkerId = w.Id
+
+	cortex, err := unmarshalCortex(job.Cortex)
+	if err != nil {
+		result.Error = fmt.Sprintf("unable to unmarshal cortex: %v", err)
+		return
+	}
+
+	if len(job.Opponent) == 0 {
+		result.Fitness = w.Scape.Fitness(cortex)
+		return
+	}
+
+	opponent, err := unmarshalCortex(job.Opponent)
+	if err != nil {
+		result.Error = fmt.Sprintf("unable to unmarshal opponent: %v", err)
+		return
+	}
+	result.Fitness = w.Scape.FitnessAgainst(cortex, opponent)
+	return
+
+}
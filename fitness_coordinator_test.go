@@ -0,0 +1,147 @@
+package neurvolve
+
+import (
+	"errors"
+	"fmt"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+// Start a coordinator listening on a loopback address
+func loopbackCoordinator(t *testing.T) (coordinator *FitnessCoordinator, listener net.Listener) {
+	coordinator = NewFitnessCoordinator()
+	coordinator.PollTimeout = 50 * time.Millisecond
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.True(t, err == nil)
+	go coordinator.Serve(listener)
+	return
+}
+
+func startLoopbackWorker(t *testing.T, id string, address string) {
+	worker := &FitnessWorker{Id: id, Scape: FakeScapeBiasSum{}}
+	go worker.DialAndRun(address)
+}
+
+func TestFitnessCoordinatorLoopback(t *testing.T) {
+
+	coordinator, listener := loopbackCoordinator(t)
+	defer listener.Close()
+	defer coordinator.Close()
+
+	for i := 0; i < 3; i++ {
+		startLoopbackWorker(t, fmt.Sprintf("worker-%d", i), listener.Addr().String())
+	}
+
+	cortex := SingleNeuronCortex("cortex")
+	cortex.Neurons[0].Bias = 5
+	opponent := SingleNeuronCortex("opponent")
+
+	assert.Equals(t, coordinator.Fitness(cortex), 5.0)
+	assert.Equals(t, coordinator.FitnessAgainst(cortex, opponent), 4.0)
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		NumWorkers:       3,
+	}
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		SingleNeuronCortex("cortex3"),
+	}
+	trainedPopulation, _, err := pt.Train(population, coordinator, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, len(trainedPopulation), 3)
+	assert.Equals(t, trainedPopulation[0].Fitness, 1.0)
+
+}
+
+func TestFitnessCoordinatorRedispatch(t *testing.T) {
+
+	coordinator, listener := loopbackCoordinator(t)
+	defer listener.Close()
+	defer coordinator.Close()
+	coordinator.JobTimeout = 100 * time.Millisecond
+
+	// a worker which takes a job and then dies without returning it
+	client, err := rpc.Dial("tcp", listener.Addr().String())
+	assert.True(t, err == nil)
+	go func() {
+		job := RemoteFitnessJob{NoJob: true}
+		for job.NoJob {
+			client.Call(fitnessCoordinatorService+".NextJob", "dead-worker", &job)
+		}
+		client.Close()
+		startLoopbackWorker(t, "live-worker", listener.Addr().String())
+	}()
+
+	cortex := SingleNeuronCortex("cortex")
+	cortex.Neurons[0].Bias = 3
+	assert.Equals(t, coordinator.Fitness(cortex), 3.0)
+
+}
+
+func TestFitnessCoordinatorIgnoresLateResults(t *testing.T) {
+
+	coordinator := NewFitnessCoordinator()
+	defer coordinator.Close()
+	coordinator.PollTimeout = 50 * time.Millisecond
+
+	fitnessChan := make(chan float64)
+	go func() {
+		fitnessChan <- coordinator.Fitness(SingleNeuronCortex("cortex"))
+	}()
+
+	job, err := coordinator.nextJob("worker")
+	for err == nil && job.NoJob {
+		job, err = coordinator.nextJob("worker")
+	}
+	assert.True(t, err == nil)
+
+	coordinator.submitResult(RemoteFitnessResult{JobId: job.JobId, Fitness: 7})
+	coordinator.submitResult(RemoteFitnessResult{JobId: job.JobId, Fitness: 8})
+	assert.Equals(t, <-fitnessChan, 7.0)
+
+}
+
+func TestFitnessCoordinatorReportsFailedJobs(t *testing.T) {
+
+	coordinator := NewFitnessCoordinator()
+	coordinator.PollTimeout = 50 * time.Millisecond
+	coordinator.MaxAttempts = 1
+
+	// a worker which fails every job it is given
+	go func() {
+		for {
+			job, err := coordinator.nextJob("failing-worker")
+			if err != nil {
+				return
+			}
+			if !job.NoJob {
+				coordinator.submitResult(RemoteFitnessResult{JobId: job.JobId, Error: "boom"})
+			}
+		}
+	}()
+
+	_, err := coordinator.TryFitness(SingleNeuronCortex("cortex"))
+	assert.True(t, errors.Is(err, ErrFitnessJobFailed))
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+	}
+	population := []*ng.Cortex{SingleNeuronCortex("cortex1")}
+	_, _, err = pt.Train(population, coordinator, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrFitnessJobFailed))
+
+	coordinator.Close()
+	_, err = coordinator.TryFitness(SingleNeuronCortex("cortex"))
+	assert.True(t, errors.Is(err, ErrCoordinatorClosed))
+
+}
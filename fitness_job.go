@@ -0,0 +1,161 @@
+package neurvolve
+
+import (
+	"context"
+	ng "github.com/tleyden/neurgo"
+	"sync"
+)
+
+// The work needed to compute the fitness of a single cortex, either
+// on its own or against a list of opponents.
+type fitnessJob struct {
+	cortex    *ng.Cortex
+	opponents []*ng.Cortex
+	scores    []float64
+
+	// how many fitness samples make up each score
+	policy EvaluationPolicy
+
+	// set when the scape's objectives should be measured instead
+	multiObjective bool
+	objectives     []float64
+
+	// set when the scape should also describe the cortex's behavior
+	measureBehavior bool
+	behavior        []float64
+
+	// private copies of the opponents, used when jobs run concurrently
+	opponentCopies []*ng.Cortex
+
+	// set when the scape failed to evaluate the cortex
+	err error
+}
+
+// Run the fitness jobs, spreading them across pt.NumWorkers goroutines.
+// The scores for each job end up in the job itself, so the results do
+// not depend on the order in which the jobs happen to finish.  Once ctx
+// is done, no more jobs are started and ctx.Err() is returned.  If the
+// scape fails to evaluate a cortex, the error of the first such job is
+// returned.
+func (pt *PopulationTrainer) runFitnessJobs(ctx context.Context, jobs []*fitnessJob, scape Scape) error {
+
+	if pt.NumWorkers <= 1 {
+		for _, job := range jobs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			job.run(scape)
+			if job.err != nil {
+				return job.err
+			}
+		}
+		return nil
+	}
+
+	// a cortex cannot be run in two simulations at once, and any cortex
+	// might be an opponent in several jobs, so give each job its own
+	// copy of the opponents.  the copies are made before any of the
+	// workers start so that nothing is copied while it is running.
+	for _, job := range jobs {
+		opponentCopies := make([]*ng.Cortex, len(job.opponents))
+		for i, opponent := range job.opponents {
+			opponentCopies[i] = opponent.Copy()
+		}
+		job.opponentCopies = opponentCopies
+	}
+
+	if err := pt.runConcurrently(ctx, len(jobs), func(i int) {
+		jobs[i].run(scape)
+	}); err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.err != nil {
+			return job.err
+		}
+	}
+	return nil
+
+}
+
+// Call run with each index from 0 to numJobs-1, spread across
+// pt.NumWorkers goroutines.  Once ctx is done, no more jobs are started
+// and ctx.Err() is returned.
+func (pt *PopulationTrainer) runConcurrently(ctx context.Context, numJobs int, run func(i int)) error {
+
+	jobChan := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < pt.NumWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				run(job)
+			}
+		}()
+	}
+
+	var err error
+	for job := 0; job < numJobs; job++ {
+		select {
+		case jobChan <- job:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(jobChan)
+	wg.Wait()
+
+	return err
+
+}
+
+func (job *fitnessJob) run(scape Scape) {
+
+	if job.err = job.computeScores(scape); job.err != nil {
+		return
+	}
+
+	if job.measureBehavior {
+		job.behavior = scape.(BehaviorScape).Behavior(job.cortex)
+	}
+
+}
+
+func (job *fitnessJob) computeScores(scape Scape) (err error) {
+
+	if job.multiObjective {
+		job.objectives = scape.(MultiObjectiveScape).Objectives(job.cortex)
+		job.scores = []float64{0}
+		if len(job.objectives) > 0 {
+			job.scores[0] = job.objectives[0]
+		}
+		return
+	}
+
+	if len(job.opponents) == 0 {
+		var samples []float64
+		if samples, err = job.policy.sample(scape, job.cortex); err != nil {
+			return
+		}
+		job.scores = []float64{job.policy.aggregate(samples)}
+		return
+	}
+
+	opponents := job.opponents
+	if job.opponentCopies != nil {
+		opponents = job.opponentCopies
+	}
+
+	job.scores = make([]float64, len(opponents))
+	for i, opponent := range opponents {
+		if job.scores[i], err = job.policy.fitnessAgainst(scape, job.cortex, opponent); err != nil {
+			return
+		}
+	}
+	return
+
+}
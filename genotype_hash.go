@@ -0,0 +1,114 @@
+package neurvolve
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	ng "github.com/tleyden/neurgo"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A hash of everything about the cortex that affects what it computes:
+// its sensors and actuators, the layer, bias and activation function of
+// each neuron, and every connection along with its weights.  UUIDs are
+// ignored, as is the order of the neurons, so a copy of a cortex with new
+// ids has the same hash, as does a cortex which a mutator left unchanged.
+//
+// Neurons are told apart by their own attributes and, over a number of
+// rounds, the labels of the nodes connected to them, in the style of the
+// Weisfeiler-Lehman graph isomorphism test.  Sensors and actuators are
+// told apart by their position in the cortex.
+func GenotypeHash(cortex *ng.Cortex) string {
+
+	labels := make(map[string]string)
+	for i, sensor := range cortex.Sensors {
+		labels[sensor.NodeId.UUID] = fmt.Sprintf("sensor:%d:%d", i, sensor.VectorLength)
+	}
+	for i, actuator := range cortex.Actuators {
+		labels[actuator.NodeId.UUID] = fmt.Sprintf("actuator:%d:%d", i, actuator.VectorLength)
+	}
+	for _, neuron := range cortex.Neurons {
+		activation := ""
+		if neuron.ActivationFunction != nil {
+			activation = neuron.ActivationFunction.Name
+		}
+		labels[neuron.NodeId.UUID] = hashLabel(fmt.Sprintf("neuron:%s:%s:%s",
+			formatHashFloat(neuron.NodeId.LayerIndex), activation, formatHashFloat(neuron.Bias)))
+	}
+
+	// refine the neuron labels until no more neurons can be told apart
+	numDistinct := countDistinctNeuronLabels(cortex, labels)
+	for round := 0; round < len(cortex.Neurons); round++ {
+		refined := make(map[string]string)
+		for _, neuron := range cortex.Neurons {
+			uuid := neuron.NodeId.UUID
+			refined[uuid] = hashLabel(labels[uuid] + "|" + describeInbound(neuron.Inbound, labels))
+		}
+		for uuid, label := range refined {
+			labels[uuid] = label
+		}
+		refinedDistinct := countDistinctNeuronLabels(cortex, labels)
+		if refinedDistinct == numDistinct {
+			break
+		}
+		numDistinct = refinedDistinct
+	}
+
+	neuronLabels := make([]string, 0)
+	for _, neuron := range cortex.Neurons {
+		uuid := neuron.NodeId.UUID
+		neuronLabels = append(neuronLabels, labels[uuid]+"|"+describeInbound(neuron.Inbound, labels))
+	}
+	sort.Strings(neuronLabels)
+
+	parts := make([]string, 0)
+	for _, sensor := range cortex.Sensors {
+		parts = append(parts, labels[sensor.NodeId.UUID])
+	}
+	parts = append(parts, neuronLabels...)
+	for _, actuator := range cortex.Actuators {
+		parts = append(parts, labels[actuator.NodeId.UUID]+"|"+describeInbound(actuator.Inbound, labels))
+	}
+
+	return hashLabel(strings.Join(parts, "\n"))
+
+}
+
+// The inbound connections, by the label of their source and their
+// weights, in a canonical order
+func describeInbound(inbound []*ng.InboundConnection, labels map[string]string) string {
+	connections := make([]string, 0)
+	for _, connection := range inbound {
+		source, ok := labels[connection.NodeId.UUID]
+		if !ok {
+			source = "?"
+		}
+		weights := make([]string, len(connection.Weights))
+		for i, weight := range connection.Weights {
+			weights[i] = formatHashFloat(weight)
+		}
+		connections = append(connections, source+"="+strings.Join(weights, ","))
+	}
+	sort.Strings(connections)
+	return strings.Join(connections, ";")
+}
+
+func countDistinctNeuronLabels(cortex *ng.Cortex, labels map[string]string) int {
+	distinct := make(map[string]bool)
+	for _, neuron := range cortex.Neurons {
+		distinct[labels[neuron.NodeId.UUID]] = true
+	}
+	return len(distinct)
+}
+
+func hashLabel(label string) string {
+	sum := sha256.Sum256([]byte(label))
+	return hex.EncodeToString(sum[:])
+}
+
+// Format a float exactly, so that any change to a weight changes the hash
+func formatHashFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,56 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestGenotypeHashIgnoresIds(t *testing.T) {
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	other.NodeId = ng.NewCortexId("other-cortex")
+	assert.Equals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+	// the order of the neurons doesn't matter either
+	neurons := other.Neurons
+	neurons[0], neurons[len(neurons)-1] = neurons[len(neurons)-1], neurons[0]
+	assert.Equals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+}
+
+func TestGenotypeHashWeights(t *testing.T) {
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	other.Neurons[0].Inbound[0].Weights[0] += 0.001
+	assert.NotEquals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+}
+
+func TestGenotypeHashBiasAndActivation(t *testing.T) {
+
+	cortex := BasicCortex()
+
+	other := cortex.Copy()
+	other.Neurons[1].Bias += 1
+	assert.NotEquals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+	other = cortex.Copy()
+	other.Neurons[1].ActivationFunction = ng.EncodableTanh()
+	assert.NotEquals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+}
+
+func TestGenotypeHashTopology(t *testing.T) {
+
+	random := newRandom()
+
+	cortex := BasicCortex()
+	other := cortex.Copy()
+	ok, _ := AddNeuronNonRecurrent(random, other)
+	assert.True(t, ok)
+	assert.NotEquals(t, GenotypeHash(cortex), GenotypeHash(other))
+
+}
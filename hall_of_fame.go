@@ -0,0 +1,170 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// The fittest cortexes seen over all generations of a training run.  With
+// noisy fitness scores, a champion can be culled after a single unlucky
+// generation, but it will still be kept here.
+//
+// A member that is evaluated again in a later generation has the mean of
+// all its evaluations as its fitness, rather than the best of them, so
+// that a lucky evaluation doesn't keep it in the hall of fame for good.
+// The number of evaluations isn't saved in checkpoints, so after a resume
+// the fitness of each member counts as a single evaluation.
+type HallOfFame struct {
+
+	// The number of cortexes it keeps.  0 means it keeps every cortex it
+	// is given.
+	MaxSize int
+
+	members EvaluatedCortexes
+
+	// the number of evaluations averaged into each member's fitness, by
+	// uuid, for members evaluated more than once
+	numEvaluations map[string]int
+
+	mutex sync.RWMutex
+}
+
+func NewHallOfFame(maxSize int) *HallOfFame {
+	return &HallOfFame{
+		MaxSize: maxSize,
+		members: make(EvaluatedCortexes, 0),
+	}
+}
+
+// Consider each of the evaluated cortexes for a place in the hall of fame.
+// The hall of fame keeps its own copies, so later changes to the cortexes
+// will not affect it.
+func (h *HallOfFame) Add(evaldCortexes []EvaluatedCortex) {
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for _, evaldCortex := range evaldCortexes {
+
+		uuid := evaldCortex.Cortex.NodeId.UUID
+		existing := h.indexOf(uuid)
+		if existing >= 0 {
+			h.addEvaluation(existing, evaldCortex.Fitness)
+			sort.Sort(h.members)
+			h.truncate()
+			continue
+		}
+
+		if h.full() && evaldCortex.Fitness <= h.members[len(h.members)-1].Fitness {
+			continue
+		}
+
+		member := evaldCortex
+		member.Cortex = evaldCortex.Cortex.Copy()
+		h.members = append(h.members, member)
+		sort.Sort(h.members)
+		h.truncate()
+
+	}
+
+}
+
+// Average another evaluation into the fitness of the member at index i
+func (h *HallOfFame) addEvaluation(i int, fitness float64) {
+
+	uuid := h.members[i].Cortex.NodeId.UUID
+	if h.numEvaluations == nil {
+		h.numEvaluations = make(map[string]int)
+	}
+	n := h.numEvaluations[uuid]
+	if n == 0 {
+		n = 1
+	}
+
+	h.members[i].Fitness += (fitness - h.members[i].Fitness) / float64(n+1)
+	h.numEvaluations[uuid] = n + 1
+
+}
+
+// Drop the least fit members beyond MaxSize
+func (h *HallOfFame) truncate() {
+	if h.MaxSize <= 0 || len(h.members) <= h.MaxSize {
+		return
+	}
+	for _, member := range h.members[h.MaxSize:] {
+		delete(h.numEvaluations, member.Cortex.NodeId.UUID)
+	}
+	h.members = h.members[:h.MaxSize]
+}
+
+// A copy of the members of the hall of fame, fittest first
+func (h *HallOfFame) Members() EvaluatedCortexes {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	members := make(EvaluatedCortexes, 0)
+	for _, member := range h.members {
+		memberCopy := member
+		memberCopy.Cortex = member.Cortex.Copy()
+		members = append(members, memberCopy)
+	}
+	return members
+}
+
+func (h *HallOfFame) Len() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.members)
+}
+
+// Choose up to numOpponents distinct members at random to act as opponents
+// for the given cortex, skipping the cortex itself.  Copies are returned,
+// so they are safe to run in a simulation.
+func (h *HallOfFame) chooseRandomOpponents(random *rand.Rand, cortex *ng.Cortex, numOpponents int) (opponents []*ng.Cortex) {
+
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	candidates := make([]*ng.Cortex, 0)
+	for _, member := range h.members {
+		if member.Cortex.NodeId.UUID == cortex.NodeId.UUID {
+			continue
+		}
+		candidates = append(candidates, member.Cortex)
+	}
+
+	opponents = make([]*ng.Cortex, 0)
+	for len(opponents) < numOpponents && len(candidates) > 0 {
+		randInt := RandomIntInRange(random, 0, len(candidates))
+		opponents = append(opponents, candidates[randInt].Copy())
+		candidates = append(candidates[:randInt], candidates[randInt+1:]...)
+	}
+	return
+
+}
+
+// The uuids of the members
+func (h *HallOfFame) uuids() (uuids []string) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for _, member := range h.members {
+		uuids = append(uuids, member.Cortex.NodeId.UUID)
+	}
+	return
+}
+
+func (h *HallOfFame) full() bool {
+	return h.MaxSize > 0 && len(h.members) >= h.MaxSize
+}
+
+func (h *HallOfFame) indexOf(uuid string) int {
+	for i, member := range h.members {
+		if member.Cortex.NodeId.UUID == uuid {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,162 @@
+package neurvolve
+
+import (
+	"context"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestHallOfFameAdd(t *testing.T) {
+
+	hallOfFame := NewHallOfFame(2)
+
+	generation1 := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 1.0},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 3.0},
+	}
+	hallOfFame.Add(generation1)
+	assert.Equals(t, hallOfFame.Len(), 2)
+
+	// cortex2 has an unlucky generation, which is averaged into its
+	// fitness, but it stays in the hall of fame
+	generation2 := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 1.0},
+		{Cortex: SingleNeuronCortex("cortex3"), Fitness: 2.5},
+	}
+	hallOfFame.Add(generation2)
+
+	members := hallOfFame.Members()
+	assert.Equals(t, len(members), 2)
+	assert.Equals(t, members[0].Cortex.NodeId.UUID, "cortex3")
+	assert.Equals(t, members[1].Cortex.NodeId.UUID, "cortex2")
+	assert.Equals(t, members[1].Fitness, 2.0)
+
+}
+
+func TestHallOfFameReevaluatedMemberMovesUp(t *testing.T) {
+
+	hallOfFame := NewHallOfFame(3)
+
+	hallOfFame.Add([]EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("top"), Fitness: 5.0},
+		{Cortex: SingleNeuronCortex("middle"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("bottom"), Fitness: 1.0},
+	})
+
+	// the middle member does well enough in a later generation to
+	// overtake the top member, and the hall of fame stays in order
+	hallOfFame.Add([]EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("middle"), Fitness: 9.0},
+	})
+
+	members := hallOfFame.Members()
+	assert.Equals(t, members[0].Cortex.NodeId.UUID, "middle")
+	assert.Equals(t, members[0].Fitness, 6.0)
+	assert.Equals(t, members[1].Cortex.NodeId.UUID, "top")
+	assert.Equals(t, members[2].Cortex.NodeId.UUID, "bottom")
+
+	// so a newcomer is compared against the least fit member, and
+	// replaces it
+	hallOfFame.Add([]EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("newcomer"), Fitness: 2.0},
+	})
+	members = hallOfFame.Members()
+	assert.Equals(t, len(members), 3)
+	assert.Equals(t, members[2].Cortex.NodeId.UUID, "newcomer")
+
+}
+
+func TestHallOfFameUnlimited(t *testing.T) {
+
+	hallOfFame := &HallOfFame{}
+
+	generation := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 1.0},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("cortex3"), Fitness: 2.0},
+	}
+	hallOfFame.Add(generation)
+	assert.Equals(t, hallOfFame.Len(), 3)
+	assert.Equals(t, hallOfFame.Members()[0].Cortex.NodeId.UUID, "cortex2")
+
+}
+
+func TestHallOfFameSnapshot(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:    1000,
+		MaxGenerations:      1000000,
+		CortexMutator:       MutateWeights,
+		HallOfFame:          NewHallOfFame(2),
+		SnapshotRequestChan: make(chan chan PopulationSnapshot),
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		SingleNeuronCortex("cortex3"),
+		SingleNeuronCortex("cortex4"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		pt.TrainContext(ctx, population, FakeScapeBiasSum{}, NullRecorder{})
+		done <- true
+	}()
+
+	// nothing has been evaluated at the start of the first generation
+	snapshot := pt.GetSnapshot()
+	assert.Equals(t, len(snapshot.Population), 4)
+	assert.Equals(t, len(snapshot.HallOfFame), 0)
+
+	snapshot = pt.GetSnapshot()
+	assert.True(t, snapshot.Generation > 0)
+	assert.Equals(t, len(snapshot.HallOfFame), 2)
+
+	cancel()
+	<-done
+
+	assert.Equals(t, len(pt.HallOfFameMembers()), 2)
+
+}
+
+func TestHallOfFameOpponents(t *testing.T) {
+
+	random := newRandom()
+
+	hallOfFame := NewHallOfFame(3)
+	cortex1 := SingleNeuronCortex("cortex1")
+	hallOfFame.Add([]EvaluatedCortex{
+		{Cortex: cortex1, Fitness: 1.0},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 2.0},
+	})
+
+	// a cortex never plays against itself, even if there
+	// are not enough other members
+	opponents := hallOfFame.chooseRandomOpponents(random, cortex1, 2)
+	assert.Equals(t, len(opponents), 1)
+	assert.Equals(t, opponents[0].NodeId.UUID, "cortex2")
+
+}
+
+func TestAddElites(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 2.0},
+		{Cortex: SingleNeuronCortex("cortex3"), Fitness: 1.0},
+	}
+
+	pt := &PopulationTrainer{NumElite: 2}
+	parents := []EvaluatedCortex{population[1], population[2]}
+	withElites := pt.addElites(population, parents)
+
+	uuids := make([]string, 0)
+	for _, evaldCortex := range withElites {
+		uuids = append(uuids, evaldCortex.Cortex.NodeId.UUID)
+	}
+	assert.DeepEquals(t, uuids, []string{"cortex1", "cortex2", "cortex3"})
+
+}
@@ -35,6 +35,11 @@ func RegisterHandlers(pt *PopulationTrainer) {
 		marshalJson(saveMap, w)
 	}
 
+	showParetoFront := func(w http.ResponseWriter, r *http.Request) {
+		snapshot := pt.GetSnapshot()
+		marshalJson(snapshot.ParetoFront, w)
+	}
+
 	showCortex := func(w http.ResponseWriter, r *http.Request) {
 		evaldPopulation := pt.GetPopulationSnapshot()
 		vars := mux.Vars(r)
@@ -61,13 +66,20 @@ func RegisterHandlers(pt *PopulationTrainer) {
 		cortex.RenderSVG(w)
 	}
 
+	showHallOfFame := func(w http.ResponseWriter, r *http.Request) {
+		snapshot := pt.GetSnapshot()
+		marshalJson(snapshot.HallOfFame, w)
+	}
+
 	r.HandleFunc("/", HomeHandler)
 	r.HandleFunc("/cortex", showAllCortexes)
 	r.HandleFunc("/cortex/uuid", showAllCortexUuids)
 	r.HandleFunc("/cortex/save", saveAllCortexes)
+	r.HandleFunc("/cortex/pareto", showParetoFront)
 	r.HandleFunc("/cortex/{cortex_uuid}", showCortex)
 	r.HandleFunc("/cortex/{cortex_uuid}/save", saveCortex)
 	r.HandleFunc("/cortex/{cortex_uuid}/svg", cortexSvgHandler)
+	r.HandleFunc("/halloffame", showHallOfFame)
 	http.Handle("/", r)
 
 }
@@ -77,9 +89,11 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 	routeMap["/cortex"] = "Show All Cortexes"
 	routeMap["/cortex/uuid"] = "Show All Cortex Uuids"
 	routeMap["/cortex/save"] = "Save All Cortexes to temp files"
+	routeMap["/cortex/pareto"] = "Show Pareto Front Cortexes"
 	routeMap["/cortex/{cortex_uuid}"] = "Show Cortex for uuid"
 	routeMap["/cortex/{cortex_uuid}/svg"] = "Show Cortex SVG for uuid"
 	routeMap["/cortex/{cortex_uuid}/save"] = "Save single cortex to temp file"
+	routeMap["/halloffame"] = "Show Hall of Fame Cortexes"
 	marshalJson(routeMap, w)
 }
 
@@ -0,0 +1,335 @@
+package neurvolve
+
+import (
+	"context"
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+)
+
+// Which islands the migrants from each island are sent to
+type MigrationTopology int
+
+const (
+	// Each island sends migrants to the next one, and the last to the first
+	RingTopology MigrationTopology = iota
+
+	// Each island sends migrants to every other island
+	FullyConnectedTopology
+
+	// Each island sends migrants to another island chosen at random
+	RandomTopology
+)
+
+// A sub-population trained by its own PopulationTrainer, which can have
+// its own CortexMutator, Selector and so on.  The MaxGenerations of the
+// trainer is ignored in favor of the IslandTrainer's.
+type Island struct {
+	Trainer    *PopulationTrainer
+	Population []*ng.Cortex
+}
+
+// Optionally implemented by a Recorder given to an IslandTrainer, to be
+// told which island each generation and fitness score belongs to.  The
+// id of an island is its index in Islands.  Recorders which don't
+// implement it are called as if there were a single population.
+type IslandRecorder interface {
+	AddIslandGeneration(islandId int, evaldCortexes []EvaluatedCortex)
+	AddIslandFitnessScore(islandId int, score float64, cortex *ng.Cortex, opponent *ng.Cortex)
+}
+
+// The island versions of the optional recorder interfaces, which are
+// passed the island id as well.  A recorder given to an IslandTrainer
+// which implements one of these is called with it instead of with the
+// interface it mirrors.
+type IslandParetoFrontRecorder interface {
+	AddIslandParetoFront(islandId int, generation int, front []EvaluatedCortex)
+}
+
+type IslandNoveltyArchiveRecorder interface {
+	AddIslandToNoveltyArchive(islandId int, generation int, cortex *ng.Cortex, behavior []float64, novelty float64)
+}
+
+type IslandStagnationRecorder interface {
+	AddIslandStagnationEvent(islandId int, event StagnationEvent)
+}
+
+type IslandMutationStatsRecorder interface {
+	AddIslandMutationStats(islandId int, generation int, stats []MutationStats)
+}
+
+type IslandLineageRecorder interface {
+	AddIslandLineage(islandId int, event LineageEvent)
+}
+
+// Trains several islands side by side, one generation at a time.  Every
+// MigrationInterval generations copies of the NumMigrants fittest cortexes
+// of each island migrate to other islands, where they replace the newest
+// offspring.  Keeping the islands mostly apart maintains more diversity
+// than a single population of the same size.
+//
+// Within each generation the islands are trained one after another, in
+// the order of Islands, so the recorder is only ever called from one
+// goroutine at a time.  Each island's trainer can still evaluate its
+// population in parallel with NumWorkers.
+type IslandTrainer struct {
+	Islands           []*Island
+	MaxGenerations    int
+	MigrationInterval int
+	NumMigrants       int
+	Topology          MigrationTopology
+
+	// If set, the migrations are drawn from Rand, along with the random
+	// choices of each island whose trainer has no Rand of its own, so
+	// that runs with the same seed are reproducible.  An island whose
+	// trainer has its own Rand always draws from that.
+	Rand *rand.Rand
+}
+
+func (it *IslandTrainer) Train(scape Scape, recorder Recorder) (trainedPopulations [][]EvaluatedCortex, succeeded bool, err error) {
+
+	trainedPopulations, stopReason, err := it.TrainContext(context.Background(), scape, recorder)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
+
+}
+
+// Train the islands until any island exceeds its trainer's fitness
+// threshold, MaxGenerations is reached or ctx is done.  Returns the most
+// recently evaluated population of each island, in the order of Islands.
+func (it *IslandTrainer) TrainContext(ctx context.Context, scape Scape, recorder Recorder) (trainedPopulations [][]EvaluatedCortex, stopReason StopReason, err error) {
+
+	if err = it.validate(); err != nil {
+		return
+	}
+
+	random := randomOrNew(it.Rand)
+
+	// the lineage of each island is reported after migration, below
+	for _, island := range it.Islands {
+		island.Trainer.holdLineage = true
+	}
+	defer func() {
+		for _, island := range it.Islands {
+			island.Trainer.holdLineage = false
+		}
+	}()
+
+	populations := make([][]EvaluatedCortex, len(it.Islands))
+	trainedPopulations = make([][]EvaluatedCortex, len(it.Islands))
+	for id, island := range it.Islands {
+		populations[id], err = island.Trainer.start(island.Population, trainerRandom(random, island.Trainer.Rand), scape, islandRecorder{recorder, id})
+		if err != nil {
+			err = fmt.Errorf("island %v: %w", id, err)
+			return
+		}
+		trainedPopulations[id] = populations[id]
+	}
+
+	for generation := 0; generation < it.MaxGenerations; generation++ {
+
+		for id, island := range it.Islands {
+			var evaluated []EvaluatedCortex
+			evaluated, populations[id], stopReason, err = island.Trainer.runGeneration(ctx, populations[id], generation, scape, islandRecorder{recorder, id})
+			if evaluated != nil {
+				trainedPopulations[id] = evaluated
+			}
+			if err != nil {
+				err = fmt.Errorf("island %v: %w", id, err)
+				return
+			}
+			if stopReason != 0 {
+				logg.LogTo("NEURVOLVE", "Island %v stopping in generation %v: %v", id, generation, stopReason)
+				return
+			}
+		}
+
+		if it.MigrationInterval > 0 && (generation+1)%it.MigrationInterval == 0 {
+			it.migrate(random, generation, trainedPopulations, populations)
+		}
+
+		// only now is it known which offspring made it into each island's
+		// next generation
+		for id, island := range it.Islands {
+			island.Trainer.recordLineage(populations[id], islandRecorder{recorder, id})
+		}
+	}
+
+	stopReason = StopReasonBudgetExhausted
+	return
+
+}
+
+// Get a snapshot of the population of the island with the given id.  The
+// island's trainer must have a SnapshotRequestChan.
+func (it *IslandTrainer) GetIslandSnapshot(islandId int) EvaluatedCortexes {
+	return it.Islands[islandId].Trainer.GetPopulationSnapshot()
+}
+
+// Copy the fittest members of each evaluated population into the next
+// generation of the islands they migrate to.  At most half of each next
+// generation is replaced by migrants.  Each immigrant's lineage records
+// the cortex it was copied from as its parent.
+func (it *IslandTrainer) migrate(random *rand.Rand, generation int, evaluatedPopulations [][]EvaluatedCortex, nextGenerations [][]EvaluatedCortex) {
+
+	immigrants := make([][]EvaluatedCortex, len(it.Islands))
+	for source, evaluated := range evaluatedPopulations {
+
+		numMigrants := it.NumMigrants
+		if numMigrants > len(evaluated) {
+			numMigrants = len(evaluated)
+		}
+
+		for _, destination := range it.destinations(random, source) {
+			for _, migrant := range evaluated[:numMigrants] {
+				immigrant := migrant
+				immigrant.Cortex = migrant.Cortex.Copy()
+				immigrant.Cortex.NodeId = ng.NewCortexId(fmt.Sprintf("cortex-%s", newUuid(random)))
+				immigrant.ParentId = migrant.Cortex.NodeId.UUID
+				immigrants[destination] = append(immigrants[destination], immigrant)
+			}
+		}
+	}
+
+	for destination, arrivals := range immigrants {
+		nextGeneration := nextGenerations[destination]
+		maxArrivals := len(nextGeneration) / 2
+		if len(arrivals) > maxArrivals {
+			arrivals = arrivals[:maxArrivals]
+		}
+
+		// the newest offspring are at the end
+		replaceFrom := len(nextGeneration) - len(arrivals)
+		copy(nextGeneration[replaceFrom:], arrivals)
+
+		trainer := it.Islands[destination].Trainer
+		for _, immigrant := range arrivals {
+			event := LineageEvent{
+				Generation:  generation,
+				ParentId:    immigrant.ParentId,
+				OffspringId: immigrant.Cortex.NodeId.UUID,
+				Migrated:    true,
+			}
+			trainer.pendingLineage = append(trainer.pendingLineage, event)
+		}
+		logg.LogTo("NEURVOLVE", "%v cortexes migrated to island %v", len(arrivals), destination)
+	}
+
+}
+
+// The ids of the islands that migrants from the source island go to
+func (it *IslandTrainer) destinations(random *rand.Rand, source int) []int {
+
+	numIslands := len(it.Islands)
+	if numIslands < 2 {
+		return []int{}
+	}
+
+	switch it.Topology {
+	case FullyConnectedTopology:
+		destinations := make([]int, 0)
+		for id := 0; id < numIslands; id++ {
+			if id != source {
+				destinations = append(destinations, id)
+			}
+		}
+		return destinations
+	case RandomTopology:
+		destination := RandomIntInRange(random, 0, numIslands-1)
+		if destination >= source {
+			destination += 1
+		}
+		return []int{destination}
+	default:
+		return []int{(source + 1) % numIslands}
+	}
+
+}
+
+func (it *IslandTrainer) validate() error {
+	if len(it.Islands) == 0 {
+		return fmt.Errorf("%w: no islands", ErrInvalidConfig)
+	}
+	for id, island := range it.Islands {
+		if island.Trainer == nil {
+			return fmt.Errorf("%w: island %v has no trainer", ErrInvalidConfig, id)
+		}
+	}
+	if it.NumMigrants < 0 || it.MigrationInterval < 0 {
+		return fmt.Errorf("%w: NumMigrants and MigrationInterval cannot be negative", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Passes the island id along to recorders which implement IslandRecorder
+type islandRecorder struct {
+	recorder Recorder
+	islandId int
+}
+
+func (r islandRecorder) AddGeneration(evaldCortexes []EvaluatedCortex) {
+	if recorder, ok := r.recorder.(IslandRecorder); ok {
+		recorder.AddIslandGeneration(r.islandId, evaldCortexes)
+		return
+	}
+	r.recorder.AddGeneration(evaldCortexes)
+}
+
+func (r islandRecorder) AddFitnessScore(score float64, cortex *ng.Cortex, opponent *ng.Cortex) {
+	if recorder, ok := r.recorder.(IslandRecorder); ok {
+		recorder.AddIslandFitnessScore(r.islandId, score, cortex, opponent)
+		return
+	}
+	r.recorder.AddFitnessScore(score, cortex, opponent)
+}
+
+func (r islandRecorder) AddParetoFront(generation int, front []EvaluatedCortex) {
+	if recorder, ok := r.recorder.(IslandParetoFrontRecorder); ok {
+		recorder.AddIslandParetoFront(r.islandId, generation, front)
+		return
+	}
+	if recorder, ok := r.recorder.(ParetoFrontRecorder); ok {
+		recorder.AddParetoFront(generation, front)
+	}
+}
+
+func (r islandRecorder) AddToNoveltyArchive(generation int, cortex *ng.Cortex, behavior []float64, novelty float64) {
+	if recorder, ok := r.recorder.(IslandNoveltyArchiveRecorder); ok {
+		recorder.AddIslandToNoveltyArchive(r.islandId, generation, cortex, behavior, novelty)
+		return
+	}
+	if recorder, ok := r.recorder.(NoveltyArchiveRecorder); ok {
+		recorder.AddToNoveltyArchive(generation, cortex, behavior, novelty)
+	}
+}
+
+func (r islandRecorder) AddStagnationEvent(event StagnationEvent) {
+	if recorder, ok := r.recorder.(IslandStagnationRecorder); ok {
+		recorder.AddIslandStagnationEvent(r.islandId, event)
+		return
+	}
+	if recorder, ok := r.recorder.(StagnationRecorder); ok {
+		recorder.AddStagnationEvent(event)
+	}
+}
+
+func (r islandRecorder) AddMutationStats(generation int, stats []MutationStats) {
+	if recorder, ok := r.recorder.(IslandMutationStatsRecorder); ok {
+		recorder.AddIslandMutationStats(r.islandId, generation, stats)
+		return
+	}
+	if recorder, ok := r.recorder.(MutationStatsRecorder); ok {
+		recorder.AddMutationStats(generation, stats)
+	}
+}
+
+func (r islandRecorder) AddLineage(event LineageEvent) {
+	if recorder, ok := r.recorder.(IslandLineageRecorder); ok {
+		recorder.AddIslandLineage(r.islandId, event)
+		return
+	}
+	if recorder, ok := r.recorder.(LineageRecorder); ok {
+		recorder.AddLineage(event)
+	}
+}
@@ -0,0 +1,200 @@
+package neurvolve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"testing"
+)
+
+func newIsland(name string, size int) *Island {
+	population := []*ng.Cortex{}
+	for i := 0; i < size; i++ {
+		population = append(population, SingleNeuronCortex(fmt.Sprintf("%v-%v", name, i)))
+	}
+	return &Island{
+		Trainer: &PopulationTrainer{
+			FitnessThreshold: 1000,
+			CortexMutator:    NoOpMutator,
+		},
+		Population: population,
+	}
+}
+
+func TestIslandDestinations(t *testing.T) {
+
+	random := newRandom()
+
+	it := &IslandTrainer{
+		Islands: []*Island{newIsland("a", 2), newIsland("b", 2), newIsland("c", 2)},
+	}
+
+	it.Topology = RingTopology
+	assert.DeepEquals(t, it.destinations(random, 2), []int{0})
+
+	it.Topology = FullyConnectedTopology
+	assert.DeepEquals(t, it.destinations(random, 1), []int{0, 2})
+
+	it.Topology = RandomTopology
+	for i := 0; i < 20; i++ {
+		destinations := it.destinations(random, 1)
+		assert.Equals(t, len(destinations), 1)
+		assert.NotEquals(t, destinations[0], 1)
+	}
+
+}
+
+func TestIslandMigration(t *testing.T) {
+
+	random := newRandom()
+
+	it := &IslandTrainer{
+		Islands:     []*Island{newIsland("a", 4), newIsland("b", 4)},
+		NumMigrants: 1,
+		Topology:    RingTopology,
+	}
+
+	evaluated := make([][]EvaluatedCortex, 2)
+	next := make([][]EvaluatedCortex, 2)
+	for id, island := range it.Islands {
+		evaluated[id] = island.Trainer.addEmptyFitnessScores(island.Population)
+		next[id] = island.Trainer.addEmptyFitnessScores(island.Population)
+	}
+
+	it.migrate(random, 0, evaluated, next)
+
+	// the fittest of each island replaces the last of the other
+	assert.Equals(t, next[1][3].ParentId, "a-0")
+	assert.Equals(t, next[0][3].ParentId, "b-0")
+	assert.NotEquals(t, next[1][3].Cortex, evaluated[0][0].Cortex)
+	assert.Equals(t, next[0][0].Cortex.NodeId.UUID, "a-0")
+
+	// and its lineage is waiting to be recorded on its new island
+	pending := it.Islands[1].Trainer.pendingLineage
+	assert.Equals(t, len(pending), 1)
+	assert.True(t, pending[0].Migrated)
+	assert.Equals(t, pending[0].ParentId, "a-0")
+	assert.Equals(t, pending[0].OffspringId, next[1][3].Cortex.NodeId.UUID)
+
+}
+
+type FakeIslandRecorder struct {
+	NullRecorder
+	generations map[int]int
+}
+
+func (r *FakeIslandRecorder) AddIslandGeneration(islandId int, evaldCortexes []EvaluatedCortex) {
+	r.generations[islandId] += 1
+}
+
+func (r *FakeIslandRecorder) AddIslandFitnessScore(islandId int, score float64, cortex *ng.Cortex, opponent *ng.Cortex) {
+}
+
+func TestIslandTrainer(t *testing.T) {
+
+	it := &IslandTrainer{
+		Islands:           []*Island{newIsland("a", 4), newIsland("b", 4), newIsland("c", 4)},
+		MaxGenerations:    5,
+		MigrationInterval: 2,
+		NumMigrants:       1,
+		Topology:          FullyConnectedTopology,
+	}
+
+	recorder := &FakeIslandRecorder{generations: make(map[int]int)}
+	trainedPopulations, succeeded, err := it.Train(FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.False(t, succeeded)
+	assert.Equals(t, len(trainedPopulations), 3)
+	for id, population := range trainedPopulations {
+		assert.Equals(t, len(population), 4)
+
+		// the initial generation plus one for each generation bred
+		assert.Equals(t, recorder.generations[id], 6)
+	}
+
+	it.Islands[1].Trainer = nil
+	_, _, err = it.Train(FakeScapeBiasSum{}, recorder)
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+}
+
+type FakeIslandLineageRecorder struct {
+	FakeIslandRecorder
+	lineage  map[int]int
+	migrated map[int]int
+}
+
+func (r *FakeIslandLineageRecorder) AddIslandLineage(islandId int, event LineageEvent) {
+	r.lineage[islandId] += 1
+	if event.Migrated {
+		r.migrated[islandId] += 1
+	}
+}
+
+func TestIslandTrainerLineage(t *testing.T) {
+
+	it := &IslandTrainer{
+		Islands:        []*Island{newIsland("a", 4), newIsland("b", 4)},
+		MaxGenerations: 3,
+	}
+
+	recorder := &FakeIslandLineageRecorder{
+		FakeIslandRecorder: FakeIslandRecorder{generations: make(map[int]int)},
+		lineage:            make(map[int]int),
+		migrated:           make(map[int]int),
+	}
+	_, _, err := it.Train(FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+
+	// half of each generation bred is new offspring
+	assert.Equals(t, recorder.lineage[0], 6)
+	assert.Equals(t, recorder.lineage[1], 6)
+	assert.Equals(t, recorder.migrated[0], 0)
+
+	// with migration every generation, an immigrant replaces one of the
+	// two offspring, whose lineage is then never recorded
+	it.MigrationInterval = 1
+	it.NumMigrants = 1
+	recorder.lineage = make(map[int]int)
+	_, _, err = it.Train(FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.Equals(t, recorder.lineage[0], 6)
+	assert.Equals(t, recorder.migrated[0], 3)
+	assert.Equals(t, recorder.migrated[1], 3)
+
+}
+
+func trainIslandsWithSeed(t *testing.T, seed int64) string {
+
+	island := newIsland("a", 4)
+	island.Trainer.CortexMutator = MutateWeights
+	island.Trainer.Rand = rand.New(rand.NewSource(42))
+
+	it := &IslandTrainer{
+		Islands:        []*Island{island, newIsland("b", 4)},
+		MaxGenerations: 3,
+		Rand:           rand.New(rand.NewSource(seed)),
+	}
+	trainedPopulations, _, err := it.Train(FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	cortexes := []*ng.Cortex{}
+	for _, evaldCortex := range trainedPopulations[0] {
+		cortexes = append(cortexes, evaldCortex.Cortex)
+	}
+	bytes, err := json.Marshal(cortexes)
+	assert.True(t, err == nil)
+	return string(bytes)
+
+}
+
+func TestIslandTrainerKeepsIslandRand(t *testing.T) {
+
+	// without migration, an island with its own Rand evolves the same
+	// way whatever the IslandTrainer's Rand is
+	assert.Equals(t, trainIslandsWithSeed(t, 1), trainIslandsWithSeed(t, 2))
+
+}
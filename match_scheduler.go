@@ -0,0 +1,270 @@
+package neurvolve
+
+import (
+	"context"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"sort"
+)
+
+// A pairing of two members of the population, by their index
+type Match struct {
+	Player   int
+	Opponent int
+}
+
+// Scapes which can play two cortexes against each other in a single
+// simulation and score both sides.  Without it, a match takes two calls
+// to FitnessAgainst, one from each side.
+type MatchScape interface {
+	Scape
+	Match(cortex *ng.Cortex, opponent *ng.Cortex) (score float64, opponentScore float64)
+}
+
+// Decides who plays whom when the fitness of a population is measured
+// by playing its members against each other.  Matches are played in
+// rounds until NextRound returns no matches, and the fitness of each
+// cortex is its average score over the matches it played.
+type MatchScheduler interface {
+
+	// The matches for the given round, which starts at 0.  standings
+	// holds the total score of each member of the population so far.
+	// Any random choices are drawn from random, the trainer's source.
+	NextRound(random *rand.Rand, round int, standings []float64, history *MatchHistory) []Match
+}
+
+// The matches played so far in a tournament
+type MatchHistory struct {
+	played     map[Match]bool
+	numMatches []int
+}
+
+func NewMatchHistory(populationSize int) *MatchHistory {
+	return &MatchHistory{
+		played:     make(map[Match]bool),
+		numMatches: make([]int, populationSize),
+	}
+}
+
+// True if the two have already played each other, in either order
+func (h *MatchHistory) Played(player, opponent int) bool {
+	return h.played[Match{player, opponent}] || h.played[Match{opponent, player}]
+}
+
+// The number of matches the member of the population has played
+func (h *MatchHistory) NumMatches(player int) int {
+	return h.numMatches[player]
+}
+
+func (h *MatchHistory) record(match Match) {
+	h.played[match] = true
+	h.numMatches[match.Player] += 1
+	h.numMatches[match.Opponent] += 1
+}
+
+// Every member of the population plays every other member once, in a
+// single round
+type RoundRobinScheduler struct{}
+
+func (s RoundRobinScheduler) NextRound(random *rand.Rand, round int, standings []float64, history *MatchHistory) (matches []Match) {
+	if round > 0 {
+		return
+	}
+	for i := range standings {
+		for j := i + 1; j < len(standings); j++ {
+			matches = append(matches, Match{i, j})
+		}
+	}
+	return
+}
+
+// A Swiss tournament of NumRounds rounds.  In each round, members are
+// paired with the next highest ranked member they have not played yet.
+// With an odd number of members, the lowest ranked one left over sits
+// the round out.
+type SwissScheduler struct {
+	NumRounds int
+}
+
+func (s SwissScheduler) NextRound(random *rand.Rand, round int, standings []float64, history *MatchHistory) (matches []Match) {
+
+	if round >= s.NumRounds {
+		return
+	}
+
+	ranking := make([]int, len(standings))
+	for i := range ranking {
+		ranking[i] = i
+	}
+	sort.SliceStable(ranking, func(a, b int) bool {
+		return standings[ranking[a]] > standings[ranking[b]]
+	})
+
+	return pairInOrder(ranking, history)
+
+}
+
+// Each member plays MatchesPerCortex matches against random opponents,
+// without any pairing being repeated while there are other opponents
+// left.  Each round is a random pairing of the whole population, and
+// those who have played the fewest matches are paired first, so the
+// number of matches never differs by more than one.
+type BalancedRandomScheduler struct {
+	MatchesPerCortex int
+}
+
+func (s BalancedRandomScheduler) NextRound(random *rand.Rand, round int, standings []float64, history *MatchHistory) (matches []Match) {
+
+	if round >= s.MatchesPerCortex {
+		return
+	}
+
+	order := random.Perm(len(standings))
+	sort.SliceStable(order, func(a, b int) bool {
+		return history.NumMatches(order[a]) < history.NumMatches(order[b])
+	})
+
+	return pairInOrder(order, history)
+
+}
+
+// Pair each unpaired member with the next unpaired member after it that
+// it hasn't played yet, or with the very next one if it has played them
+// all.  A member left over at the end is not paired.
+func pairInOrder(order []int, history *MatchHistory) (matches []Match) {
+
+	paired := make([]bool, len(order))
+	for i, player := range order {
+
+		if paired[i] {
+			continue
+		}
+
+		opponentIndex := -1
+		for j := i + 1; j < len(order); j++ {
+			if paired[j] {
+				continue
+			}
+			if opponentIndex == -1 {
+				opponentIndex = j
+			}
+			if !history.Played(player, order[j]) {
+				opponentIndex = j
+				break
+			}
+		}
+		if opponentIndex == -1 {
+			break
+		}
+
+		paired[i] = true
+		paired[opponentIndex] = true
+		matches = append(matches, Match{player, order[opponentIndex]})
+	}
+	return
+
+}
+
+// The outcome of a single match
+type matchScore struct {
+	score         float64
+	opponentScore float64
+}
+
+// Play the matches chosen by pt.MatchScheduler and return the average
+// score of each member of the population.
+func (pt *PopulationTrainer) computeTournamentFitness(ctx context.Context, population []EvaluatedCortex, scape Scape, recorder Recorder) (fitness []float64, err error) {
+
+	history := NewMatchHistory(len(population))
+	standings := make([]float64, len(population))
+
+	for round := 0; ; round++ {
+
+		matches := pt.MatchScheduler.NextRound(pt.randomSource(), round, standings, history)
+		if len(matches) == 0 {
+			break
+		}
+
+		var scores []matchScore
+		scores, err = pt.playScheduledMatches(ctx, matches, population, scape)
+		if err != nil {
+			return
+		}
+
+		for i, match := range matches {
+			player := population[match.Player].Cortex
+			opponent := population[match.Opponent].Cortex
+			pt.addFitnessScore(recorder, scores[i].score, player, opponent)
+			pt.addFitnessScore(recorder, scores[i].opponentScore, opponent, player)
+			standings[match.Player] += scores[i].score
+			standings[match.Opponent] += scores[i].opponentScore
+			history.record(match)
+		}
+	}
+
+	fitness = make([]float64, len(population))
+	for i := range population {
+		if history.NumMatches(i) > 0 {
+			fitness[i] = standings[i] / float64(history.NumMatches(i))
+		}
+	}
+	return
+
+}
+
+// Play each of the matches once, scoring both sides
+func (pt *PopulationTrainer) playScheduledMatches(ctx context.Context, matches []Match, population []EvaluatedCortex, scape Scape) (scores []matchScore, err error) {
+
+	players := make([][2]*ng.Cortex, len(matches))
+	for i, match := range matches {
+		players[i] = [2]*ng.Cortex{population[match.Player].Cortex, population[match.Opponent].Cortex}
+	}
+
+	// the error of each match, if the scape failed to score it
+	errs := make([]error, len(matches))
+
+	play := func(i int) {
+		player, opponent := players[i][0], players[i][1]
+		if matchScape, ok := scape.(MatchScape); ok {
+			scores[i].score, scores[i].opponentScore = matchScape.Match(player, opponent)
+			return
+		}
+		if scores[i].score, errs[i] = tryFitnessAgainst(scape, player, opponent); errs[i] != nil {
+			return
+		}
+		scores[i].opponentScore, errs[i] = tryFitnessAgainst(scape, opponent, player)
+	}
+
+	scores = make([]matchScore, len(matches))
+
+	if pt.NumWorkers <= 1 {
+		for i := range matches {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			play(i)
+			if errs[i] != nil {
+				err = errs[i]
+				return
+			}
+		}
+		return
+	}
+
+	// a cortex plays several matches in a round, so give each match its
+	// own copies.  as with fitness jobs, the copies are made up front.
+	for i := range players {
+		players[i] = [2]*ng.Cortex{players[i][0].Copy(), players[i][1].Copy()}
+	}
+	if err = pt.runConcurrently(ctx, len(matches), play); err != nil {
+		return
+	}
+	for _, matchErr := range errs {
+		if matchErr != nil {
+			err = matchErr
+			return
+		}
+	}
+	return
+
+}
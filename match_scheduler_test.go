@@ -0,0 +1,127 @@
+package neurvolve
+
+import (
+	"errors"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestRoundRobinScheduler(t *testing.T) {
+
+	random := newRandom()
+
+	scheduler := RoundRobinScheduler{}
+	history := NewMatchHistory(4)
+	matches := scheduler.NextRound(random, 0, make([]float64, 4), history)
+	assert.Equals(t, len(matches), 6)
+	for _, match := range matches {
+		assert.False(t, history.Played(match.Player, match.Opponent))
+		history.record(match)
+	}
+	for i := 0; i < 4; i++ {
+		assert.Equals(t, history.NumMatches(i), 3)
+	}
+	assert.Equals(t, len(scheduler.NextRound(random, 1, make([]float64, 4), history)), 0)
+
+}
+
+func TestSwissScheduler(t *testing.T) {
+
+	random := newRandom()
+
+	scheduler := SwissScheduler{NumRounds: 2}
+	history := NewMatchHistory(5)
+	standings := []float64{0, 4, 1, 3, 2}
+
+	// ranked 1, 3, 4, 2, 0 and the last one sits out
+	matches := scheduler.NextRound(random, 0, standings, history)
+	assert.DeepEquals(t, matches, []Match{{1, 3}, {4, 2}})
+	for _, match := range matches {
+		history.record(match)
+	}
+
+	// no rematches while there is anyone else left to play
+	matches = scheduler.NextRound(random, 1, standings, history)
+	assert.DeepEquals(t, matches, []Match{{1, 4}, {3, 2}})
+
+	assert.Equals(t, len(scheduler.NextRound(random, 2, standings, history)), 0)
+
+}
+
+func TestBalancedRandomScheduler(t *testing.T) {
+
+	random := newRandom()
+
+	scheduler := BalancedRandomScheduler{MatchesPerCortex: 3}
+	history := NewMatchHistory(7)
+	standings := make([]float64, 7)
+	for round := 0; ; round++ {
+		matches := scheduler.NextRound(random, round, standings, history)
+		if len(matches) == 0 {
+			break
+		}
+		for _, match := range matches {
+			history.record(match)
+		}
+	}
+
+	for i := 0; i < 7; i++ {
+		numMatches := history.NumMatches(i)
+		assert.True(t, numMatches == 2 || numMatches == 3)
+	}
+
+}
+
+type FakeMatchScape struct {
+	FakeScapeBiasSum
+	numMatches int
+}
+
+func (scape *FakeMatchScape) Match(cortex *ng.Cortex, opponent *ng.Cortex) (float64, float64) {
+	scape.numMatches += 1
+	score := scape.FitnessAgainst(cortex, opponent)
+	return score, -score
+}
+
+func TestComputeTournamentFitness(t *testing.T) {
+
+	pt := &PopulationTrainer{MatchScheduler: RoundRobinScheduler{}}
+	population := pt.addEmptyFitnessScores(biasPopulation("cortex", 0, 1, 2))
+
+	// each pairing is only simulated once
+	scape := &FakeMatchScape{}
+	evaldCortexes := pt.computeFitness(population, scape, NullRecorder{})
+	assert.Equals(t, scape.numMatches, 3)
+	assert.Equals(t, evaldCortexes[0].Fitness, 1.5)
+	assert.Equals(t, evaldCortexes[1].Fitness, 0.0)
+	assert.Equals(t, evaldCortexes[2].Fitness, -1.5)
+
+	// without a MatchScape, both sides are played
+	evaldCortexes = pt.computeFitness(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.Equals(t, evaldCortexes[0].Fitness, 1.5)
+
+	pt.NumWorkers = 4
+	evaldCortexes = pt.computeFitness(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.Equals(t, evaldCortexes[0].Fitness, 1.5)
+
+}
+
+func TestTrainWithMatchScheduler(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		MatchScheduler:   SwissScheduler{NumRounds: 2},
+	}
+
+	trainedPopulation, _, err := pt.Train(biasPopulation("cortex", 0, 1, 2, 3), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, len(trainedPopulation), 4)
+
+	pt.NumOpponents = 1
+	_, _, err = pt.Train(biasPopulation("cortex", 0, 1, 2, 3), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+}
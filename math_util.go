@@ -1,16 +1,15 @@
 package neurvolve
 
 import (
-	ng "github.com/tleyden/neurgo"
 	"math"
 	"math/rand"
 )
 
 const DEFAULT_STD_DEVIATION = 1.5
 
-func perturbParameter(parameter float64, saturationBounds []float64) float64 {
+func perturbParameter(random *rand.Rand, parameter float64, saturationBounds []float64) float64 {
 
-	parameter += ng.RandomInRange(-2*math.Pi, 2*math.Pi)
+	parameter += randomInRange(random, -2*math.Pi, 2*math.Pi)
 	return saturate(parameter, saturationBounds)
 
 }
@@ -30,10 +29,10 @@ func saturate(parameter float64, saturationBounds []float64) float64 {
 
 }
 
-func perturbParameterBellCurve(parameter float64, desiredStdDev float64) float64 {
+func perturbParameterBellCurve(random *rand.Rand, parameter float64, desiredStdDev float64) float64 {
 
 	desiredMean := parameter
-	parameter = rand.NormFloat64()*desiredStdDev + desiredMean
+	parameter = random.NormFloat64()*desiredStdDev + desiredMean
 
 	saturationBounds := []float64{-10 * math.Pi, 10 * math.Pi} // todo: pass this in as a parameter
 
@@ -10,13 +10,15 @@ import (
 
 func TestPerturbParameters(t *testing.T) {
 
+	random := newRandom()
+
 	cortex := ng.XnorCortex()
 
 	nnJson, _ := json.Marshal(cortex)
 	nnJsonString := fmt.Sprintf("%s", nnJson)
 
 	saturationBounds := []float64{-100000, 10000}
-	PerturbParameters(cortex, saturationBounds)
+	PerturbParameters(random, cortex, saturationBounds)
 
 	nnJsonAfter, _ := json.Marshal(cortex)
 	nnJsonStringAfter := fmt.Sprintf("%s", nnJsonAfter)
@@ -0,0 +1,203 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+)
+
+// A sensor which cortexes of a Morphology can be given.  The Name is
+// used as the uuid of the sensor, so the scape can tell which sensors a
+// cortex has.
+type SensorSpec struct {
+	Name         string
+	VectorLength int
+}
+
+// An actuator which cortexes of a Morphology can be given.  Each of its
+// VectorLength inputs comes from a separate neuron.
+type ActuatorSpec struct {
+	Name         string
+	VectorLength int
+}
+
+// The catalog of sensors and actuators available to a kind of agent, as
+// in DXNN.  The mutators made by AddSensorMutator, AddActuatorMutator
+// and RemoveSensorMutator let evolution discover which of them matter.
+// The scape has to find the sensors and actuators of each cortex by uuid,
+// since a cortex may have any of them, in any order.
+type Morphology struct {
+	Sensors   []SensorSpec
+	Actuators []ActuatorSpec
+}
+
+// The mutators which change the sensors and actuators of a cortex
+func (m *Morphology) Mutators() []CortexMutator {
+	return []CortexMutator{
+		AddSensorMutator(m),
+		AddActuatorMutator(m),
+		RemoveSensorMutator(m),
+	}
+}
+
+// A mutator which adds a sensor from the catalog that the cortex doesn't
+// have yet, connected to a random neuron with a weight for each element
+// of the sensor's vector
+func AddSensorMutator(morphology *Morphology) CortexMutator {
+	return func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+
+		available := make([]SensorSpec, 0)
+		for _, spec := range morphology.Sensors {
+			if !hasSensor(cortex, spec.Name) {
+				available = append(available, spec)
+			}
+		}
+		if len(available) == 0 || len(cortex.Neurons) == 0 {
+			return false, nil
+		}
+		spec := available[RandomIntInRange(random, 0, len(available))]
+
+		sensor := &ng.Sensor{
+			NodeId:       ng.NewSensorId(spec.Name, 0.0),
+			VectorLength: spec.VectorLength,
+		}
+		sensor.Init()
+		cortex.SetSensors(append(cortex.Sensors, sensor))
+
+		neuron := randomNeuron(random, cortex)
+		neuronAddInlinkFrom(random, neuron, sensor.NodeId)
+
+		record := newMutationRecord("AddSensor")
+		record.addNode(sensor.NodeId)
+		record.addLink(cortex, sensor.NodeId, neuron.NodeId)
+		return true, record
+	}
+}
+
+// A mutator which adds an actuator from the catalog that the cortex
+// doesn't have yet, fed by as many different random neurons as it has
+// inputs
+func AddActuatorMutator(morphology *Morphology) CortexMutator {
+	return func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+
+		available := make([]ActuatorSpec, 0)
+		for _, spec := range morphology.Actuators {
+			if !hasActuator(cortex, spec.Name) && spec.VectorLength > 0 && spec.VectorLength <= len(cortex.Neurons) {
+				available = append(available, spec)
+			}
+		}
+		if len(available) == 0 {
+			return false, nil
+		}
+		spec := available[RandomIntInRange(random, 0, len(available))]
+
+		actuator := &ng.Actuator{
+			NodeId:       ng.NewActuatorId(spec.Name, actuatorLayer(cortex)),
+			VectorLength: spec.VectorLength,
+		}
+		actuator.Init()
+		cortex.SetActuators(append(cortex.Actuators, actuator))
+
+		record := newMutationRecord("AddActuator")
+		record.addNode(actuator.NodeId)
+
+		order := random.Perm(len(cortex.Neurons))
+		for i := 0; actuator.CanAddInboundConnection() && i < len(order); i++ {
+			neuron := cortex.Neurons[order[i]]
+			neuronAddOutlinkTo(random, neuron, actuator.NodeId)
+			record.addLink(cortex, neuron.NodeId, actuator.NodeId)
+		}
+
+		return true, record
+	}
+}
+
+// A mutator which removes one of the cortex's sensors that is in the
+// catalog.  Sensors which aren't in the catalog are never removed, and
+// neither is a sensor which is the only input of one of its neurons, or
+// the cortex's last sensor.
+func RemoveSensorMutator(morphology *Morphology) CortexMutator {
+	return func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+
+		if len(cortex.Sensors) < 2 {
+			return false, nil
+		}
+
+		removable := make([]*ng.Sensor, 0)
+		for _, sensor := range cortex.Sensors {
+			if morphology.hasSensorSpec(sensor.NodeId.UUID) && canRemoveSensor(cortex, sensor) {
+				removable = append(removable, sensor)
+			}
+		}
+		if len(removable) == 0 {
+			return false, nil
+		}
+		sensor := removable[RandomIntInRange(random, 0, len(removable))]
+
+		record := newMutationRecord("RemoveSensor")
+		record.removeNode(sensor.NodeId)
+		for _, connection := range sensor.Outbound {
+			record.removeLink(cortex, sensor.NodeId, connection.NodeId)
+		}
+
+		for _, connection := range sensor.Outbound {
+			ng.DisconnectInbound(cortex.FindInboundConnector(connection.NodeId), sensor.NodeId)
+		}
+		sensors := make([]*ng.Sensor, 0)
+		for _, other := range cortex.Sensors {
+			if other != sensor {
+				sensors = append(sensors, other)
+			}
+		}
+		cortex.SetSensors(sensors)
+
+		return true, record
+	}
+}
+
+func (m *Morphology) hasSensorSpec(name string) bool {
+	for _, spec := range m.Sensors {
+		if spec.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasSensor(cortex *ng.Cortex, uuid string) bool {
+	for _, sensor := range cortex.Sensors {
+		if sensor.NodeId.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+func hasActuator(cortex *ng.Cortex, uuid string) bool {
+	for _, actuator := range cortex.Actuators {
+		if actuator.NodeId.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// Every neuron the sensor feeds has to have another input
+func canRemoveSensor(cortex *ng.Cortex, sensor *ng.Sensor) bool {
+	for _, connection := range sensor.Outbound {
+		if numInboundFromOthers(cortex, connection.NodeId) < 2 {
+			return false
+		}
+	}
+	return true
+}
+
+// The layer of the existing actuators, which come after all the neurons
+func actuatorLayer(cortex *ng.Cortex) float64 {
+	layer := 1.0
+	for i, actuator := range cortex.Actuators {
+		if i == 0 || actuator.NodeId.LayerIndex > layer {
+			layer = actuator.NodeId.LayerIndex
+		}
+	}
+	return layer
+}
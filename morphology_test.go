@@ -0,0 +1,109 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func testMorphology() *Morphology {
+	return &Morphology{
+		Sensors: []SensorSpec{
+			{Name: "sensor", VectorLength: 2},
+			{Name: "camera", VectorLength: 3},
+		},
+		Actuators: []ActuatorSpec{
+			{Name: "actuator", VectorLength: 1},
+			{Name: "wheels", VectorLength: 2},
+		},
+	}
+}
+
+func TestAddSensorMutator(t *testing.T) {
+
+	random := newRandom()
+
+	cortex := BasicCortex()
+	addSensor := AddSensorMutator(testMorphology())
+
+	ok, mutateResult := addSensor(random, cortex)
+	assert.True(t, ok)
+	sensor := cortex.FindSensor(mutateResult.AddedNodes[0])
+	assert.Equals(t, sensor.NodeId.UUID, "camera")
+	assert.Equals(t, len(cortex.Sensors), 2)
+	assert.Equals(t, len(sensor.Outbound), 1)
+	assert.True(t, cortex.Validate())
+
+	// the neuron it feeds has a weight for each element of its vector
+	neuron := cortex.FindNeuron(sensor.Outbound[0].NodeId)
+	inbound := neuron.InboundUUIDMap()["camera"]
+	assert.Equals(t, len(inbound.Weights), 3)
+
+	// there are no more sensors in the catalog
+	ok, _ = addSensor(random, cortex)
+	assert.False(t, ok)
+
+}
+
+func TestAddActuatorMutator(t *testing.T) {
+
+	random := newRandom()
+
+	cortex := BasicCortex()
+	addActuator := AddActuatorMutator(testMorphology())
+
+	ok, mutateResult := addActuator(random, cortex)
+	assert.True(t, ok)
+	actuator := cortex.FindActuator(mutateResult.AddedNodes[0])
+	assert.Equals(t, actuator.NodeId.UUID, "wheels")
+	assert.Equals(t, len(mutateResult.AddedLinks), 2)
+	assert.Equals(t, actuator.NodeId.LayerIndex, cortex.Actuators[0].NodeId.LayerIndex)
+	assert.Equals(t, len(cortex.Actuators), 2)
+	assert.True(t, cortex.Validate())
+
+	// fed by two different neurons
+	assert.Equals(t, len(actuator.Inbound), 2)
+	assert.False(t, actuator.CanAddInboundConnection())
+	assert.NotEquals(t, actuator.Inbound[0].NodeId.UUID, actuator.Inbound[1].NodeId.UUID)
+
+	ok, _ = addActuator(random, cortex)
+	assert.False(t, ok)
+
+}
+
+func TestRemoveSensorMutator(t *testing.T) {
+
+	random := newRandom()
+
+	morphology := testMorphology()
+	cortex := BasicCortex()
+	removeSensor := RemoveSensorMutator(morphology)
+
+	// the only sensor can't be removed
+	ok, _ := removeSensor(random, cortex)
+	assert.False(t, ok)
+
+	// nor can the new sensor while it is the only input of its neuron,
+	// so give the first hidden neuron a second input from it
+	camera := &ng.Sensor{
+		NodeId:       ng.NewSensorId("camera", 0.0),
+		VectorLength: 3,
+	}
+	camera.Init()
+	cortex.SetSensors(append(cortex.Sensors, camera))
+	neuronAddInlinkFrom(random, cortex.Neurons[0], camera.NodeId)
+
+	ok, mutateResult := removeSensor(random, cortex)
+	assert.True(t, ok)
+	assert.Equals(t, len(cortex.Sensors), 1)
+	assert.True(t, cortex.Validate())
+
+	// either sensor could have gone, but the first hidden neuron is
+	// still connected to the one that remains
+	remaining := cortex.Sensors[0]
+	removed := mutateResult.RemovedNodes[0]
+	assert.NotEquals(t, remaining.NodeId.UUID, removed.UUID)
+	assert.Equals(t, len(cortex.Neurons[0].Inbound), 1)
+	assert.Equals(t, cortex.Neurons[0].Inbound[0].NodeId.UUID, remaining.NodeId.UUID)
+
+}
@@ -0,0 +1,200 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Scapes which measure a cortex against several objectives at once, such
+// as task performance, network size and evaluation latency.  Every
+// objective is maximized, so costs should be returned as negative numbers.
+// The first objective doubles as the fitness of the cortex, which is what
+// the fitness threshold and the hall of fame are based on.
+type MultiObjectiveScape interface {
+	Scape
+	Objectives(cortex *ng.Cortex) []float64
+}
+
+// Optionally implemented by a Recorder to be told the Pareto front of
+// each generation of a multi-objective run
+type ParetoFrontRecorder interface {
+	AddParetoFront(generation int, front []EvaluatedCortex)
+}
+
+// Wraps a scape to trade its fitness off against the size of the network
+// and the time taken to evaluate it.  The objectives are the fitness, the
+// negated number of neurons and connections, and the negated number of
+// seconds taken to compute the fitness.
+type NetworkCostScape struct {
+	Scape
+}
+
+func (scape NetworkCostScape) Objectives(cortex *ng.Cortex) []float64 {
+
+	startTime := time.Now()
+	fitness := scape.Fitness(cortex)
+	latency := time.Since(startTime).Seconds()
+
+	size := len(cortex.Neurons)
+	for _, neuron := range cortex.Neurons {
+		size += len(neuron.Inbound)
+	}
+
+	return []float64{fitness, -float64(size), -latency}
+}
+
+// True if the objectives are at least as good as the other objectives in
+// every respect, and better in at least one
+func dominates(objectives, otherObjectives []float64) bool {
+	better := false
+	for i := range objectives {
+		if i >= len(otherObjectives) {
+			break
+		}
+		if objectives[i] < otherObjectives[i] {
+			return false
+		}
+		if objectives[i] > otherObjectives[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// Sort the population by Pareto rank, and within each rank by crowding
+// distance, most isolated first.  This is the order in which NSGA-II
+// prefers cortexes.  ParetoRank and CrowdingDistance are set on each
+// member of the population.
+func sortByParetoRank(population EvaluatedCortexes) {
+	for _, front := range paretoFronts(population) {
+		assignCrowdingDistance(population, front)
+	}
+	sort.SliceStable(population, func(i, j int) bool {
+		if population[i].ParetoRank != population[j].ParetoRank {
+			return population[i].ParetoRank < population[j].ParetoRank
+		}
+		return population[i].CrowdingDistance > population[j].CrowdingDistance
+	})
+}
+
+// Split the population into successive non-dominated fronts, setting the
+// ParetoRank of each member.  The first front has rank 1.
+func paretoFronts(population EvaluatedCortexes) (fronts [][]int) {
+
+	dominatedBy := make([][]int, len(population))
+	numDominating := make([]int, len(population))
+
+	front := make([]int, 0)
+	for i := range population {
+		for j := range population {
+			if i == j {
+				continue
+			}
+			if dominates(population[i].Objectives, population[j].Objectives) {
+				dominatedBy[i] = append(dominatedBy[i], j)
+			} else if dominates(population[j].Objectives, population[i].Objectives) {
+				numDominating[i] += 1
+			}
+		}
+		if numDominating[i] == 0 {
+			front = append(front, i)
+		}
+	}
+
+	fronts = make([][]int, 0)
+	for rank := 1; len(front) > 0; rank++ {
+		fronts = append(fronts, front)
+		nextFront := make([]int, 0)
+		for _, i := range front {
+			population[i].ParetoRank = rank
+			for _, j := range dominatedBy[i] {
+				numDominating[j] -= 1
+				if numDominating[j] == 0 {
+					nextFront = append(nextFront, j)
+				}
+			}
+		}
+		front = nextFront
+	}
+	return
+
+}
+
+// Set the crowding distance of each member of the front: the sum over
+// all objectives of the normalized distance between its neighbours.
+// Members at either end of the front for any objective are given an
+// infinite distance so they are always kept.
+func assignCrowdingDistance(population EvaluatedCortexes, front []int) {
+
+	for _, i := range front {
+		population[i].CrowdingDistance = 0
+	}
+	if len(front) == 0 {
+		return
+	}
+
+	numObjectives := len(population[front[0]].Objectives)
+	sorted := append([]int{}, front...)
+	for objective := 0; objective < numObjectives; objective++ {
+
+		value := func(i int) float64 {
+			return population[sorted[i]].Objectives[objective]
+		}
+		sort.SliceStable(sorted, func(a, b int) bool {
+			return population[sorted[a]].Objectives[objective] < population[sorted[b]].Objectives[objective]
+		})
+
+		last := len(sorted) - 1
+		population[sorted[0]].CrowdingDistance = math.Inf(1)
+		population[sorted[last]].CrowdingDistance = math.Inf(1)
+
+		valueRange := value(last) - value(0)
+		if valueRange == 0 {
+			continue
+		}
+		for i := 1; i < last; i++ {
+			population[sorted[i]].CrowdingDistance += (value(i+1) - value(i-1)) / valueRange
+		}
+	}
+
+}
+
+// The members of the population with a Pareto rank of 1, which no other
+// member dominates.  The ranks must already have been assigned.
+func (evaldCortexes EvaluatedCortexes) ParetoFront() EvaluatedCortexes {
+	front := make(EvaluatedCortexes, 0)
+	for _, evaldCortex := range evaldCortexes {
+		if evaldCortex.ParetoRank == 1 {
+			front = append(front, evaldCortex)
+		}
+	}
+	return front
+}
+
+// Binary tournaments decided by the crowded comparison of NSGA-II: the
+// lower Pareto rank wins, and between equal ranks the larger crowding
+// distance wins.  Use it with a MultiObjective PopulationTrainer.
+type CrowdedTournamentSelector struct{}
+
+func (s CrowdedTournamentSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+	parents = make([]EvaluatedCortex, 0)
+	for i := 0; i < numParents; i++ {
+		winner := population[RandomIntInRange(random, 0, len(population))]
+		contender := population[RandomIntInRange(random, 0, len(population))]
+		if crowdedLess(contender, winner) {
+			winner = contender
+		}
+		parents = append(parents, winner)
+	}
+	return
+}
+
+func crowdedLess(evaldCortex, other EvaluatedCortex) bool {
+	if evaldCortex.ParetoRank != other.ParetoRank {
+		return evaldCortex.ParetoRank < other.ParetoRank
+	}
+	return evaldCortex.CrowdingDistance > other.CrowdingDistance
+}
@@ -0,0 +1,129 @@
+package neurvolve
+
+import (
+	"context"
+	"errors"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"testing"
+)
+
+func TestDominates(t *testing.T) {
+	assert.True(t, dominates([]float64{2, 2}, []float64{1, 2}))
+	assert.False(t, dominates([]float64{2, 2}, []float64{2, 2}))
+	assert.False(t, dominates([]float64{2, 1}, []float64{1, 2}))
+}
+
+func TestSortByParetoRank(t *testing.T) {
+
+	population := EvaluatedCortexes{
+		{Objectives: []float64{1, 1}},
+		{Objectives: []float64{3, 1}},
+		{Objectives: []float64{2, 2}},
+		{Objectives: []float64{1, 3}},
+		{Objectives: []float64{0, 0}},
+	}
+	sortByParetoRank(population)
+
+	assert.Equals(t, len(population.ParetoFront()), 3)
+	assert.Equals(t, population[3].ParetoRank, 2)
+	assert.DeepEquals(t, population[3].Objectives, []float64{1, 1})
+	assert.Equals(t, population[4].ParetoRank, 3)
+
+	// the ends of the front are the most isolated, the middle is not
+	assert.Equals(t, population[0].CrowdingDistance, math.Inf(1))
+	assert.Equals(t, population[1].CrowdingDistance, math.Inf(1))
+	assert.DeepEquals(t, population[2].Objectives, []float64{2, 2})
+	assert.Equals(t, population[2].CrowdingDistance, 2.0)
+
+}
+
+type FakeScapeMultiObjective struct {
+	FakeScapeBiasSum
+}
+
+func (scape FakeScapeMultiObjective) Objectives(cortex *ng.Cortex) []float64 {
+	return []float64{scape.Fitness(cortex), -float64(len(cortex.Neurons))}
+}
+
+type FakeParetoFrontRecorder struct {
+	NullRecorder
+	fronts [][]EvaluatedCortex
+}
+
+func (r *FakeParetoFrontRecorder) AddParetoFront(generation int, front []EvaluatedCortex) {
+	r.fronts = append(r.fronts, front)
+}
+
+func TestTrainMultiObjective(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		MultiObjective:   true,
+		Selector:         CrowdedTournamentSelector{},
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		BasicCortex(),
+	}
+
+	recorder := &FakeParetoFrontRecorder{}
+	trainedPopulation, _, err := pt.Train(population, FakeScapeMultiObjective{}, recorder)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(trainedPopulation), 3)
+	assert.Equals(t, len(recorder.fronts), 3)
+	for _, evaldCortex := range trainedPopulation {
+		assert.Equals(t, len(evaldCortex.Objectives), 2)
+		assert.True(t, evaldCortex.ParetoRank > 0)
+	}
+
+	_, _, err = pt.Train(population, FakeScapeBiasSum{}, recorder)
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+}
+
+func TestParetoFrontSnapshot(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold:    1000,
+		MaxGenerations:      1000000,
+		CortexMutator:       NoOpMutator,
+		MultiObjective:      true,
+		SnapshotRequestChan: make(chan chan PopulationSnapshot),
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		BasicCortex(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		pt.TrainContext(ctx, population, FakeScapeMultiObjective{}, NullRecorder{})
+		done <- true
+	}()
+
+	// nothing has been evaluated at the start of the first generation
+	snapshot := pt.GetSnapshot()
+	assert.Equals(t, len(snapshot.ParetoFront), 0)
+
+	// after that, the front is the one ranked after evaluation, even
+	// though the offspring in the population have not been ranked yet
+	snapshot = pt.GetSnapshot()
+	assert.True(t, len(snapshot.ParetoFront) > 0)
+	for _, evaldCortex := range snapshot.ParetoFront {
+		assert.Equals(t, evaldCortex.ParetoRank, 1)
+		assert.Equals(t, len(evaldCortex.Objectives), 2)
+	}
+
+	cancel()
+	<-done
+
+}
@@ -0,0 +1,455 @@
+package neurvolve
+
+import (
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// The number of operators a MutationPolicy tries before giving up, unless
+// its MaxAttempts says otherwise
+const DEFAULT_MUTATION_ATTEMPTS = 100
+
+// A mutation operator, and how likely a MutationPolicy is to choose it
+type MutationOperator struct {
+	Name    string
+	Mutator CortexMutator
+
+	// Relative to the probabilities of the other operators of the
+	// policy, so they don't have to add up to 1
+	Probability float64
+}
+
+// How an operator of a MutationPolicy has fared so far
+type MutationStats struct {
+	Name string
+
+	// The probability of the operator being chosen, which changes over
+	// time if the policy is adaptive
+	Probability float64
+
+	// The number of times the operator was chosen, and the number of
+	// those times it managed to mutate the cortex
+	Attempts  int
+	Successes int
+
+	// The number of offspring it produced which were fitter than their
+	// parent
+	Improvements int
+}
+
+// Recorders which implement this are given the stats of the
+// PopulationTrainer's MutationPolicy after each generation is evaluated
+type MutationStatsRecorder interface {
+	AddMutationStats(generation int, stats []MutationStats)
+}
+
+// Chooses which mutation operator to apply to a cortex, at random
+// according to the probability of each, and keeps stats on how each of
+// them fares.  If an operator fails to mutate the cortex, another one is
+// chosen, up to MaxAttempts times.
+//
+// If Adaptive is set, the probabilities follow the operators' record of
+// producing offspring fitter than their parents, by probability matching:
+// each operator keeps an estimate of its chance of an improvement, which
+// moves towards each new result at the AdaptationRate, and its probability
+// is its share of the estimates, but never less than MinProbability.
+// Operators with a Probability of 0 are never chosen, even then.
+//
+// Its Mutate method is a CortexMutator, but only trainers which know about
+// the policy, such as the PopulationTrainer and TopologyMutatingTrainer
+// when it is set as their MutationPolicy, tell it which offspring improved.
+type MutationPolicy struct {
+	Operators []MutationOperator
+
+	// 0 means DEFAULT_MUTATION_ATTEMPTS
+	MaxAttempts int
+
+	Adaptive bool
+
+	// Defaults to 0.1
+	AdaptationRate float64
+
+	// Defaults to a fifth of the probability each operator would have if
+	// they were all equally likely, leaving out those with a Probability
+	// of 0
+	MinProbability float64
+
+	stats   []MutationStats
+	quality []float64
+	mutex   sync.Mutex
+}
+
+// A policy which is equally likely to choose any of the mutators.  Each
+// operator is named after its mutator function.
+func NewMutationPolicy(mutators []CortexMutator) *MutationPolicy {
+	operators := make([]MutationOperator, 0)
+	for _, mutator := range mutators {
+		operator := MutationOperator{
+			Name:        mutatorName(mutator),
+			Mutator:     mutator,
+			Probability: 1.0,
+		}
+		operators = append(operators, operator)
+	}
+	return &MutationPolicy{Operators: operators}
+}
+
+// Apply an operator chosen by the policy to the cortex
+func (p *MutationPolicy) Mutate(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	_, success, result = p.mutate(random, cortex)
+	return
+}
+
+// The stats of each operator, in the same order as the Operators
+func (p *MutationPolicy) Stats() []MutationStats {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.initStats()
+	probabilities := p.probabilities()
+	stats := make([]MutationStats, len(p.stats))
+	for i, operatorStats := range p.stats {
+		stats[i] = operatorStats
+		stats[i].Probability = probabilities[i]
+	}
+	return stats
+
+}
+
+// Returns the index of the operator which mutated the cortex, or -1 if
+// none of them did
+func (p *MutationPolicy) mutate(random *rand.Rand, cortex *ng.Cortex) (operator int, success bool, result MutateResult) {
+
+	operator = -1
+	if len(p.Operators) == 0 {
+		return
+	}
+
+	for i := 0; i < p.maxAttempts(); i++ {
+		chosen := p.choose(random)
+		success, result = p.Operators[chosen].Mutator(random, cortex)
+		p.recordAttempt(chosen, success)
+		if success {
+			operator = chosen
+			if result == nil {
+				result = newMutationRecord(p.Operators[chosen].Name)
+			}
+			return
+		}
+		logg.LogTo("NEURVOLVE", "Mutate with %v didn't work, retrying...", p.Operators[chosen].Name)
+	}
+	return
+
+}
+
+// Like mutate, but returns ErrMutationFailed if no operator could mutate
+// the cortex, or if one panicked while trying
+func (p *MutationPolicy) apply(random *rand.Rand, cortex *ng.Cortex) (operator int, record *MutationRecord, err error) {
+	record, err = ApplyMutator(random, cortex, func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+		operator, success, result = p.mutate(random, cortex)
+		return
+	})
+	return
+}
+
+// Make a single attempt at mutating the cortex, with an operator chosen
+// by the policy.  Returns ErrMutationFailed if the operator failed,
+// panicked or left the cortex invalid, and the attempt only counts as a
+// success in the operator's stats if it did none of those.
+func (p *MutationPolicy) attempt(random *rand.Rand, cortex *ng.Cortex) (operator int, record *MutationRecord, err error) {
+
+	operator = p.choose(random)
+	name := p.Operators[operator].Name
+
+	record, err = ApplyMutator(random, cortex, p.Operators[operator].Mutator)
+	if err == nil && !cortex.Validate() {
+		err = fmt.Errorf("%w: %v left cortex %v invalid", ErrMutationFailed, name, cortex.NodeId.UUID)
+	}
+	p.recordAttempt(operator, err == nil)
+
+	if err != nil {
+		record = nil
+	} else if record == nil {
+		record = newMutationRecord(name)
+	}
+	return
+
+}
+
+func (p *MutationPolicy) choose(random *rand.Rand) int {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.initStats()
+	probabilities := p.probabilities()
+	r := random.Float64()
+	for i, probability := range probabilities {
+		r -= probability
+		if r < 0 {
+			return i
+		}
+	}
+
+	// rounding errors can leave a little over, which goes to the last
+	// operator that can be chosen at all
+	for i := len(probabilities) - 1; i > 0; i-- {
+		if probabilities[i] > 0 {
+			return i
+		}
+	}
+	return 0
+
+}
+
+func (p *MutationPolicy) recordAttempt(operator int, success bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.initStats()
+	p.stats[operator].Attempts += 1
+	if success {
+		p.stats[operator].Successes += 1
+	}
+}
+
+// Tell the policy whether an offspring produced by the operator was
+// fitter than its parent
+func (p *MutationPolicy) credit(operator int, improved bool) {
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.initStats()
+	reward := 0.0
+	if improved {
+		p.stats[operator].Improvements += 1
+		reward = 1.0
+	}
+	p.quality[operator] += p.adaptationRate() * (reward - p.quality[operator])
+
+}
+
+// Copies of the stats and of the estimate of each operator's quality,
+// for saving in a checkpoint
+func (p *MutationPolicy) state() (stats []MutationStats, quality []float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.initStats()
+	return append([]MutationStats{}, p.stats...), append([]float64{}, p.quality...)
+}
+
+// Restore the stats and quality estimates saved in a checkpoint.  They
+// are ignored if the number of operators has changed since.
+func (p *MutationPolicy) restore(stats []MutationStats, quality []float64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(stats) != len(p.Operators) || len(quality) != len(p.Operators) {
+		return
+	}
+	p.stats = append([]MutationStats{}, stats...)
+	p.quality = append([]float64{}, quality...)
+}
+
+// Set up the stats the first time they are needed, or again if the
+// operators have changed since
+func (p *MutationPolicy) initStats() {
+
+	if len(p.stats) == len(p.Operators) {
+		return
+	}
+
+	p.stats = make([]MutationStats, len(p.Operators))
+	for i, operator := range p.Operators {
+		p.stats[i].Name = operator.Name
+	}
+	p.quality = p.initialProbabilities()
+
+}
+
+// The probability of choosing each operator.  Must be called with the
+// mutex held, after initStats.
+func (p *MutationPolicy) probabilities() []float64 {
+
+	initial := p.initialProbabilities()
+	if !p.Adaptive {
+		return initial
+	}
+
+	// an operator with a Probability of 0 is never chosen, so only the
+	// others share in the probability
+	numEnabled := 0
+	totalQuality := 0.0
+	for i, quality := range p.quality {
+		if initial[i] > 0 {
+			numEnabled += 1
+			totalQuality += quality
+		}
+	}
+	minProbability := p.minProbability(numEnabled)
+
+	probabilities := make([]float64, len(p.quality))
+	for i, quality := range p.quality {
+		if initial[i] == 0 {
+			continue
+		}
+		share := 1 / float64(numEnabled)
+		if totalQuality > 0 {
+			share = quality / totalQuality
+		}
+		probabilities[i] = minProbability + (1-float64(numEnabled)*minProbability)*share
+	}
+	return probabilities
+
+}
+
+// The operators' probabilities, normalized to add up to 1
+func (p *MutationPolicy) initialProbabilities() []float64 {
+
+	total := 0.0
+	for _, operator := range p.Operators {
+		total += operator.Probability
+	}
+
+	probabilities := make([]float64, len(p.Operators))
+	for i, operator := range p.Operators {
+		if total > 0 {
+			probabilities[i] = operator.Probability / total
+		} else {
+			probabilities[i] = 1 / float64(len(p.Operators))
+		}
+	}
+	return probabilities
+
+}
+
+func (p *MutationPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DEFAULT_MUTATION_ATTEMPTS
+	}
+	return p.MaxAttempts
+}
+
+func (p *MutationPolicy) adaptationRate() float64 {
+	if p.AdaptationRate <= 0 {
+		return 0.1
+	}
+	return p.AdaptationRate
+}
+
+// The MinProbability, or its default when numEnabled operators can be
+// chosen
+func (p *MutationPolicy) minProbability(numEnabled int) float64 {
+	if p.MinProbability <= 0 {
+		return 0.2 / float64(numEnabled)
+	}
+	return p.MinProbability
+}
+
+func (p *MutationPolicy) validate() error {
+	if len(p.Operators) == 0 {
+		return fmt.Errorf("%w: MutationPolicy has no Operators", ErrInvalidConfig)
+	}
+	total := 0.0
+	numEnabled := 0
+	for _, operator := range p.Operators {
+		if operator.Mutator == nil {
+			return fmt.Errorf("%w: mutation operator %v has no Mutator", ErrInvalidConfig, operator.Name)
+		}
+		if operator.Probability < 0 {
+			return fmt.Errorf("%w: mutation operator %v has a negative Probability", ErrInvalidConfig, operator.Name)
+		}
+		total += operator.Probability
+		if operator.Probability > 0 {
+			numEnabled += 1
+		}
+	}
+	if total == 0 {
+		return fmt.Errorf("%w: mutation operators all have a Probability of 0", ErrInvalidConfig)
+	}
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("%w: MaxAttempts cannot be negative", ErrInvalidConfig)
+	}
+	if p.AdaptationRate < 0 || p.AdaptationRate > 1 {
+		return fmt.Errorf("%w: AdaptationRate must be between 0 and 1", ErrInvalidConfig)
+	}
+	if p.MinProbability < 0 || p.MinProbability*float64(numEnabled) >= 1 {
+		return fmt.Errorf("%w: MinProbability must be at least 0 and less than 1 / the number of Operators with a Probability above 0", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// The name of the mutator function, without its package
+func mutatorName(mutator CortexMutator) string {
+	name := runtime.FuncForPC(reflect.ValueOf(mutator).Pointer()).Name()
+	if slash := strings.LastIndex(name, "/"); slash >= 0 {
+		name = name[slash+1:]
+	}
+	if dot := strings.Index(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+// The parent's fitness and the MutationPolicy operators which produced an
+// offspring, so they can be credited once the offspring is evaluated
+type mutationCredit struct {
+	parentFitness float64
+	operators     []int
+}
+
+func (pt *PopulationTrainer) expectMutationCredit(offspring *ng.Cortex, parentFitness float64, operators []int) {
+	if pt.MutationPolicy == nil || len(operators) == 0 {
+		return
+	}
+	if pt.mutationCredits == nil {
+		pt.mutationCredits = make(map[string]mutationCredit)
+	}
+	pt.mutationCredits[offspring.NodeId.UUID] = mutationCredit{
+		parentFitness: parentFitness,
+		operators:     operators,
+	}
+}
+
+// The fitness the scape gave the parent, which with NoveltySearch is not
+// its Fitness, since that has been blended with its novelty by the time
+// it breeds.  Offspring are credited by comparing it with their own
+// fitness, before any novelty is blended in.
+func (pt *PopulationTrainer) rawFitness(parent EvaluatedCortex) float64 {
+	if pt.NoveltySearch != nil {
+		return parent.RawFitness
+	}
+	return parent.Fitness
+}
+
+// Credit the operators which produced each evaluated offspring with
+// whether it is fitter than its parent, and pass the stats on to the
+// recorder
+func (pt *PopulationTrainer) creditMutations(evaluated []EvaluatedCortex, generation int, recorder Recorder) {
+
+	if pt.MutationPolicy == nil {
+		return
+	}
+
+	for _, evaldCortex := range evaluated {
+		credit, ok := pt.mutationCredits[evaldCortex.Cortex.NodeId.UUID]
+		if !ok {
+			continue
+		}
+		for _, operator := range credit.operators {
+			pt.MutationPolicy.credit(operator, evaldCortex.Fitness > credit.parentFitness)
+		}
+	}
+	pt.mutationCredits = nil
+
+	if statsRecorder, ok := recorder.(MutationStatsRecorder); ok {
+		statsRecorder.AddMutationStats(generation, pt.MutationPolicy.Stats())
+	}
+
+}
@@ -0,0 +1,181 @@
+package neurvolve
+
+import (
+	"context"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"testing"
+)
+
+type FakeMutationStatsRecorder struct {
+	NullRecorder
+	generations []int
+	stats       [][]MutationStats
+}
+
+func (r *FakeMutationStatsRecorder) AddMutationStats(generation int, stats []MutationStats) {
+	r.generations = append(r.generations, generation)
+	r.stats = append(r.stats, stats)
+}
+
+func failingMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	return false, nil
+}
+
+func addToBiases(amount float64) CortexMutator {
+	return func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+		for _, neuron := range cortex.Neurons {
+			neuron.Bias += amount
+		}
+		return true, newMutationRecord("addToBiases")
+	}
+}
+
+func TestNewMutationPolicy(t *testing.T) {
+
+	policy := NewMutationPolicy([]CortexMutator{NoOpMutator, MutateWeights})
+	assert.Equals(t, policy.Operators[0].Name, "NoOpMutator")
+	assert.Equals(t, policy.Operators[1].Name, "MutateWeights")
+
+	stats := policy.Stats()
+	assert.Equals(t, len(stats), 2)
+	assert.Equals(t, stats[0].Probability, 0.5)
+	assert.Equals(t, stats[1].Probability, 0.5)
+
+	stats = NewTopologyOrWeightPolicy().Stats()
+	assert.Equals(t, stats[0].Name, "topology")
+	assert.True(t, stats[0].Probability > 0.0399 && stats[0].Probability < 0.0401)
+	assert.True(t, stats[1].Probability > 0.9599 && stats[1].Probability < 0.9601)
+
+}
+
+func TestMutationPolicyRetries(t *testing.T) {
+
+	random := newRandom()
+
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "fail", Mutator: failingMutator, Probability: 1},
+			{Name: "succeed", Mutator: NoOpMutator, Probability: 1},
+			{Name: "never", Mutator: NoOpMutator, Probability: 0},
+		},
+	}
+	assert.True(t, policy.validate() == nil)
+
+	cortex := BasicCortex()
+	for i := 0; i < 50; i++ {
+		success, _ := policy.Mutate(random, cortex)
+		assert.True(t, success)
+	}
+
+	stats := policy.Stats()
+	assert.Equals(t, stats[0].Successes, 0)
+	assert.Equals(t, stats[1].Attempts, 50)
+	assert.Equals(t, stats[1].Successes, 50)
+	assert.Equals(t, stats[2].Attempts, 0)
+
+	// a single attempt fails as often as it picks the failing operator
+	policy.MaxAttempts = 1
+	failures := 0
+	for i := 0; i < 50; i++ {
+		if success, _ := policy.Mutate(random, cortex); !success {
+			failures += 1
+		}
+	}
+	assert.Equals(t, policy.Stats()[0].Attempts, stats[0].Attempts+failures)
+
+}
+
+func TestMutationPolicyAdaptive(t *testing.T) {
+
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "good", Mutator: NoOpMutator, Probability: 1},
+			{Name: "bad", Mutator: NoOpMutator, Probability: 1},
+		},
+	}
+	for i := 0; i < 30; i++ {
+		policy.credit(0, true)
+		policy.credit(1, false)
+	}
+
+	// the probabilities only follow the improvements if it is adaptive
+	stats := policy.Stats()
+	assert.Equals(t, stats[0].Improvements, 30)
+	assert.Equals(t, stats[1].Improvements, 0)
+	assert.Equals(t, stats[0].Probability, 0.5)
+
+	policy.Adaptive = true
+	stats = policy.Stats()
+	assert.True(t, stats[0].Probability > 0.8)
+	assert.True(t, stats[1].Probability >= 0.1)
+	assert.True(t, stats[0].Probability+stats[1].Probability > 0.9999)
+	assert.True(t, stats[0].Probability+stats[1].Probability < 1.0001)
+
+}
+
+func TestMutationPolicyAdaptiveKeepsZeroProbability(t *testing.T) {
+
+	random := newRandom()
+
+	policy := &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "good", Mutator: NoOpMutator, Probability: 1},
+			{Name: "bad", Mutator: NoOpMutator, Probability: 1},
+			{Name: "never", Mutator: NoOpMutator, Probability: 0},
+		},
+		Adaptive: true,
+	}
+	for i := 0; i < 30; i++ {
+		policy.credit(0, true)
+		policy.credit(1, false)
+	}
+
+	// the minimum probability is shared by the operators which can be
+	// chosen, and doesn't bring back the one that can't
+	stats := policy.Stats()
+	assert.Equals(t, stats[2].Probability, 0.0)
+	assert.True(t, stats[1].Probability >= 0.1)
+	assert.True(t, stats[0].Probability+stats[1].Probability > 0.9999)
+	assert.True(t, stats[0].Probability+stats[1].Probability < 1.0001)
+
+	cortex := BasicCortex()
+	for i := 0; i < 50; i++ {
+		policy.Mutate(random, cortex)
+	}
+	assert.Equals(t, policy.Stats()[2].Attempts, 0)
+
+}
+
+func TestPopulationTrainerMutationPolicy(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   10,
+		MutationPolicy: &MutationPolicy{
+			Operators: []MutationOperator{
+				{Name: "increase", Mutator: addToBiases(1), Probability: 1},
+				{Name: "decrease", Mutator: addToBiases(-1), Probability: 1},
+			},
+			Adaptive: true,
+		},
+	}
+	recorder := &FakeMutationStatsRecorder{}
+
+	population := biasPopulation("cortex", 1, 2, 3, 4)
+	_, _, err := pt.TrainContext(context.Background(), population, FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.True(t, len(recorder.stats) > 1)
+	assert.Equals(t, recorder.generations[0], 0)
+
+	// no offspring have been evaluated yet in the first generation
+	assert.Equals(t, recorder.stats[0][0].Attempts, 0)
+
+	// offspring with bigger biases are always fitter than their parents
+	stats := recorder.stats[len(recorder.stats)-1]
+	assert.True(t, stats[0].Improvements > 0)
+	assert.Equals(t, stats[1].Improvements, 0)
+	assert.True(t, stats[0].Probability > stats[1].Probability)
+
+}
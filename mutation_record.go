@@ -0,0 +1,286 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+)
+
+// What a mutator changed.  Mutators return nil when they fail.
+type MutateResult = *MutationRecord
+
+// A connection which was added or removed, with its weights at the time
+type LinkRecord struct {
+	Source  *ng.NodeId
+	Target  *ng.NodeId
+	Weights []float64
+}
+
+// A change to the weights of the connection from Source to Target
+type WeightChange struct {
+	Source     *ng.NodeId
+	Target     *ng.NodeId
+	OldWeights []float64
+	NewWeights []float64
+}
+
+type BiasChange struct {
+	Neuron  *ng.NodeId
+	OldBias float64
+	NewBias float64
+}
+
+type ActivationChange struct {
+	Neuron        *ng.NodeId
+	OldActivation string
+	NewActivation string
+}
+
+// Everything a mutation changed about a cortex, in enough detail to log it
+// or replay it.  The node ids are copies, so they stay the same however
+// the cortex changes later.
+type MutationRecord struct {
+
+	// The name of the mutator which was called
+	Operator string
+
+	// Every node which was added, removed or changed, or which gained or
+	// lost a connection, each listed once
+	NodeIds []*ng.NodeId
+
+	AddedNodes        []*ng.NodeId
+	RemovedNodes      []*ng.NodeId
+	AddedLinks        []LinkRecord
+	RemovedLinks      []LinkRecord
+	WeightChanges     []WeightChange
+	BiasChanges       []BiasChange
+	ActivationChanges []ActivationChange
+
+	// The mutations that make up a compound mutation, in the order they
+	// were made
+	Mutations []*MutationRecord
+}
+
+// A lineage recorder is told how each offspring was mutated from its
+// parent, so it can reconstruct the history of each cortex
+type LineageRecorder interface {
+	AddLineage(event LineageEvent)
+}
+
+// The mutations which turned a copy of the parent, or of the fitter
+// parent when crossed over, into the offspring
+type LineageEvent struct {
+	Generation  int
+	ParentId    string
+	OffspringId string
+	Mutations   []*MutationRecord
+
+	// The other parent the offspring was crossed over with, if any
+	MateId string
+
+	// Set when the offspring is an unchanged copy of the parent, which
+	// migrated to this island from another one.  See IslandTrainer.
+	Migrated bool
+}
+
+func newMutationRecord(operator string) *MutationRecord {
+	return &MutationRecord{
+		Operator: operator,
+		NodeIds:  make([]*ng.NodeId, 0),
+	}
+}
+
+// Sets the Operator, unless the record is nil
+func (r *MutationRecord) named(operator string) *MutationRecord {
+	if r != nil {
+		r.Operator = operator
+	}
+	return r
+}
+
+func (r *MutationRecord) addNode(nodeId *ng.NodeId) {
+	r.AddedNodes = append(r.AddedNodes, r.affect(nodeId))
+}
+
+func (r *MutationRecord) removeNode(nodeId *ng.NodeId) {
+	r.RemovedNodes = append(r.RemovedNodes, r.affect(nodeId))
+}
+
+// Record the connection source -> target, which has just been made
+func (r *MutationRecord) addLink(cortex *ng.Cortex, source *ng.NodeId, target *ng.NodeId) {
+	link := LinkRecord{
+		Source:  r.affect(source),
+		Target:  r.affect(target),
+		Weights: linkWeights(cortex, source, target),
+	}
+	r.AddedLinks = append(r.AddedLinks, link)
+}
+
+// Record the connection source -> target, which is about to be removed
+func (r *MutationRecord) removeLink(cortex *ng.Cortex, source *ng.NodeId, target *ng.NodeId) {
+	link := LinkRecord{
+		Source:  r.affect(source),
+		Target:  r.affect(target),
+		Weights: linkWeights(cortex, source, target),
+	}
+	r.RemovedLinks = append(r.RemovedLinks, link)
+}
+
+func (r *MutationRecord) changeWeights(source *ng.NodeId, target *ng.NodeId, oldWeights []float64, newWeights []float64) {
+	change := WeightChange{
+		Source:     r.affect(source),
+		Target:     r.affect(target),
+		OldWeights: append([]float64{}, oldWeights...),
+		NewWeights: append([]float64{}, newWeights...),
+	}
+	r.WeightChanges = append(r.WeightChanges, change)
+}
+
+func (r *MutationRecord) changeBias(neuron *ng.NodeId, oldBias float64, newBias float64) {
+	change := BiasChange{
+		Neuron:  r.affect(neuron),
+		OldBias: oldBias,
+		NewBias: newBias,
+	}
+	r.BiasChanges = append(r.BiasChanges, change)
+}
+
+func (r *MutationRecord) changeActivation(neuron *ng.NodeId, oldActivation string, newActivation string) {
+	change := ActivationChange{
+		Neuron:        r.affect(neuron),
+		OldActivation: oldActivation,
+		NewActivation: newActivation,
+	}
+	r.ActivationChanges = append(r.ActivationChanges, change)
+}
+
+// Add a mutation to a compound mutation
+func (r *MutationRecord) addMutation(mutation *MutationRecord) {
+	for _, nodeId := range mutation.NodeIds {
+		r.affect(nodeId)
+	}
+	r.Mutations = append(r.Mutations, mutation)
+}
+
+// Add the node to NodeIds, if it isn't there already, and return a copy
+// of its id
+func (r *MutationRecord) affect(nodeId *ng.NodeId) *ng.NodeId {
+	nodeIdCopy := *nodeId
+	if !r.affects(nodeId) {
+		affected := nodeIdCopy
+		r.NodeIds = append(r.NodeIds, &affected)
+	}
+	return &nodeIdCopy
+}
+
+func (r *MutationRecord) affects(nodeId *ng.NodeId) bool {
+	for _, affected := range r.NodeIds {
+		if affected.UUID == nodeId.UUID {
+			return true
+		}
+	}
+	return false
+}
+
+// A copy of the weights of the connection source -> target, or nil if
+// there is no such connection
+func linkWeights(cortex *ng.Cortex, source *ng.NodeId, target *ng.NodeId) []float64 {
+	for _, connection := range inboundConnections(cortex, target) {
+		if connection.NodeId.UUID == source.UUID {
+			return append([]float64{}, connection.Weights...)
+		}
+	}
+	return nil
+}
+
+// The weights, biases and activation functions of some neurons, so that
+// any changes made to them can be recorded
+type parameterSnapshot struct {
+	biases      map[string]float64
+	activations map[string]string
+	weights     map[string]map[string][]float64
+}
+
+func snapshotParameters(neurons []*ng.Neuron) parameterSnapshot {
+	snapshot := parameterSnapshot{
+		biases:      make(map[string]float64),
+		activations: make(map[string]string),
+		weights:     make(map[string]map[string][]float64),
+	}
+	for _, neuron := range neurons {
+		uuid := neuron.NodeId.UUID
+		snapshot.biases[uuid] = neuron.Bias
+		snapshot.activations[uuid] = activationName(neuron)
+		snapshot.weights[uuid] = make(map[string][]float64)
+		for _, connection := range neuron.Inbound {
+			snapshot.weights[uuid][connection.NodeId.UUID] = append([]float64{}, connection.Weights...)
+		}
+	}
+	return snapshot
+}
+
+// Record the parameters of the neurons which differ from the snapshot
+func (r *MutationRecord) addParameterChanges(before parameterSnapshot, neurons []*ng.Neuron) {
+	for _, neuron := range neurons {
+		uuid := neuron.NodeId.UUID
+		if oldBias, ok := before.biases[uuid]; ok && oldBias != neuron.Bias {
+			r.changeBias(neuron.NodeId, oldBias, neuron.Bias)
+		}
+		if oldActivation, ok := before.activations[uuid]; ok && oldActivation != activationName(neuron) {
+			r.changeActivation(neuron.NodeId, oldActivation, activationName(neuron))
+		}
+		for _, connection := range neuron.Inbound {
+			oldWeights, ok := before.weights[uuid][connection.NodeId.UUID]
+			if ok && !equalWeights(oldWeights, connection.Weights) {
+				r.changeWeights(connection.NodeId, neuron.NodeId, oldWeights, connection.Weights)
+			}
+		}
+	}
+}
+
+func equalWeights(a []float64, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Remember the mutations which made the offspring, to be passed on to
+// the recorder once its generation is recorded
+func (pt *PopulationTrainer) expectLineage(offspring EvaluatedCortex, mateId string, mutations []*MutationRecord) {
+	event := LineageEvent{
+		Generation:  offspring.CreatedInGeneration,
+		ParentId:    offspring.ParentId,
+		OffspringId: offspring.Cortex.NodeId.UUID,
+		Mutations:   mutations,
+		MateId:      mateId,
+	}
+	pt.pendingLineage = append(pt.pendingLineage, event)
+}
+
+// Pass the lineage of the offspring which made it into the generation on
+// to the recorder
+func (pt *PopulationTrainer) recordLineage(generation []EvaluatedCortex, recorder Recorder) {
+
+	pending := pt.pendingLineage
+	pt.pendingLineage = nil
+
+	lineageRecorder, ok := recorder.(LineageRecorder)
+	if !ok {
+		return
+	}
+
+	offspring := make(map[string]bool)
+	for _, evaldCortex := range generation {
+		offspring[evaldCortex.Cortex.NodeId.UUID] = true
+	}
+	for _, event := range pending {
+		if offspring[event.OffspringId] {
+			lineageRecorder.AddLineage(event)
+		}
+	}
+
+}
@@ -0,0 +1,134 @@
+package neurvolve
+
+import (
+	"context"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"testing"
+)
+
+type FakeLineageRecorder struct {
+	NullRecorder
+	events []LineageEvent
+}
+
+func (r *FakeLineageRecorder) AddLineage(event LineageEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestMutationRecordNodeIds(t *testing.T) {
+
+	cortex := BasicCortex()
+	neuron1 := cortex.Neurons[0]
+	neuron2 := cortex.Neurons[1]
+
+	record := newMutationRecord("test")
+	record.removeLink(cortex, neuron1.NodeId, neuron2.NodeId)
+	record.changeBias(neuron2.NodeId, 0.25, 0)
+	assert.Equals(t, len(record.NodeIds), 2)
+	assert.DeepEquals(t, record.RemovedLinks[0].Weights, neuron2.Inbound[0].Weights)
+
+	// the record keeps its own copies of the ids
+	neuron2.NodeId.UUID = "renamed"
+	assert.Equals(t, record.NodeIds[1].UUID, "hidden-neuron2")
+	assert.Equals(t, record.BiasChanges[0].Neuron.UUID, "hidden-neuron2")
+
+}
+
+func TestMutateAllWeightsRecord(t *testing.T) {
+
+	random := newRandom()
+
+	cortex := BasicCortex()
+	ok, mutateResult := MutateAllWeightsBellCurve(random, cortex)
+	assert.True(t, ok)
+	assert.Equals(t, mutateResult.Operator, "MutateAllWeightsBellCurve")
+
+	// every neuron has one inbound connection and a bias
+	assert.Equals(t, len(mutateResult.BiasChanges), 4)
+	assert.Equals(t, len(mutateResult.WeightChanges), 4)
+	for i, change := range mutateResult.BiasChanges {
+		assert.Equals(t, change.Neuron.UUID, cortex.Neurons[i].NodeId.UUID)
+		assert.Equals(t, change.NewBias, cortex.Neurons[i].Bias)
+	}
+	assert.Equals(t, mutateResult.BiasChanges[0].OldBias, -30.0)
+	assert.Equals(t, len(mutateResult.WeightChanges[0].OldWeights), 2)
+	assert.DeepEquals(t, mutateResult.WeightChanges[0].NewWeights, cortex.Neurons[0].Inbound[0].Weights)
+
+}
+
+func TestLineageRecorder(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    addToBiases(1),
+	}
+	recorder := &FakeLineageRecorder{}
+
+	population := biasPopulation("cortex", 1, 2, 3, 4)
+	_, _, err := pt.TrainContext(context.Background(), population, FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.True(t, len(recorder.events) > 0)
+
+	for _, event := range recorder.events {
+		assert.NotEquals(t, event.OffspringId, event.ParentId)
+		assert.Equals(t, len(event.Mutations), 1)
+		assert.Equals(t, event.Mutations[0].Operator, "addToBiases")
+	}
+
+}
+
+func TestCompoundMutatorRecord(t *testing.T) {
+
+	random := newRandom()
+
+	policy := NewMutationPolicy([]CortexMutator{AddBias, RemoveBias})
+	mutator := &CompoundMutator{Policy: policy, NumMutations: FixedMutationCount(2)}
+
+	cortex := BasicCortex()
+	ok, mutateResult := mutator.Mutate(random, cortex)
+	assert.True(t, ok)
+	assert.Equals(t, len(mutateResult.Mutations), 2)
+
+	// each step changed a bias, and the compound record lists every
+	// neuron they touched
+	affected := make(map[string]bool)
+	for _, mutation := range mutateResult.Mutations {
+		assert.Equals(t, len(mutation.BiasChanges), 1)
+		affected[mutation.BiasChanges[0].Neuron.UUID] = true
+	}
+	assert.Equals(t, len(mutateResult.NodeIds), len(affected))
+
+	unused := ng.NewNeuronId("unused", 0.5)
+	assert.False(t, mutateResult.affects(unused))
+
+}
+
+// Crosses over by copying the fitter parent
+func copyFitterParent(random *rand.Rand, fitterParent *ng.Cortex, otherParent *ng.Cortex) (*ng.Cortex, error) {
+	return fitterParent.Copy(), nil
+}
+
+func TestLineageAfterCrossover(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		CortexMutator:        NoOpMutator,
+		Crossover:            copyFitterParent,
+		CrossoverProbability: 1.0,
+	}
+
+	weaker := EvaluatedCortex{Cortex: SingleNeuronCortex("weaker"), Fitness: 1}
+	fitter := EvaluatedCortex{Cortex: SingleNeuronCortex("fitter"), Fitness: 5}
+	offspring, err := pt.breed([]EvaluatedCortex{weaker, fitter}, 1)
+	assert.True(t, err == nil)
+
+	// the offspring was bred from the weaker parent, but is a copy of
+	// the fitter one
+	assert.Equals(t, offspring[0].ParentId, fitter.Cortex.NodeId.UUID)
+	assert.Equals(t, len(pt.pendingLineage), 1)
+	assert.Equals(t, pt.pendingLineage[0].ParentId, fitter.Cortex.NodeId.UUID)
+	assert.Equals(t, pt.pendingLineage[0].MateId, weaker.Cortex.NodeId.UUID)
+
+}
@@ -5,12 +5,12 @@ import (
 	ng "github.com/tleyden/neurgo"
 	"log"
 	"math"
+	"math/rand"
 )
 
-type OutboundChooser func(*ng.Neuron) *ng.OutboundConnection
-type NeuronMutator func(*ng.Neuron) (bool, MutateResult)
-type MutateResult interface{}
-type CortexMutator func(*ng.Cortex) (bool, MutateResult)
+type OutboundChooser func(*rand.Rand, *ng.Neuron) *ng.OutboundConnection
+type NeuronMutator func(*rand.Rand, *ng.Neuron) (bool, MutateResult)
+type CortexMutator func(*rand.Rand, *ng.Cortex) (bool, MutateResult)
 
 func CortexMutatorsNonTopological() []CortexMutator {
 	mutators := []CortexMutator{
@@ -29,6 +29,10 @@ func CortexMutatorsRecurrent(includeNonTopological bool) []CortexMutator {
 		AddInlinkRecurrent,
 		AddOutlinkRecurrent,
 		OutspliceRecurrent,
+		RemoveNeuronRecurrent,
+		RemoveInlinkRecurrent,
+		RemoveOutlinkRecurrent,
+		SpliceOutRecurrent,
 	}
 	if includeNonTopological {
 		commonMutators := CortexMutatorsNonTopological()
@@ -44,6 +48,10 @@ func CortexMutatorsNonRecurrent(includeNonTopological bool) []CortexMutator {
 		AddInlinkNonRecurrent,
 		AddOutlinkNonRecurrent,
 		OutspliceNonRecurrent,
+		RemoveNeuronNonRecurrent,
+		RemoveInlinkNonRecurrent,
+		RemoveOutlinkNonRecurrent,
+		SpliceOutNonRecurrent,
 	}
 	if includeNonTopological {
 		commonMutators := CortexMutatorsNonTopological()
@@ -67,66 +75,78 @@ func inboundConnectionCandidates(neuron *ng.Neuron) []*ng.NodeId {
 	sensorNodeIds := cortex.SensorNodeIds()
 	availableNodeIds := append(neuronNodeIds, sensorNodeIds...)
 
-	// hackish way to delete a vew elements from this slice.
-	// put in a map and delete from map, then back to slice. TODO: fixme
-	availableNodeIdMap := make(map[string]*ng.NodeId)
-	for _, nodeId := range availableNodeIds {
-		availableNodeIdMap[nodeId.UUID] = nodeId
-	}
-
 	// remove things we already have inbound connections from
+	excludedUuids := make(map[string]bool)
 	for _, inboundConnection := range neuron.Inbound {
-		nodeId := inboundConnection.NodeId
-		delete(availableNodeIdMap, nodeId.UUID)
+		excludedUuids[inboundConnection.NodeId.UUID] = true
 	}
 
-	availableNodeIds = make([]*ng.NodeId, 0)
-	for _, nodeId := range availableNodeIdMap {
-		availableNodeIds = append(availableNodeIds, nodeId)
-	}
-	return availableNodeIds
+	return filterNodeIds(availableNodeIds, excludedUuids)
+
+}
 
+// The node ids which are not excluded, without duplicates, in their
+// original order.  The order matters, since it has to be the same from
+// one run to the next for random choices to be reproducible.
+func filterNodeIds(nodeIds []*ng.NodeId, excludedUuids map[string]bool) []*ng.NodeId {
+	filtered := make([]*ng.NodeId, 0)
+	seen := make(map[string]bool)
+	for _, nodeId := range nodeIds {
+		if excludedUuids[nodeId.UUID] || seen[nodeId.UUID] {
+			continue
+		}
+		seen[nodeId.UUID] = true
+		filtered = append(filtered, nodeId)
+	}
+	return filtered
 }
 
-func AddNeuronNonRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
+func AddNeuronNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 	numAttempts := len(cortex.AllNodeIds()) * 5
 
 	for i := 0; i < numAttempts; i++ {
 
 		nodeIdLayerMap := cortex.NodeIdLayerMap()
-		neuronLayerMap := cortex.NeuronLayerMap()
-		randomLayer := neuronLayerMap.ChooseRandomLayer()
+		randomLayer := chooseRandomNeuronLayer(random, cortex)
 
-		upstreamNodeId := nodeIdLayerMap.ChooseNodeIdPrecedingLayer(randomLayer)
+		upstreamNodeId := chooseNodeIdPrecedingLayer(random, nodeIdLayerMap, randomLayer)
 		if upstreamNodeId == nil {
 			continue
 		}
 
-		downstreamNodeId := findDownstreamNodeId(cortex, nodeIdLayerMap, randomLayer)
+		downstreamNodeId := findDownstreamNodeId(random, cortex, nodeIdLayerMap, randomLayer)
 		if downstreamNodeId == nil {
 			continue
 		}
 
-		neuron := cortex.CreateNeuronInLayer(randomLayer)
-		neuronAddInlinkFrom(neuron, upstreamNodeId)
-		neuronAddOutlinkTo(neuron, downstreamNodeId)
+		neuron := createNeuronInLayer(random, cortex, randomLayer)
+		neuronAddInlinkFrom(random, neuron, upstreamNodeId)
+		neuronAddOutlinkTo(random, neuron, downstreamNodeId)
 
-		return true, neuron
+		return true, addedNeuronRecord("AddNeuronNonRecurrent", neuron, upstreamNodeId, downstreamNodeId)
 
 	}
 	return false, nil
 }
 
-func AddNeuronRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
+// The record of a new neuron connected from source and to target
+func addedNeuronRecord(operator string, neuron *ng.Neuron, source *ng.NodeId, target *ng.NodeId) *MutationRecord {
+	record := newMutationRecord(operator)
+	record.addNode(neuron.NodeId)
+	record.addLink(neuron.Cortex, source, neuron.NodeId)
+	record.addLink(neuron.Cortex, neuron.NodeId, target)
+	return record
+}
+
+func AddNeuronRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 
 	numAttempts := len(cortex.AllNodeIds()) * 5
 
 	for i := 0; i < numAttempts; i++ {
 
 		nodeIdLayerMap := cortex.NodeIdLayerMap()
-		neuronLayerMap := cortex.NeuronLayerMap()
-		randomLayer := neuronLayerMap.ChooseRandomLayer()
-		inboundNodeId := findRecurrentInboundNodeId(cortex,
+		randomLayer := chooseRandomNeuronLayer(random, cortex)
+		inboundNodeId := findRecurrentInboundNodeId(random, cortex,
 			nodeIdLayerMap,
 			randomLayer)
 
@@ -139,9 +159,9 @@ func AddNeuronRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
 			continue
 		}
 
-		neuron := cortex.CreateNeuronInLayer(randomLayer)
+		neuron := createNeuronInLayer(random, cortex, randomLayer)
 
-		outboundNodeId := findRecurrentOutboundNodeId(cortex,
+		outboundNodeId := findRecurrentOutboundNodeId(random, cortex,
 			nodeIdLayerMap,
 			randomLayer)
 
@@ -150,10 +170,10 @@ func AddNeuronRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
 			continue
 		}
 
-		neuronAddInlinkFrom(neuron, inboundNodeId)
-		neuronAddOutlinkTo(neuron, outboundNodeId)
+		neuronAddInlinkFrom(random, neuron, inboundNodeId)
+		neuronAddOutlinkTo(random, neuron, outboundNodeId)
 
-		return true, neuron
+		return true, addedNeuronRecord("AddNeuronRecurrent", neuron, inboundNodeId, outboundNodeId)
 
 	}
 
@@ -162,13 +182,13 @@ func AddNeuronRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
 
 }
 
-func Outsplice(cortex *ng.Cortex, chooseOutbound OutboundChooser) (bool, *ng.Neuron) {
+func Outsplice(random *rand.Rand, cortex *ng.Cortex, chooseOutbound OutboundChooser) (bool, MutateResult) {
 
 	numAttempts := len(cortex.AllNodeIds()) * 5
 
 	for i := 0; i < numAttempts; i++ {
-		neuronA := randomNeuron(cortex)
-		outbound := chooseOutbound(neuronA)
+		neuronA := randomNeuron(random, cortex)
+		outbound := chooseOutbound(random, neuronA)
 		if outbound == nil {
 			continue
 		}
@@ -186,15 +206,18 @@ func Outsplice(cortex *ng.Cortex, chooseOutbound OutboundChooser) (bool, *ng.Neu
 		layerK := nodeIdLayerMap.LayerBetweenOrNew(layerA, layerB)
 
 		// create neuron K
-		neuronK := cortex.CreateNeuronInLayer(layerK)
+		neuronK := createNeuronInLayer(random, cortex, layerK)
+		record := newMutationRecord("Outsplice")
+		record.addNode(neuronK.NodeId)
 
 		// disconnect neuronA <-> nodeB
+		record.removeLink(cortex, neuronA.NodeId, nodeIdB)
 		nodeBConnector := cortex.FindInboundConnector(nodeIdB)
 		ng.DisconnectOutbound(neuronA, nodeIdB)
 		ng.DisconnectInbound(nodeBConnector, neuronA)
 
 		// connect neuronA -> neuronK
-		weights := randomWeights(1)
+		weights := randomWeights(random, 1)
 		ng.ConnectOutbound(neuronA, neuronK)
 		ng.ConnectInboundWeighted(neuronK, neuronA, weights)
 
@@ -209,26 +232,30 @@ func Outsplice(cortex *ng.Cortex, chooseOutbound OutboundChooser) (bool, *ng.Neu
 			ng.ConnectOutbound(neuronK, actuatorB)
 			ng.ConnectInbound(nodeBConnector, neuronK)
 		}
-		return true, neuronK
+		record.addLink(cortex, neuronA.NodeId, neuronK.NodeId)
+		record.addLink(cortex, neuronK.NodeId, nodeIdB)
+		return true, record
 
 	}
 	return false, nil
 
 }
 
-func OutspliceRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
+func OutspliceRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 	chooseOutboundFunction := randomOutbound
-	return Outsplice(cortex, chooseOutboundFunction)
+	ok, record := Outsplice(random, cortex, chooseOutboundFunction)
+	return ok, record.named("OutspliceRecurrent")
 }
 
-func OutspliceNonRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
+func OutspliceNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 	chooseOutboundFunction := randomNonRecurrentOutbound
-	return Outsplice(cortex, chooseOutboundFunction)
+	ok, record := Outsplice(random, cortex, chooseOutboundFunction)
+	return ok, record.named("OutspliceNonRecurrent")
 }
 
-func randomNonRecurrentOutbound(neuron *ng.Neuron) *ng.OutboundConnection {
+func randomNonRecurrentOutbound(random *rand.Rand, neuron *ng.Neuron) *ng.OutboundConnection {
 	for i := 0; i < len(neuron.Outbound); i++ {
-		randIndex := RandomIntInRange(0, len(neuron.Outbound))
+		randIndex := RandomIntInRange(random, 0, len(neuron.Outbound))
 		outbound := neuron.Outbound[randIndex]
 		if neuron.IsConnectionRecurrent(outbound) {
 			continue
@@ -239,28 +266,28 @@ func randomNonRecurrentOutbound(neuron *ng.Neuron) *ng.OutboundConnection {
 	return nil
 }
 
-func randomOutbound(neuron *ng.Neuron) *ng.OutboundConnection {
+func randomOutbound(random *rand.Rand, neuron *ng.Neuron) *ng.OutboundConnection {
 	for i := 0; i < len(neuron.Outbound); i++ {
-		randIndex := RandomIntInRange(0, len(neuron.Outbound))
+		randIndex := RandomIntInRange(random, 0, len(neuron.Outbound))
 		return neuron.Outbound[randIndex]
 	}
 	return nil
 }
 
-func randomNeuron(cortex *ng.Cortex) *ng.Neuron {
+func randomNeuron(random *rand.Rand, cortex *ng.Cortex) *ng.Neuron {
 	neurons := cortex.Neurons
-	randIndex := RandomIntInRange(0, len(neurons))
+	randIndex := RandomIntInRange(random, 0, len(neurons))
 	return neurons[randIndex]
 }
 
 // Find a nodeId suitable for use as an inbound node for a newly created
 // neuron.  This can either be a sensor node or another neuron node (including
 // the new neuron itself), but it cannot be an actuator node.
-func findRecurrentInboundNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
+func findRecurrentInboundNodeId(random *rand.Rand, cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
 
 	keys := layerMap.Keys()
 	actuatorLayer := keys[len(keys)-1]
-	chosenNodeId := layerMap.ChooseNodeIdPrecedingLayer(actuatorLayer)
+	chosenNodeId := chooseNodeIdPrecedingLayer(random, layerMap, actuatorLayer)
 	return chosenNodeId
 
 }
@@ -269,7 +296,7 @@ func findRecurrentInboundNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap,
 // neuron.  This can either be a either another neuron node (including
 // the new neuron itself), or an actuator (if it has space), but it cannot
 // be a sensor node
-func findRecurrentOutboundNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
+func findRecurrentOutboundNodeId(random *rand.Rand, cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
 
 	numAttempts := len(cortex.AllNodeIds()) * 5
 
@@ -278,7 +305,7 @@ func findRecurrentOutboundNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap
 	sensorLayer := keys[0]
 
 	for i := 0; i < numAttempts; i++ {
-		chosenNodeId := layerMap.ChooseNodeIdFollowingLayer(sensorLayer)
+		chosenNodeId := chooseNodeIdFollowingLayer(random, layerMap, sensorLayer)
 		if chosenNodeId.NodeType == ng.ACTUATOR {
 			// make sure it has capacity for new incoming
 			actuator := cortex.FindActuator(chosenNodeId)
@@ -294,13 +321,13 @@ func findRecurrentOutboundNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap
 
 }
 
-func findDownstreamNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
+func findDownstreamNodeId(random *rand.Rand, cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromLayer float64) *ng.NodeId {
 
 	numAttempts := len(cortex.AllNodeIds()) * 5
 
 	for i := 0; i < numAttempts; i++ {
 
-		downstreamNodeId := layerMap.ChooseNodeIdFollowingLayer(fromLayer)
+		downstreamNodeId := chooseNodeIdFollowingLayer(random, layerMap, fromLayer)
 
 		if downstreamNodeId == nil {
 			log.Printf("findDownstreamNodeId unable to find downstream neuron, cannot add neuron")
@@ -320,7 +347,7 @@ func findDownstreamNodeId(cortex *ng.Cortex, layerMap ng.LayerToNodeIdMap, fromL
 
 }
 
-func NeuronAddInlinkNonRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronAddInlinkNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 
 	availableNodeIds := inboundConnectionCandidates(neuron)
 
@@ -333,32 +360,38 @@ func NeuronAddInlinkNonRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
 
 	}
 
-	return neuronAddInlink(neuron, nonRecurrentNodeIds)
+	ok, record := neuronAddInlink(random, neuron, nonRecurrentNodeIds)
+	return ok, record.named("NeuronAddInlinkNonRecurrent")
 }
 
-func NeuronAddInlinkRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronAddInlinkRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 
 	// choose a random element B, where element B is another
 	// neuron or a sensor which is not already connected
 	// to this neuron.
 	availableNodeIds := inboundConnectionCandidates(neuron)
-	return neuronAddInlink(neuron, availableNodeIds)
+	ok, record := neuronAddInlink(random, neuron, availableNodeIds)
+	return ok, record.named("NeuronAddInlinkRecurrent")
 }
 
-func neuronAddInlink(neuron *ng.Neuron, availableNodeIds []*ng.NodeId) (bool, *ng.InboundConnection) {
+func neuronAddInlink(random *rand.Rand, neuron *ng.Neuron, availableNodeIds []*ng.NodeId) (bool, *MutationRecord) {
 
 	if len(availableNodeIds) == 0 {
 		log.Printf("Warning: unable to add inlink to neuron: %v", neuron)
 		return false, nil
 	}
 
-	randIndex := ng.RandomIntInRange(0, len(availableNodeIds))
+	randIndex := RandomIntInRange(random, 0, len(availableNodeIds))
 	chosenNodeId := availableNodeIds[randIndex]
-	return true, neuronAddInlinkFrom(neuron, chosenNodeId)
+	neuronAddInlinkFrom(random, neuron, chosenNodeId)
+
+	record := newMutationRecord("")
+	record.addLink(neuron.Cortex, chosenNodeId, neuron.NodeId)
+	return true, record
 
 }
 
-func neuronAddInlinkFrom(neuron *ng.Neuron, sourceNodeId *ng.NodeId) *ng.InboundConnection {
+func neuronAddInlinkFrom(random *rand.Rand, neuron *ng.Neuron, sourceNodeId *ng.NodeId) *ng.InboundConnection {
 
 	cortex := neuron.Cortex
 
@@ -368,7 +401,7 @@ func neuronAddInlinkFrom(neuron *ng.Neuron, sourceNodeId *ng.NodeId) *ng.Inbound
 		sensor := cortex.FindSensor(sourceNodeId)
 		weightVectorLength = sensor.VectorLength
 	}
-	weights := randomWeights(weightVectorLength)
+	weights := randomWeights(random, weightVectorLength)
 
 	// make an inbound connection sourceNodeId <- neuron
 	connection := neuron.ConnectInboundWeighted(sourceNodeId, weights)
@@ -394,17 +427,10 @@ func outboundConnectionCandidates(neuron *ng.Neuron) []*ng.NodeId {
 	actuatorNodeIds := cortex.ActuatorNodeIds()
 	availableNodeIds := append(neuronNodeIds, actuatorNodeIds...)
 
-	// hackish way to delete a vew elements from this slice.
-	// put in a map and delete from map, then back to slice. TODO: fixme
-	availableNodeIdMap := make(map[string]*ng.NodeId)
-	for _, nodeId := range availableNodeIds {
-		availableNodeIdMap[nodeId.UUID] = nodeId
-	}
-
 	// remove things we are already connected to
+	excludedUuids := make(map[string]bool)
 	for _, outboundConnection := range neuron.Outbound {
-		nodeId := outboundConnection.NodeId
-		delete(availableNodeIdMap, nodeId.UUID)
+		excludedUuids[outboundConnection.NodeId.UUID] = true
 	}
 
 	// remove actuators that can't support any more inbound connections
@@ -413,33 +439,32 @@ func outboundConnectionCandidates(neuron *ng.Neuron) []*ng.NodeId {
 		// does the actuator have capacity for another
 		// incoming connection?
 		if actuator.CanAddInboundConnection() == false {
-			delete(availableNodeIdMap, actuatorNodeId.UUID)
+			excludedUuids[actuatorNodeId.UUID] = true
 		}
 	}
 
-	availableNodeIds = make([]*ng.NodeId, 0)
-	for _, nodeId := range availableNodeIdMap {
-		availableNodeIds = append(availableNodeIds, nodeId)
-	}
-	return availableNodeIds
+	return filterNodeIds(availableNodeIds, excludedUuids)
 
 }
 
-func neuronAddOutlink(neuron *ng.Neuron, availableNodeIds []*ng.NodeId) (bool, *ng.OutboundConnection) {
+func neuronAddOutlink(random *rand.Rand, neuron *ng.Neuron, availableNodeIds []*ng.NodeId) (bool, *MutationRecord) {
 
 	if len(availableNodeIds) == 0 {
 		log.Printf("Warning: unable to add outlink to neuron: %v", neuron)
 		return false, nil
 	}
 
-	randIndex := ng.RandomIntInRange(0, len(availableNodeIds))
+	randIndex := RandomIntInRange(random, 0, len(availableNodeIds))
 	chosenNodeId := availableNodeIds[randIndex]
+	neuronAddOutlinkTo(random, neuron, chosenNodeId)
 
-	return true, neuronAddOutlinkTo(neuron, chosenNodeId)
+	record := newMutationRecord("")
+	record.addLink(neuron.Cortex, neuron.NodeId, chosenNodeId)
+	return true, record
 
 }
 
-func neuronAddOutlinkTo(neuron *ng.Neuron, targetNodeId *ng.NodeId) *ng.OutboundConnection {
+func neuronAddOutlinkTo(random *rand.Rand, neuron *ng.Neuron, targetNodeId *ng.NodeId) *ng.OutboundConnection {
 
 	cortex := neuron.Cortex
 
@@ -451,7 +476,7 @@ func neuronAddOutlinkTo(neuron *ng.Neuron, targetNodeId *ng.NodeId) *ng.Outbound
 		connection := ng.ConnectOutbound(neuron, chosenNeuron)
 
 		// make an inbound connection targetNodeId <- neuron
-		weights := randomWeights(1)
+		weights := randomWeights(random, 1)
 		ng.ConnectInboundWeighted(chosenNeuron, neuron, weights)
 		return connection
 
@@ -473,16 +498,17 @@ func neuronAddOutlinkTo(neuron *ng.Neuron, targetNodeId *ng.NodeId) *ng.Outbound
 
 }
 
-func NeuronAddOutlinkRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronAddOutlinkRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 
 	// choose a random element B, where element B is another
 	// neuron or a sensor which is not already connected
 	// to this neuron.
 	availableNodeIds := outboundConnectionCandidates(neuron)
-	return neuronAddOutlink(neuron, availableNodeIds)
+	ok, record := neuronAddOutlink(random, neuron, availableNodeIds)
+	return ok, record.named("NeuronAddOutlinkRecurrent")
 }
 
-func NeuronAddOutlinkNonRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronAddOutlinkNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 
 	availableNodeIds := outboundConnectionCandidates(neuron)
 
@@ -495,38 +521,47 @@ func NeuronAddOutlinkNonRecurrent(neuron *ng.Neuron) (bool, MutateResult) {
 
 	}
 
-	return neuronAddOutlink(neuron, nonRecurrentNodeIds)
+	ok, record := neuronAddOutlink(random, neuron, nonRecurrentNodeIds)
+	return ok, record.named("NeuronAddOutlinkNonRecurrent")
 
 }
 
-func NeuronMutateWeights(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronMutateWeights(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	before := snapshotParameters([]*ng.Neuron{neuron})
 	didPerturbAnyWeights := false
 	probability := parameterPerturbProbability(neuron)
 	for _, cxn := range neuron.Inbound {
 		saturationBounds := []float64{-100000, 100000}
-		didPerturbWeight := possiblyPerturbConnection(cxn, probability, saturationBounds)
+		didPerturbWeight := possiblyPerturbConnection(random, cxn, probability, saturationBounds)
 		if didPerturbWeight == true {
 			didPerturbAnyWeights = true
 		}
 	}
-	return didPerturbAnyWeights, nil
+	if !didPerturbAnyWeights {
+		return false, nil
+	}
+	record := newMutationRecord("NeuronMutateWeights")
+	record.addParameterChanges(before, []*ng.Neuron{neuron})
+	return true, record
 }
 
-func NeuronMutateActivation(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronMutateActivation(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 
 	encodableActivations := ng.AllEncodableActivations()
 
 	for i := 0; i < 100; i++ {
 
 		// pick a random activation function from list
-		randomIndex := ng.RandomIntInRange(0, len(encodableActivations))
+		randomIndex := RandomIntInRange(random, 0, len(encodableActivations))
 
 		chosenActivation := encodableActivations[randomIndex]
 
 		// if we chose a different activation than current one, use it
 		if chosenActivation.Name != neuron.ActivationFunction.Name {
+			record := newMutationRecord("NeuronMutateActivation")
+			record.changeActivation(neuron.NodeId, neuron.ActivationFunction.Name, chosenActivation.Name)
 			neuron.ActivationFunction = chosenActivation
-			return true, nil
+			return true, record
 		}
 	}
 
@@ -535,43 +570,389 @@ func NeuronMutateActivation(neuron *ng.Neuron) (bool, MutateResult) {
 
 }
 
-func NeuronResetWeights(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronResetWeights(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	before := snapshotParameters([]*ng.Neuron{neuron})
 	for _, cxn := range neuron.Inbound {
 		for j, _ := range cxn.Weights {
-			cxn.Weights[j] = RandomWeight()
+			cxn.Weights[j] = RandomWeight(random)
 		}
 	}
-	return true, nil
+	record := newMutationRecord("NeuronResetWeights")
+	record.addParameterChanges(before, []*ng.Neuron{neuron})
+	return true, record
 }
 
-func NeuronAddBias(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronAddBias(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 	if neuron.Bias == 0 {
-		neuron.Bias = RandomBias()
-		return true, nil
+		neuron.Bias = RandomBias(random)
+		record := newMutationRecord("NeuronAddBias")
+		record.changeBias(neuron.NodeId, 0, neuron.Bias)
+		return true, record
 	}
 	return false, nil
 }
 
-func NeuronRemoveBias(neuron *ng.Neuron) (bool, MutateResult) {
+func NeuronRemoveBias(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
 	if neuron.Bias != 0 {
+		record := newMutationRecord("NeuronRemoveBias")
+		record.changeBias(neuron.NodeId, neuron.Bias, 0)
 		neuron.Bias = 0
-		return true, nil
+		return true, record
 	}
 	return false, nil
 }
 
-func RandomNeuronMutator(c *ng.Cortex, mutator NeuronMutator) (bool, MutateResult) {
-	neuron := randomNeuron(c)
-	return mutator(neuron)
+// The inbound connections of a neuron or actuator
+func inboundConnections(cortex *ng.Cortex, nodeId *ng.NodeId) []*ng.InboundConnection {
+	switch nodeId.NodeType {
+	case ng.NEURON:
+		return cortex.FindNeuron(nodeId).Inbound
+	case ng.ACTUATOR:
+		return cortex.FindActuator(nodeId).Inbound
+	}
+	return nil
+}
+
+// The outbound connections of a sensor or neuron
+func outboundConnections(cortex *ng.Cortex, nodeId *ng.NodeId) []*ng.OutboundConnection {
+	switch nodeId.NodeType {
+	case ng.SENSOR:
+		return cortex.FindSensor(nodeId).Outbound
+	case ng.NEURON:
+		return cortex.FindNeuron(nodeId).Outbound
+	}
+	return nil
+}
+
+// The number of inbound connections the node has from nodes other than itself
+func numInboundFromOthers(cortex *ng.Cortex, nodeId *ng.NodeId) int {
+	numInbound := 0
+	for _, connection := range inboundConnections(cortex, nodeId) {
+		if connection.NodeId.UUID != nodeId.UUID {
+			numInbound += 1
+		}
+	}
+	return numInbound
+}
+
+// The number of outbound connections the node has to nodes other than itself
+func numOutboundToOthers(cortex *ng.Cortex, nodeId *ng.NodeId) int {
+	numOutbound := 0
+	for _, connection := range outboundConnections(cortex, nodeId) {
+		if connection.NodeId.UUID != nodeId.UUID {
+			numOutbound += 1
+		}
+	}
+	return numOutbound
+}
+
+// A connection can be removed as long as it doesn't leave the source
+// without any outputs or the target without any inputs, and the target is
+// not an actuator
+func canRemoveConnection(cortex *ng.Cortex, source *ng.NodeId, target *ng.NodeId) bool {
+	if source.UUID == target.UUID {
+		return true
+	}
+	if !canRemoveInput(target) {
+		return false
+	}
+	return numOutboundToOthers(cortex, source) > 1 && numInboundFromOthers(cortex, target) > 1
+}
+
+// An actuator takes one value from each of its inputs, so its inputs can
+// be replaced, but removing one would leave it short of a value
+func canRemoveInput(target *ng.NodeId) bool {
+	return target.NodeType != ng.ACTUATOR
+}
+
+// Remove the connection source -> target from both ends
+func removeConnection(cortex *ng.Cortex, source *ng.NodeId, target *ng.NodeId) {
+	ng.DisconnectOutbound(cortex.FindConnector(source), target)
+	ng.DisconnectInbound(cortex.FindInboundConnector(target), source)
+}
+
+func removeNeuronFromCortex(cortex *ng.Cortex, neuron *ng.Neuron) {
+	neurons := make([]*ng.Neuron, 0)
+	for _, other := range cortex.Neurons {
+		if other != neuron {
+			neurons = append(neurons, other)
+		}
+	}
+	cortex.Neurons = neurons
+}
+
+func hasRecurrentConnections(neuron *ng.Neuron) bool {
+	return len(neuron.RecurrentInboundConnections()) > 0 || len(neuron.RecurrentOutboundConnections()) > 0
+}
+
+func NeuronRemoveInlinkRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	ok, record := neuronRemoveInlink(random, neuron, neuron.Inbound)
+	return ok, record.named("NeuronRemoveInlinkRecurrent")
+}
+
+func NeuronRemoveInlinkNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	nonRecurrentInbound := make([]*ng.InboundConnection, 0)
+	for _, connection := range neuron.Inbound {
+		if !neuron.IsInboundConnectionRecurrent(connection) {
+			nonRecurrentInbound = append(nonRecurrentInbound, connection)
+		}
+	}
+	ok, record := neuronRemoveInlink(random, neuron, nonRecurrentInbound)
+	return ok, record.named("NeuronRemoveInlinkNonRecurrent")
+}
+
+func neuronRemoveInlink(random *rand.Rand, neuron *ng.Neuron, candidates []*ng.InboundConnection) (bool, *MutationRecord) {
+
+	cortex := neuron.Cortex
+
+	removable := make([]*ng.InboundConnection, 0)
+	for _, connection := range candidates {
+		if canRemoveConnection(cortex, connection.NodeId, neuron.NodeId) {
+			removable = append(removable, connection)
+		}
+	}
+	if len(removable) == 0 {
+		return false, nil
+	}
+
+	chosen := removable[RandomIntInRange(random, 0, len(removable))]
+	record := newMutationRecord("")
+	record.removeLink(cortex, chosen.NodeId, neuron.NodeId)
+	removeConnection(cortex, chosen.NodeId, neuron.NodeId)
+	return true, record
+
+}
+
+func NeuronRemoveOutlinkRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	ok, record := neuronRemoveOutlink(random, neuron, neuron.Outbound)
+	return ok, record.named("NeuronRemoveOutlinkRecurrent")
+}
+
+func NeuronRemoveOutlinkNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	nonRecurrentOutbound := make([]*ng.OutboundConnection, 0)
+	for _, connection := range neuron.Outbound {
+		if !neuron.IsConnectionRecurrent(connection) {
+			nonRecurrentOutbound = append(nonRecurrentOutbound, connection)
+		}
+	}
+	ok, record := neuronRemoveOutlink(random, neuron, nonRecurrentOutbound)
+	return ok, record.named("NeuronRemoveOutlinkNonRecurrent")
+}
+
+func neuronRemoveOutlink(random *rand.Rand, neuron *ng.Neuron, candidates []*ng.OutboundConnection) (bool, *MutationRecord) {
+
+	cortex := neuron.Cortex
+
+	removable := make([]*ng.OutboundConnection, 0)
+	for _, connection := range candidates {
+		if canRemoveConnection(cortex, neuron.NodeId, connection.NodeId) {
+			removable = append(removable, connection)
+		}
+	}
+	if len(removable) == 0 {
+		return false, nil
+	}
+
+	chosen := removable[RandomIntInRange(random, 0, len(removable))]
+	record := newMutationRecord("")
+	record.removeLink(cortex, neuron.NodeId, chosen.NodeId)
+	removeConnection(cortex, neuron.NodeId, chosen.NodeId)
+	return true, record
+
+}
+
+// Remove any neuron whose inputs and outputs all have other connections
+// to fall back on
+func NeuronRemoveRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	ok, record := neuronRemove(neuron)
+	return ok, record.named("NeuronRemoveRecurrent")
+}
+
+// Same as NeuronRemoveRecurrent, but only for neurons without any
+// recurrent connections
+func NeuronRemoveNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	if hasRecurrentConnections(neuron) {
+		return false, nil
+	}
+	ok, record := neuronRemove(neuron)
+	return ok, record.named("NeuronRemoveNonRecurrent")
+}
+
+func neuronRemove(neuron *ng.Neuron) (bool, *MutationRecord) {
+
+	cortex := neuron.Cortex
+	if len(cortex.Neurons) < 2 {
+		return false, nil
+	}
+
+	for _, connection := range neuron.Inbound {
+		source := connection.NodeId
+		if source.UUID != neuron.NodeId.UUID && numOutboundToOthers(cortex, source) < 2 {
+			return false, nil
+		}
+	}
+	for _, connection := range neuron.Outbound {
+		target := connection.NodeId
+		if target.UUID == neuron.NodeId.UUID {
+			continue
+		}
+		if !canRemoveInput(target) || numInboundFromOthers(cortex, target) < 2 {
+			return false, nil
+		}
+	}
+
+	record := newMutationRecord("")
+	record.removeNode(neuron.NodeId)
+	recordNeuronLinksRemoved(record, neuron)
+
+	// disconnect the nodes on the other end of each connection, since the
+	// neuron itself is going away
+	for _, connection := range neuron.Inbound {
+		if connection.NodeId.UUID != neuron.NodeId.UUID {
+			ng.DisconnectOutbound(cortex.FindConnector(connection.NodeId), neuron.NodeId)
+		}
+	}
+	for _, connection := range neuron.Outbound {
+		if connection.NodeId.UUID != neuron.NodeId.UUID {
+			ng.DisconnectInbound(cortex.FindInboundConnector(connection.NodeId), neuron.NodeId)
+		}
+	}
+	removeNeuronFromCortex(cortex, neuron)
+
+	return true, record
+
+}
+
+// Record the removal of every connection to and from the neuron, with
+// connections from the neuron to itself recorded once
+func recordNeuronLinksRemoved(record *MutationRecord, neuron *ng.Neuron) {
+	cortex := neuron.Cortex
+	for _, connection := range neuron.Inbound {
+		record.removeLink(cortex, connection.NodeId, neuron.NodeId)
+	}
+	for _, connection := range neuron.Outbound {
+		if connection.NodeId.UUID != neuron.NodeId.UUID {
+			record.removeLink(cortex, neuron.NodeId, connection.NodeId)
+		}
+	}
+}
+
+// Remove a pass-through neuron, which has a single input and a single
+// output besides connections to itself, and connect its input straight
+// to its output.  The neuron's input weights are scaled by the weight
+// its output had, so the new connection has roughly the same effect.
+func NeuronSpliceOutRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	ok, record := neuronSpliceOut(neuron)
+	return ok, record.named("NeuronSpliceOutRecurrent")
+}
+
+// Same as NeuronSpliceOutRecurrent, but only for neurons without any
+// recurrent connections, so the new connection is not recurrent either
+func NeuronSpliceOutNonRecurrent(random *rand.Rand, neuron *ng.Neuron) (bool, MutateResult) {
+	if hasRecurrentConnections(neuron) {
+		return false, nil
+	}
+	ok, record := neuronSpliceOut(neuron)
+	return ok, record.named("NeuronSpliceOutNonRecurrent")
 }
 
-func ReattemptingNeuronMutator(c *ng.Cortex, mutator NeuronMutator) (bool, MutateResult) {
+func neuronSpliceOut(neuron *ng.Neuron) (bool, *MutationRecord) {
+
+	cortex := neuron.Cortex
+	if len(cortex.Neurons) < 2 {
+		return false, nil
+	}
+	if numInboundFromOthers(cortex, neuron.NodeId) != 1 || numOutboundToOthers(cortex, neuron.NodeId) != 1 {
+		return false, nil
+	}
+
+	var inbound *ng.InboundConnection
+	for _, connection := range neuron.Inbound {
+		if connection.NodeId.UUID != neuron.NodeId.UUID {
+			inbound = connection
+		}
+	}
+	var target *ng.NodeId
+	for _, connection := range neuron.Outbound {
+		if connection.NodeId.UUID != neuron.NodeId.UUID {
+			target = connection.NodeId
+		}
+	}
+	source := inbound.NodeId
+	if source.UUID == target.UUID {
+		return false, nil
+	}
+
+	// actuators only take single values from neurons
+	if target.NodeType == ng.ACTUATOR && source.NodeType != ng.NEURON {
+		return false, nil
+	}
+
+	var existing, replaced *ng.InboundConnection
+	for _, connection := range inboundConnections(cortex, target) {
+		switch connection.NodeId.UUID {
+		case source.UUID:
+			existing = connection
+		case neuron.NodeId.UUID:
+			replaced = connection
+		}
+	}
+
+	// merging two inputs of an actuator would leave it short of one
+	if existing != nil && !canRemoveInput(target) {
+		return false, nil
+	}
+
+	scale := 1.0
+	if target.NodeType == ng.NEURON && len(replaced.Weights) > 0 {
+		scale = replaced.Weights[0]
+	}
+	weights := make([]float64, len(inbound.Weights))
+	for i, weight := range inbound.Weights {
+		weights[i] = weight * scale
+	}
+
+	record := newMutationRecord("")
+	record.removeNode(neuron.NodeId)
+	recordNeuronLinksRemoved(record, neuron)
+
+	if existing != nil {
+		oldWeights := append([]float64{}, existing.Weights...)
+		for i := range existing.Weights {
+			existing.Weights[i] += weights[i]
+		}
+		record.changeWeights(source, target, oldWeights, existing.Weights)
+		ng.DisconnectInbound(cortex.FindInboundConnector(target), neuron.NodeId)
+	} else {
+		// take over the neuron's place among the target's inputs, since
+		// the order of an actuator's inputs matters
+		sourceNodeId := *source
+		replaced.NodeId = &sourceNodeId
+		if target.NodeType == ng.NEURON {
+			replaced.Weights = weights
+		}
+		ng.ConnectOutbound(cortex.FindConnector(source), cortex.FindInboundConnector(target))
+		record.addLink(cortex, source, target)
+	}
+	ng.DisconnectOutbound(cortex.FindConnector(source), neuron.NodeId)
+	removeNeuronFromCortex(cortex, neuron)
+
+	return true, record
+
+}
+
+func RandomNeuronMutator(random *rand.Rand, c *ng.Cortex, mutator NeuronMutator) (bool, MutateResult) {
+	neuron := randomNeuron(random, c)
+	return mutator(random, neuron)
+}
+
+func ReattemptingNeuronMutator(random *rand.Rand, c *ng.Cortex, mutator NeuronMutator) (bool, MutateResult) {
 
 	numAttempts := len(c.AllNodeIds()) * 5
 
 	for i := 0; i < numAttempts; i++ {
-		neuron := randomNeuron(c)
-		ok, mutateResult := mutator(neuron)
+		neuron := randomNeuron(random, c)
+		ok, mutateResult := mutator(random, neuron)
 		if ok {
 			return ok, mutateResult
 		}
@@ -579,75 +960,137 @@ func ReattemptingNeuronMutator(c *ng.Cortex, mutator NeuronMutator) (bool, Mutat
 	return false, nil
 }
 
-func AddBias(cortex *ng.Cortex) (bool, MutateResult) {
-	return RandomNeuronMutator(cortex, NeuronAddBias)
+func AddBias(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := RandomNeuronMutator(random, cortex, NeuronAddBias)
+	return ok, record.named("AddBias")
+}
+
+func RemoveBias(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := RandomNeuronMutator(random, cortex, NeuronRemoveBias)
+	return ok, record.named("RemoveBias")
+}
+
+func MutateWeights(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := RandomNeuronMutator(random, cortex, NeuronMutateWeights)
+	return ok, record.named("MutateWeights")
+}
+
+func ResetWeights(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := RandomNeuronMutator(random, cortex, NeuronResetWeights)
+	return ok, record.named("ResetWeights")
+}
+
+func MutateActivation(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := RandomNeuronMutator(random, cortex, NeuronMutateActivation)
+	return ok, record.named("MutateActivation")
 }
 
-func RemoveBias(cortex *ng.Cortex) (bool, MutateResult) {
-	return RandomNeuronMutator(cortex, NeuronRemoveBias)
+func AddInlinkRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronAddInlinkRecurrent)
+	return ok, record.named("AddInlinkRecurrent")
 }
 
-func MutateWeights(cortex *ng.Cortex) (bool, MutateResult) {
-	return RandomNeuronMutator(cortex, NeuronMutateWeights)
+func AddInlinkNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronAddInlinkNonRecurrent)
+	return ok, record.named("AddInlinkNonRecurrent")
 }
 
-func ResetWeights(cortex *ng.Cortex) (bool, MutateResult) {
-	return RandomNeuronMutator(cortex, NeuronResetWeights)
+func AddOutlinkRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronAddOutlinkRecurrent)
+	return ok, record.named("AddOutlinkRecurrent")
 }
 
-func MutateActivation(cortex *ng.Cortex) (bool, MutateResult) {
-	return RandomNeuronMutator(cortex, NeuronMutateActivation)
+func AddOutlinkNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronAddOutlinkNonRecurrent)
+	return ok, record.named("AddOutlinkNonRecurrent")
 }
 
-func AddInlinkRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
-	return ReattemptingNeuronMutator(cortex, NeuronAddInlinkRecurrent)
+func RemoveNeuronRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveRecurrent)
+	return ok, record.named("RemoveNeuronRecurrent")
 }
 
-func AddInlinkNonRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
-	return ReattemptingNeuronMutator(cortex, NeuronAddInlinkNonRecurrent)
+func RemoveNeuronNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveNonRecurrent)
+	return ok, record.named("RemoveNeuronNonRecurrent")
 }
 
-func AddOutlinkRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
-	return ReattemptingNeuronMutator(cortex, NeuronAddOutlinkRecurrent)
+func RemoveInlinkRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveInlinkRecurrent)
+	return ok, record.named("RemoveInlinkRecurrent")
 }
 
-func AddOutlinkNonRecurrent(cortex *ng.Cortex) (bool, MutateResult) {
-	return ReattemptingNeuronMutator(cortex, NeuronAddOutlinkNonRecurrent)
+func RemoveInlinkNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveInlinkNonRecurrent)
+	return ok, record.named("RemoveInlinkNonRecurrent")
 }
 
-func NoOpMutator(cortex *ng.Cortex) (success bool, result MutateResult) {
+func RemoveOutlinkRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveOutlinkRecurrent)
+	return ok, record.named("RemoveOutlinkRecurrent")
+}
+
+func RemoveOutlinkNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronRemoveOutlinkNonRecurrent)
+	return ok, record.named("RemoveOutlinkNonRecurrent")
+}
+
+func SpliceOutRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronSpliceOutRecurrent)
+	return ok, record.named("SpliceOutRecurrent")
+}
+
+func SpliceOutNonRecurrent(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+	ok, record := ReattemptingNeuronMutator(random, cortex, NeuronSpliceOutNonRecurrent)
+	return ok, record.named("SpliceOutNonRecurrent")
+}
+
+func NoOpMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
 	success = true
-	result = "nothing"
+	result = newMutationRecord("NoOpMutator")
+	return
+}
+
+func MutateAllWeightsBellCurve(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	success, result = MutateAllWeightsWithStepSize(random, cortex, DEFAULT_STD_DEVIATION)
+	result = result.named("MutateAllWeightsBellCurve")
 	return
 }
 
-func MutateAllWeightsBellCurve(cortex *ng.Cortex) (success bool, result MutateResult) {
+// Perturb every weight and bias by a normally distributed amount with a
+// standard deviation of stdDev.  See SelfAdaptation.
+func MutateAllWeightsWithStepSize(random *rand.Rand, cortex *ng.Cortex, stdDev float64) (success bool, result MutateResult) {
 
-	stdDev := DEFAULT_STD_DEVIATION
+	before := snapshotParameters(cortex.Neurons)
 
 	for _, neuron := range cortex.Neurons {
 		for _, inboundConnection := range neuron.Inbound {
 			weights := inboundConnection.Weights
 			for k, weight := range weights {
-				newWeight := perturbParameterBellCurve(weight, stdDev)
+				newWeight := perturbParameterBellCurve(random, weight, stdDev)
 				weights[k] = newWeight
 			}
 		}
 
-		newBias := perturbParameterBellCurve(neuron.Bias, stdDev)
+		newBias := perturbParameterBellCurve(random, neuron.Bias, stdDev)
 		neuron.Bias = newBias
 
 	}
 
 	success = true
-	result = "nothing"
+	result = newMutationRecord("MutateAllWeightsWithStepSize")
+	result.addParameterChanges(before, cortex.Neurons)
 	return
 }
 
-func TopologyOrWeightMutator(cortex *ng.Cortex) (success bool, result MutateResult) {
+// The policy used by TopologyOrWeightMutator, which mutates the topology
+// 4% of the time, by retrying non recurrent topological mutators until
+// one works, and perturbs the weights the rest of the time
+func NewTopologyOrWeightPolicy() *MutationPolicy {
+
+	topology := NewMutationPolicy(CortexMutatorsNonRecurrent(false))
 
-	randomNumber := ng.RandomIntInRange(0, 100)
-	if randomNumber > 95 {
+	mutateTopology := func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 		logg.LogTo("NEURVOLVE", "Attempting to mutate topology")
 
 		// before we mutate the cortex, we need to init it,
@@ -655,30 +1098,36 @@ func TopologyOrWeightMutator(cortex *ng.Cortex) (success bool, result MutateResu
 		// there are no DataChan's.
 		cortex.Init()
 
-		// apply topological mutation
-		didMutate := false
-		includeNonTopological := false
-		mutators := CortexMutatorsNonRecurrent(includeNonTopological)
-		for i := 0; i <= 100; i++ {
-			randInt := RandomIntInRange(0, len(mutators))
-			mutator := mutators[randInt]
-			didMutate, _ = mutator(cortex)
-			if !didMutate {
-				logg.LogTo("NEURVOLVE", "Mutate didn't work, retrying...")
-				continue
-			}
-			break
-		}
+		didMutate, result := topology.Mutate(random, cortex)
 		logg.LogTo("NEURVOLVE", "did mutate: %v", didMutate)
-		success = didMutate
-	} else {
+		return didMutate, result
+	}
+
+	mutateWeights := func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
 		logg.LogTo("NEURVOLVE", "Attempting to mutate weights")
-		// mutate the weights
+		before := snapshotParameters(cortex.Neurons)
 		saturationBounds := []float64{-10 * math.Pi, 10 * math.Pi}
-		PerturbParameters(cortex, saturationBounds)
-		success = true
+		PerturbParameters(random, cortex, saturationBounds)
+		record := newMutationRecord("PerturbParameters")
+		record.addParameterChanges(before, cortex.Neurons)
+		return true, record
 	}
 
-	result = "nothing"
-	return
+	return &MutationPolicy{
+		Operators: []MutationOperator{
+			{Name: "topology", Mutator: mutateTopology, Probability: 0.04},
+			{Name: "weights", Mutator: mutateWeights, Probability: 0.96},
+		},
+		MaxAttempts: 1,
+	}
+
+}
+
+// Mutates the topology 4% of the time, and perturbs the weights the rest
+// of the time.  Each call uses a new policy, so nothing is shared between
+// the trainers using it.  To change the probabilities, or to see how
+// often each kind of mutation was made, give the trainer its own
+// NewTopologyOrWeightPolicy instead.
+func TopologyOrWeightMutator(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+	return NewTopologyOrWeightPolicy().Mutate(random, cortex)
 }
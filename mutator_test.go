@@ -22,6 +22,8 @@ func TestVisualize(t *testing.T) {
 
 func TestOutspliceRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	logg.LogKeys["TEST"] = true
 	logg.LogKeys["NEURVOLVE"] = true
 
@@ -46,8 +48,7 @@ func TestOutspliceRecurrent(t *testing.T) {
 
 		numNeuronsBefore := len(cortex.Neurons)
 		neuronLayerMapBefore := cortex.NeuronLayerMap()
-		ok, mutateResult := OutspliceRecurrent(cortex)
-		neuron := mutateResult.(*ng.Neuron)
+		ok, mutateResult := OutspliceRecurrent(random, cortex)
 
 		if !ok {
 			continue
@@ -55,6 +56,11 @@ func TestOutspliceRecurrent(t *testing.T) {
 			numOutspliced += 1
 		}
 
+		neuron := cortex.FindNeuron(mutateResult.AddedNodes[0])
+		assert.Equals(t, mutateResult.Operator, "OutspliceRecurrent")
+		assert.Equals(t, len(mutateResult.AddedLinks), 2)
+		assert.Equals(t, len(mutateResult.RemovedLinks), 1)
+
 		assert.True(t, neuron.ActivationFunction != nil)
 		numNeuronsAfter := len(cortex.Neurons)
 		assert.Equals(t, numNeuronsAfter, numNeuronsBefore+1)
@@ -87,6 +93,8 @@ func TestOutspliceRecurrent(t *testing.T) {
 
 func TestOutspliceNonRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	numOutspliced := 0
@@ -97,8 +105,7 @@ func TestOutspliceNonRecurrent(t *testing.T) {
 		cortex := BasicCortex()
 		numNeuronsBefore := len(cortex.Neurons)
 		neuronLayerMapBefore := cortex.NeuronLayerMap()
-		ok, mutateResult := OutspliceNonRecurrent(cortex)
-		neuron := mutateResult.(*ng.Neuron)
+		ok, mutateResult := OutspliceNonRecurrent(random, cortex)
 
 		if !ok {
 			continue
@@ -106,6 +113,8 @@ func TestOutspliceNonRecurrent(t *testing.T) {
 			numOutspliced += 1
 		}
 
+		neuron := cortex.FindNeuron(mutateResult.AddedNodes[0])
+
 		assert.True(t, neuron.ActivationFunction != nil)
 		numNeuronsAfter := len(cortex.Neurons)
 		assert.Equals(t, numNeuronsAfter, numNeuronsBefore+1)
@@ -137,6 +146,8 @@ func TestOutspliceNonRecurrent(t *testing.T) {
 
 func TestAddNeuronNonRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	numUnableToAdd := 0
@@ -146,14 +157,14 @@ func TestAddNeuronNonRecurrent(t *testing.T) {
 
 		cortex := BasicCortex()
 		numNeuronsBefore := len(cortex.Neurons)
-		ok, mutateResult := AddNeuronNonRecurrent(cortex)
+		ok, mutateResult := AddNeuronNonRecurrent(random, cortex)
 
 		if !ok {
 			numUnableToAdd += 1
 			continue
 		}
 
-		neuron := mutateResult.(*ng.Neuron)
+		neuron := cortex.FindNeuron(mutateResult.AddedNodes[0])
 		assert.True(t, neuron.ActivationFunction != nil)
 		numNeuronsAfter := len(cortex.Neurons)
 		addedNeuron := numNeuronsAfter == numNeuronsBefore+1
@@ -183,6 +194,8 @@ func TestAddNeuronNonRecurrent(t *testing.T) {
 
 func TestAddNeuronRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	numAdded := 0
@@ -192,8 +205,7 @@ func TestAddNeuronRecurrent(t *testing.T) {
 
 		cortex := BasicCortex()
 		numNeuronsBefore := len(cortex.Neurons)
-		ok, mutateResult := AddNeuronRecurrent(cortex)
-		neuron := mutateResult.(*ng.Neuron)
+		ok, mutateResult := AddNeuronRecurrent(random, cortex)
 
 		if !ok {
 			continue
@@ -201,6 +213,8 @@ func TestAddNeuronRecurrent(t *testing.T) {
 			numAdded += 1
 		}
 
+		neuron := cortex.FindNeuron(mutateResult.AddedNodes[0])
+
 		assert.True(t, neuron != nil)
 		assert.True(t, neuron.ActivationFunction != nil)
 		numNeuronsAfter := len(cortex.Neurons)
@@ -224,17 +238,19 @@ func TestAddNeuronRecurrent(t *testing.T) {
 
 func TestNeuronAddInlinkRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	madeNonRecurrentInlink := false
 	madeRecurrentInlink := false
 
 	for i := 0; i < 100; i++ {
 		xnorCortex := ng.XnorCortex()
 		neuron := xnorCortex.NeuronUUIDMap()["output-neuron"]
-		ok, mutateResult := NeuronAddInlinkRecurrent(neuron)
+		ok, mutateResult := NeuronAddInlinkRecurrent(random, neuron)
 		if !ok {
 			continue
 		}
-		inboundConnection := mutateResult.(*ng.InboundConnection)
+		inboundConnection := inboundFrom(neuron, mutateResult.AddedLinks[0].Source)
 		if neuron.IsInboundConnectionRecurrent(inboundConnection) {
 
 			// the first time we make a nonRecurrentInlink,
@@ -272,6 +288,8 @@ func TestNeuronAddInlinkRecurrent(t *testing.T) {
 
 func TestNeuronAddInlinkNonRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	madeNonRecurrentInlink := false
@@ -297,15 +315,15 @@ func TestNeuronAddInlinkNonRecurrent(t *testing.T) {
 
 		hiddenNeuron3.Init()
 		xnorCortex.Neurons = append(xnorCortex.Neurons, hiddenNeuron3)
-		weights := randomWeights(sensor.VectorLength)
+		weights := randomWeights(random, sensor.VectorLength)
 		sensor.ConnectOutbound(hiddenNeuron3)
 		hiddenNeuron3.ConnectInboundWeighted(sensor, weights)
 
-		ok, mutateResult := NeuronAddInlinkNonRecurrent(neuron)
+		ok, mutateResult := NeuronAddInlinkNonRecurrent(random, neuron)
 		if !ok {
 			continue
 		}
-		inboundConnection := mutateResult.(*ng.InboundConnection)
+		inboundConnection := inboundFrom(neuron, mutateResult.AddedLinks[0].Source)
 
 		if neuron.IsInboundConnectionRecurrent(inboundConnection) {
 			madeRecurrentInlink = true
@@ -319,7 +337,7 @@ func TestNeuronAddInlinkNonRecurrent(t *testing.T) {
 			// sensor.  if it was the sensor, then the hiddenNeuron3
 			// is "dangliing" and so lets connect it
 			if inboundConnection.NodeId.UUID == "sensor" {
-				weights2 := randomWeights(1)
+				weights2 := randomWeights(random, 1)
 				hiddenNeuron3.ConnectOutbound(neuron)
 				neuron.ConnectInboundWeighted(hiddenNeuron3, weights2)
 			}
@@ -341,6 +359,8 @@ func TestNeuronAddInlinkNonRecurrent(t *testing.T) {
 
 func TestNeuronAddOutlinkNonRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	madeNonRecurrentLink := false
@@ -349,11 +369,11 @@ func TestNeuronAddOutlinkNonRecurrent(t *testing.T) {
 	for i := 0; i < 100; i++ {
 		xnorCortex := BasicCortex()
 		neuron := xnorCortex.NeuronUUIDMap()["hidden-neuron1"]
-		ok, mutateResult := NeuronAddOutlinkNonRecurrent(neuron)
+		ok, mutateResult := NeuronAddOutlinkNonRecurrent(random, neuron)
 		if !ok {
 			continue
 		}
-		outboundConnection := mutateResult.(*ng.OutboundConnection)
+		outboundConnection := outboundTo(neuron, mutateResult.AddedLinks[0].Target)
 		if neuron.IsConnectionRecurrent(outboundConnection) {
 			madeRecurrentLink = true
 		} else {
@@ -369,6 +389,8 @@ func TestNeuronAddOutlinkNonRecurrent(t *testing.T) {
 
 func TestNeuronAddOutlinkRecurrent(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 
 	madeNonRecurrentLink := false
@@ -381,11 +403,11 @@ func TestNeuronAddOutlinkRecurrent(t *testing.T) {
 
 		numOutlinksBefore := len(neuron.Outbound)
 
-		ok, mutateResult := NeuronAddOutlinkRecurrent(neuron)
+		ok, mutateResult := NeuronAddOutlinkRecurrent(random, neuron)
 		if !ok {
 			continue
 		}
-		outboundConnection := mutateResult.(*ng.OutboundConnection)
+		outboundConnection := outboundTo(neuron, mutateResult.AddedLinks[0].Target)
 
 		numOutlinksAfter := len(neuron.Outbound)
 
@@ -428,6 +450,8 @@ func TestNeuronAddOutlinkRecurrent(t *testing.T) {
 
 func TestNeuronMutateWeights(t *testing.T) {
 
+	random := newRandom()
+
 	xnorCortex := ng.XnorCortex()
 	neuron := xnorCortex.NeuronUUIDMap()["output-neuron"]
 	assert.True(t, neuron != nil)
@@ -436,7 +460,7 @@ func TestNeuronMutateWeights(t *testing.T) {
 	foundModifiedWeight := false
 	for i := 0; i < 100; i++ {
 
-		didMutateWeights, _ := NeuronMutateWeights(neuron)
+		didMutateWeights, _ := NeuronMutateWeights(random, neuron)
 		if didMutateWeights == true {
 
 			foundModifiedWeight = verifyWeightsModified(neuron, neuronCopy)
@@ -455,6 +479,8 @@ func TestNeuronMutateWeights(t *testing.T) {
 
 func TestNeuronResetWeights(t *testing.T) {
 
+	random := newRandom()
+
 	xnorCortex := ng.XnorCortex()
 	neuron := xnorCortex.NeuronUUIDMap()["output-neuron"]
 	assert.True(t, neuron != nil)
@@ -463,7 +489,7 @@ func TestNeuronResetWeights(t *testing.T) {
 	foundModifiedWeight := false
 	for i := 0; i < 100; i++ {
 
-		NeuronResetWeights(neuron)
+		NeuronResetWeights(random, neuron)
 		foundModifiedWeight = verifyWeightsModified(neuron, neuronCopy)
 
 		if foundModifiedWeight == true {
@@ -478,33 +504,47 @@ func TestNeuronResetWeights(t *testing.T) {
 
 func TestNeuronMutateActivation(t *testing.T) {
 
+	random := newRandom()
+
 	ng.SeedRandom()
 	neuron := &ng.Neuron{
 		ActivationFunction: ng.EncodableSigmoid(),
 		NodeId:             ng.NewNeuronId("neuron", 0.25),
 		Bias:               10,
 	}
-	NeuronMutateActivation(neuron)
+	_, mutateResult := NeuronMutateActivation(random, neuron)
 	assert.True(t, neuron.ActivationFunction != nil)
 	assert.True(t, neuron.ActivationFunction.Name != ng.EncodableSigmoid().Name)
 
+	change := mutateResult.ActivationChanges[0]
+	assert.Equals(t, change.Neuron.UUID, "neuron")
+	assert.Equals(t, change.OldActivation, ng.EncodableSigmoid().Name)
+	assert.Equals(t, change.NewActivation, neuron.ActivationFunction.Name)
+
 }
 
 func TestNeuronRemoveBias(t *testing.T) {
 
+	random := newRandom()
+
 	neuron := &ng.Neuron{
 		ActivationFunction: ng.EncodableSigmoid(),
 		NodeId:             ng.NewNeuronId("neuron", 0.25),
 		Bias:               10,
 	}
 	neuron.Init()
-	NeuronRemoveBias(neuron)
+	_, mutateResult := NeuronRemoveBias(random, neuron)
 	assert.True(t, neuron.Bias == 0)
+	assert.Equals(t, mutateResult.Operator, "NeuronRemoveBias")
+	assert.Equals(t, mutateResult.BiasChanges[0].OldBias, 10.0)
+	assert.Equals(t, mutateResult.BiasChanges[0].NewBias, 0.0)
 
 }
 
 func TestNeuronAddBias(t *testing.T) {
 
+	random := newRandom()
+
 	// basic case where there is no bias
 
 	neuron := &ng.Neuron{
@@ -513,7 +553,7 @@ func TestNeuronAddBias(t *testing.T) {
 	}
 	neuron.Init()
 
-	NeuronAddBias(neuron)
+	NeuronAddBias(random, neuron)
 	assert.True(t, neuron.Bias != 0)
 
 	// make sure it treats 0 bias as not having a bias
@@ -525,7 +565,7 @@ func TestNeuronAddBias(t *testing.T) {
 	}
 	neuron.Init()
 
-	NeuronAddBias(neuron)
+	NeuronAddBias(random, neuron)
 	assert.True(t, neuron.Bias != 0)
 
 	// make sure it doesn't add a bias if there is an existing one
@@ -536,24 +576,27 @@ func TestNeuronAddBias(t *testing.T) {
 		Bias:               10,
 	}
 	neuron.Init()
-	NeuronAddBias(neuron)
+	NeuronAddBias(random, neuron)
 	assert.True(t, neuron.Bias == 10)
 
 }
 
 func TestAddBias(t *testing.T) {
+	random := newRandom()
 	xnorCortex := ng.XnorCortex()
 	for _, neuron := range xnorCortex.Neurons {
 		neuron.Bias = 0.0
 	}
 	beforeString := ng.JsonString(xnorCortex)
-	AddBias(xnorCortex)
+	AddBias(random, xnorCortex)
 	afterString := ng.JsonString(xnorCortex)
 	assert.True(t, beforeString != afterString)
 }
 
 func TestMutatorsThatAlwaysMutate(t *testing.T) {
 
+	random := newRandom()
+
 	testCortex := BasicCortex()
 	cortexMutators := []CortexMutator{
 		RemoveBias,
@@ -571,7 +614,7 @@ func TestMutatorsThatAlwaysMutate(t *testing.T) {
 	}
 	for _, cortexMutator := range cortexMutators {
 		beforeString := ng.JsonString(testCortex)
-		ok, _ := cortexMutator(testCortex)
+		ok, _ := cortexMutator(random, testCortex)
 		assert.True(t, ok)
 		afterString := ng.JsonString(testCortex)
 		hasChanged := beforeString != afterString
@@ -588,11 +631,12 @@ func TestMutatorsThatAlwaysMutate(t *testing.T) {
 }
 
 func TestMutateAllWeightsBellCurve(t *testing.T) {
+	random := newRandom()
 	testCortex := BasicCortex()
 	testCortexCopy := testCortex.Copy()
 
 	// make a copy and mutate weights
-	_, _ = MutateAllWeightsBellCurve(testCortexCopy)
+	_, _ = MutateAllWeightsBellCurve(random, testCortexCopy)
 
 	// make sure all weights are different in copy
 	for _, neuron := range testCortex.Neurons {
@@ -629,3 +673,260 @@ func verifyWeightsModified(neuron, neuronCopy *ng.Neuron) bool {
 	return foundModifiedWeight
 
 }
+
+func inboundFrom(neuron *ng.Neuron, source *ng.NodeId) *ng.InboundConnection {
+	for _, connection := range neuron.Inbound {
+		if connection.NodeId.UUID == source.UUID {
+			return connection
+		}
+	}
+	return nil
+}
+
+func outboundTo(neuron *ng.Neuron, target *ng.NodeId) *ng.OutboundConnection {
+	for _, connection := range neuron.Outbound {
+		if connection.NodeId.UUID == target.UUID {
+			return connection
+		}
+	}
+	return nil
+}
+
+func numConnections(cortex *ng.Cortex) (numInbound int, numOutbound int) {
+	for _, neuron := range cortex.Neurons {
+		numInbound += len(neuron.Inbound)
+		numOutbound += len(neuron.Outbound)
+	}
+	for _, actuator := range cortex.Actuators {
+		numInbound += len(actuator.Inbound)
+	}
+	for _, sensor := range cortex.Sensors {
+		numOutbound += len(sensor.Outbound)
+	}
+	return
+}
+
+func TestRemoveNeuronNonRecurrent(t *testing.T) {
+
+	random := newRandom()
+
+	// every neuron in a chain is the only link between its neighbors
+	cortex := BasicCortex()
+	ok, _ := RemoveNeuronNonRecurrent(random, cortex)
+	assert.False(t, ok)
+
+	for i := 0; i < 50; i++ {
+
+		cortex = BasicCortex()
+		ok, _ = AddNeuronNonRecurrent(random, cortex)
+		assert.True(t, ok)
+
+		ok, mutateResult := RemoveNeuronNonRecurrent(random, cortex)
+		assert.True(t, ok)
+		removed := mutateResult.RemovedNodes[0]
+		assert.Equals(t, len(cortex.Neurons), 4)
+		assert.True(t, cortex.FindNeuron(removed) == nil)
+		assert.True(t, cortex.Validate())
+
+		numInbound, numOutbound := numConnections(cortex)
+		assert.Equals(t, numInbound, numOutbound)
+		assert.Equals(t, len(cortex.Actuators[0].Inbound), 1)
+
+		fitness := cortex.Fitness(ng.XnorTrainingSamples())
+		assert.True(t, fitness >= 0)
+	}
+
+}
+
+// A cortex whose actuator takes a value from each of two neurons, and
+// where neuron1 also feeds neuron2
+func twoInputActuatorCortex() *ng.Cortex {
+
+	sensor := &ng.Sensor{
+		NodeId:       ng.NewSensorId("sensor", 0.0),
+		VectorLength: 1,
+	}
+	sensor.Init()
+
+	neuron1 := &ng.Neuron{
+		ActivationFunction: ng.EncodableIdentity(),
+		NodeId:             ng.NewNeuronId("neuron1", 0.15),
+	}
+	neuron1.Init()
+
+	neuron2 := &ng.Neuron{
+		ActivationFunction: ng.EncodableIdentity(),
+		NodeId:             ng.NewNeuronId("neuron2", 0.25),
+	}
+	neuron2.Init()
+
+	actuator := &ng.Actuator{
+		NodeId:       ng.NewActuatorId("actuator", 0.5),
+		VectorLength: 2,
+	}
+	actuator.Init()
+
+	sensor.ConnectOutbound(neuron1)
+	neuron1.ConnectInboundWeighted(sensor, []float64{1})
+	sensor.ConnectOutbound(neuron2)
+	neuron2.ConnectInboundWeighted(sensor, []float64{1})
+
+	neuron1.ConnectOutbound(neuron2)
+	neuron2.ConnectInboundWeighted(neuron1, []float64{1})
+
+	neuron1.ConnectOutbound(actuator)
+	actuator.ConnectInbound(neuron1)
+	neuron2.ConnectOutbound(actuator)
+	actuator.ConnectInbound(neuron2)
+
+	cortex := &ng.Cortex{
+		NodeId: ng.NewCortexId("cortex"),
+	}
+	cortex.SetSensors([]*ng.Sensor{sensor})
+	cortex.SetNeurons([]*ng.Neuron{neuron1, neuron2})
+	cortex.SetActuators([]*ng.Actuator{actuator})
+
+	return cortex
+
+}
+
+func TestRemoveKeepsActuatorInputs(t *testing.T) {
+
+	random := newRandom()
+
+	// neither neuron can go, since each is an input of the actuator
+	cortex := twoInputActuatorCortex()
+	for _, neuron := range cortex.Neurons {
+		ok, _ := NeuronRemoveRecurrent(random, neuron)
+		assert.False(t, ok)
+	}
+
+	// the only outlink of neuron1 that can go is the one to neuron2
+	for i := 0; i < 20; i++ {
+		cortex = twoInputActuatorCortex()
+		ok, mutateResult := NeuronRemoveOutlinkRecurrent(random, cortex.Neurons[0])
+		assert.True(t, ok)
+		assert.Equals(t, mutateResult.RemovedLinks[0].Target.UUID, "neuron2")
+		assert.Equals(t, len(cortex.Actuators[0].Inbound), 2)
+		assert.True(t, cortex.Validate())
+	}
+
+	// and no inlink of the actuator can go
+	cortex = twoInputActuatorCortex()
+	assert.False(t, canRemoveConnection(cortex, cortex.Neurons[0].NodeId, cortex.Actuators[0].NodeId))
+
+}
+
+func TestRemoveInlinkNonRecurrent(t *testing.T) {
+
+	random := newRandom()
+
+	cortex := BasicCortex()
+	ok, _ := RemoveInlinkNonRecurrent(random, cortex)
+	assert.False(t, ok)
+
+	for i := 0; i < 50; i++ {
+
+		cortex = BasicCortex()
+		numInboundBefore, _ := numConnections(cortex)
+		ok, _ = AddInlinkNonRecurrent(random, cortex)
+		assert.True(t, ok)
+
+		ok, _ = RemoveInlinkNonRecurrent(random, cortex)
+		assert.True(t, ok)
+		assert.True(t, cortex.Validate())
+
+		numInbound, numOutbound := numConnections(cortex)
+		assert.Equals(t, numInbound, numInboundBefore)
+		assert.Equals(t, numOutbound, numInboundBefore)
+		for _, neuron := range cortex.Neurons {
+			assert.True(t, len(neuron.Inbound) > 0)
+			assert.True(t, len(neuron.Outbound) > 0)
+		}
+	}
+
+}
+
+func TestRemoveOutlinkRecurrent(t *testing.T) {
+
+	random := newRandom()
+
+	for i := 0; i < 50; i++ {
+
+		cortex := BasicCortexRecurrent()
+		numInboundBefore, _ := numConnections(cortex)
+		ok, _ := AddOutlinkRecurrent(random, cortex)
+		assert.True(t, ok)
+
+		ok, _ = RemoveOutlinkRecurrent(random, cortex)
+		assert.True(t, ok)
+		assert.True(t, cortex.Validate())
+
+		numInbound, numOutbound := numConnections(cortex)
+		assert.Equals(t, numInbound, numInboundBefore)
+		assert.Equals(t, numOutbound, numInboundBefore)
+		assert.Equals(t, len(cortex.Actuators[0].Inbound), 1)
+
+		fitness := cortex.Fitness(ng.XnorTrainingSamples())
+		assert.True(t, fitness >= 0)
+	}
+
+}
+
+func TestSpliceOutNonRecurrent(t *testing.T) {
+
+	random := newRandom()
+
+	for i := 0; i < 50; i++ {
+
+		cortex := BasicCortex()
+		ok, mutateResult := SpliceOutNonRecurrent(random, cortex)
+		assert.True(t, ok)
+		removed := mutateResult.RemovedNodes[0]
+		assert.Equals(t, len(cortex.Neurons), 3)
+		assert.True(t, cortex.FindNeuron(removed) == nil)
+		assert.True(t, cortex.Validate())
+
+		// still a chain from the sensor to the actuator
+		for _, neuron := range cortex.Neurons {
+			assert.Equals(t, len(neuron.Inbound), 1)
+			assert.Equals(t, len(neuron.Outbound), 1)
+		}
+		assert.Equals(t, len(cortex.Actuators[0].Inbound), 1)
+		assert.Equals(t, len(cortex.Sensors[0].Outbound), 1)
+
+		fitness := cortex.Fitness(ng.XnorTrainingSamples())
+		assert.True(t, fitness >= 0)
+	}
+
+}
+
+func TestSpliceOutScalesWeights(t *testing.T) {
+
+	random := newRandom()
+
+	// splice out hidden-neuron2, between hidden-neuron1 and hidden-neuron3
+	cortex := BasicCortex()
+	neuron2 := cortex.Neurons[1]
+	neuron3 := cortex.Neurons[2]
+	neuron2.Inbound[0].Weights = []float64{2}
+	neuron3.Inbound[0].Weights = []float64{3}
+
+	ok, mutateResult := NeuronSpliceOutNonRecurrent(random, neuron2)
+	assert.True(t, ok)
+	assert.Equals(t, neuron3.Inbound[0].NodeId.UUID, "hidden-neuron1")
+	assert.DeepEquals(t, neuron3.Inbound[0].Weights, []float64{6})
+
+	// the record has everything needed to replay it
+	assert.Equals(t, mutateResult.Operator, "NeuronSpliceOutNonRecurrent")
+	assert.Equals(t, mutateResult.RemovedNodes[0].UUID, "hidden-neuron2")
+	assert.Equals(t, len(mutateResult.RemovedLinks), 2)
+	assert.DeepEquals(t, mutateResult.RemovedLinks[0].Weights, []float64{2})
+	assert.DeepEquals(t, mutateResult.RemovedLinks[1].Weights, []float64{3})
+	assert.Equals(t, len(mutateResult.AddedLinks), 1)
+	assert.Equals(t, mutateResult.AddedLinks[0].Source.UUID, "hidden-neuron1")
+	assert.Equals(t, mutateResult.AddedLinks[0].Target.UUID, "hidden-neuron3")
+	assert.DeepEquals(t, mutateResult.AddedLinks[0].Weights, []float64{6})
+	assert.Equals(t, len(mutateResult.NodeIds), 3)
+
+}
@@ -0,0 +1,187 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Scapes which can describe how a cortex behaves, rather than just how
+// well it did.  For example the behavior could be the output vector of
+// the cortex over a set of training samples, or where it ended up in a
+// maze.  Two cortexes with similar behavior should have behavior vectors
+// that are close together.
+type BehaviorScape interface {
+	Scape
+	Behavior(cortex *ng.Cortex) []float64
+}
+
+// Optionally implemented by a Recorder to be told about each behavior
+// added to the novelty archive
+type NoveltyArchiveRecorder interface {
+	AddToNoveltyArchive(generation int, cortex *ng.Cortex, behavior []float64, novelty float64)
+}
+
+// Rewards cortexes for behaving differently from the rest of the
+// population and from the behaviors seen in earlier generations, which
+// helps on deceptive tasks where following the fitness score leads to a
+// dead end.  Set it as the NoveltySearch of a PopulationTrainer to use it.
+type NoveltySearch struct {
+
+	// The novelty of a behavior is its mean distance to the K nearest
+	// behaviors in the population and the archive.  Defaults to 15.
+	K int
+
+	// Behaviors with a novelty above this are added to the archive
+	AdditionThreshold float64
+
+	// When the archive is larger than this, the oldest behaviors are
+	// dropped.  0 means the archive can grow without limit.
+	MaxArchiveSize int
+
+	// How much novelty counts for, from 0 to 1.  The score that cortexes
+	// are selected by is NoveltyWeight * novelty plus 1 - NoveltyWeight
+	// times the fitness.  The fitness threshold is always checked against
+	// the fitness alone.  Defaults to 1, scoring by novelty alone; to
+	// select by fitness alone, leave NoveltySearch unset instead.
+	NoveltyWeight float64
+
+	archive [][]float64
+	mutex   sync.RWMutex
+}
+
+func NewNoveltySearch(k int, additionThreshold float64) *NoveltySearch {
+	return &NoveltySearch{
+		K:                 k,
+		AdditionThreshold: additionThreshold,
+		NoveltyWeight:     1.0,
+		archive:           make([][]float64, 0),
+	}
+}
+
+// A copy of the behaviors in the archive, oldest first
+func (n *NoveltySearch) Archive() [][]float64 {
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	archive := make([][]float64, 0)
+	for _, behavior := range n.archive {
+		archive = append(archive, append([]float64{}, behavior...))
+	}
+	return archive
+
+}
+
+// Replace the archive with one saved in a checkpoint
+func (n *NoveltySearch) restoreArchive(archive [][]float64) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.archive = make([][]float64, 0)
+	for _, behavior := range archive {
+		n.archive = append(n.archive, append([]float64{}, behavior...))
+	}
+}
+
+// Score the novelty of each member of the population, whose Behavior must
+// already be set, then add the most novel behaviors to the archive.  In
+// the scored copy of the population that is returned, the Fitness of each
+// member is replaced with its blend of novelty and fitness, and the
+// fitness it had is kept as its RawFitness.
+func (n *NoveltySearch) score(population []EvaluatedCortex, generation int, recorder Recorder) (scored []EvaluatedCortex) {
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	scored = make([]EvaluatedCortex, len(population))
+	for i, evaldCortex := range population {
+		scored[i] = evaldCortex
+		scored[i].Novelty = n.novelty(i, population)
+		scored[i].RawFitness = evaldCortex.Fitness
+	}
+
+	weight := n.NoveltyWeight
+	if weight == 0 {
+		weight = 1
+	}
+
+	archiveRecorder, hasArchiveRecorder := recorder.(NoveltyArchiveRecorder)
+	for i, evaldCortex := range scored {
+
+		if evaldCortex.Novelty > n.AdditionThreshold {
+			n.archive = append(n.archive, append([]float64{}, evaldCortex.Behavior...))
+			if hasArchiveRecorder {
+				archiveRecorder.AddToNoveltyArchive(generation, evaldCortex.Cortex, evaldCortex.Behavior, evaldCortex.Novelty)
+			}
+		}
+
+		scored[i].Fitness = weight*evaldCortex.Novelty + (1-weight)*evaldCortex.Fitness
+	}
+
+	if n.MaxArchiveSize > 0 && len(n.archive) > n.MaxArchiveSize {
+		n.archive = n.archive[len(n.archive)-n.MaxArchiveSize:]
+	}
+
+	return
+
+}
+
+// The mean distance from the behavior of population[index] to its k
+// nearest neighbours among the rest of the population and the archive
+func (n *NoveltySearch) novelty(index int, population []EvaluatedCortex) float64 {
+
+	behavior := population[index].Behavior
+
+	distances := make([]float64, 0)
+	for i, other := range population {
+		if i != index {
+			distances = append(distances, behaviorDistance(behavior, other.Behavior))
+		}
+	}
+	for _, archived := range n.archive {
+		distances = append(distances, behaviorDistance(behavior, archived))
+	}
+	if len(distances) == 0 {
+		return 0
+	}
+
+	sort.Float64s(distances)
+
+	k := n.K
+	if k <= 0 {
+		k = 15
+	}
+	if k > len(distances) {
+		k = len(distances)
+	}
+
+	total := 0.0
+	for _, distance := range distances[:k] {
+		total += distance
+	}
+	return total / float64(k)
+
+}
+
+// The euclidean distance between two behaviors.  Any values past the end
+// of the shorter behavior are compared against 0.
+func behaviorDistance(behavior, other []float64) float64 {
+	length := len(behavior)
+	if len(other) > length {
+		length = len(other)
+	}
+	total := 0.0
+	for i := 0; i < length; i++ {
+		difference := valueAt(behavior, i) - valueAt(other, i)
+		total += difference * difference
+	}
+	return math.Sqrt(total)
+}
+
+func valueAt(values []float64, index int) float64 {
+	if index < len(values) {
+		return values[index]
+	}
+	return 0
+}
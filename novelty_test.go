@@ -0,0 +1,145 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestNoveltyScore(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Behavior: []float64{0, 0}},
+		{Behavior: []float64{0, 1}},
+		{Behavior: []float64{0, 5}},
+	}
+
+	noveltySearch := NewNoveltySearch(1, 3.0)
+	scored := noveltySearch.score(population, 0, NullRecorder{})
+
+	// the distance to the single nearest neighbour
+	assert.Equals(t, scored[0].Novelty, 1.0)
+	assert.Equals(t, scored[1].Novelty, 1.0)
+	assert.Equals(t, scored[2].Novelty, 4.0)
+	assert.Equals(t, scored[2].Fitness, 4.0)
+
+	// the population itself is unchanged
+	assert.Equals(t, population[2].Fitness, 0.0)
+
+	// only the most novel behavior was archived
+	assert.DeepEquals(t, noveltySearch.Archive(), [][]float64{{0, 5}})
+
+	// an archived behavior is no longer novel
+	scored = noveltySearch.score([]EvaluatedCortex{{Behavior: []float64{0, 5}}}, 1, NullRecorder{})
+	assert.Equals(t, scored[0].Novelty, 0.0)
+
+}
+
+func TestNoveltyBlend(t *testing.T) {
+
+	population := []EvaluatedCortex{{Behavior: []float64{0}}, {Behavior: []float64{2}}}
+	population[0].Fitness = 10
+
+	noveltySearch := NewNoveltySearch(1, 100)
+	noveltySearch.NoveltyWeight = 0.5
+	scored := noveltySearch.score(population, 0, NullRecorder{})
+	assert.Equals(t, scored[0].Fitness, 6.0)
+	assert.Equals(t, scored[1].Fitness, 1.0)
+
+}
+
+func TestNoveltyWeightDefault(t *testing.T) {
+
+	population := []EvaluatedCortex{{Behavior: []float64{0}}, {Behavior: []float64{2}}}
+	population[0].Fitness = 10
+
+	// a zero NoveltyWeight scores by novelty alone, like NewNoveltySearch
+	noveltySearch := &NoveltySearch{K: 1, AdditionThreshold: 100}
+	scored := noveltySearch.score(population, 0, NullRecorder{})
+	assert.Equals(t, scored[0].Fitness, 2.0)
+	assert.Equals(t, scored[1].Fitness, 2.0)
+
+}
+
+func TestNoveltyMaxArchiveSize(t *testing.T) {
+
+	noveltySearch := NewNoveltySearch(1, 0)
+	noveltySearch.MaxArchiveSize = 2
+	population := []EvaluatedCortex{
+		{Behavior: []float64{0}},
+		{Behavior: []float64{1}},
+		{Behavior: []float64{3}},
+	}
+	noveltySearch.score(population, 0, NullRecorder{})
+	assert.DeepEquals(t, noveltySearch.Archive(), [][]float64{{1}, {3}})
+
+}
+
+type FakeScapeBehavior struct {
+	FakeScapeBiasSum
+}
+
+func (scape FakeScapeBehavior) Behavior(cortex *ng.Cortex) []float64 {
+	return []float64{scape.Fitness(cortex)}
+}
+
+type FakeNoveltyArchiveRecorder struct {
+	NullRecorder
+	numArchived int
+}
+
+func (r *FakeNoveltyArchiveRecorder) AddToNoveltyArchive(generation int, cortex *ng.Cortex, behavior []float64, novelty float64) {
+	r.numArchived += 1
+}
+
+func TestTrainNoveltySearch(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		NoveltySearch:    NewNoveltySearch(2, 0.5),
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		BasicCortex(),
+	}
+
+	recorder := &FakeNoveltyArchiveRecorder{}
+	trainedPopulation, _, err := pt.Train(population, FakeScapeBehavior{}, recorder)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(trainedPopulation), 3)
+	assert.True(t, recorder.numArchived > 0)
+	assert.Equals(t, len(pt.NoveltySearch.Archive()), recorder.numArchived)
+
+}
+
+func TestNoveltySearchCreditsRawFitness(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		NoveltySearch: NewNoveltySearch(1, 1000),
+		MutationPolicy: &MutationPolicy{
+			Operators: []MutationOperator{
+				{Name: "increase", Mutator: addToBiases(1), Probability: 1},
+			},
+		},
+	}
+
+	population := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 1, Behavior: []float64{0}},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 2, Behavior: []float64{100}},
+	}
+	scored := pt.NoveltySearch.score(population, 0, NullRecorder{})
+	assert.Equals(t, scored[0].Fitness, 100.0)
+	assert.Equals(t, scored[0].RawFitness, 1.0)
+
+	// the offspring is credited against the fitness its parent was given
+	// by the scape, not the parent's novelty
+	offspring, err := pt.breed(scored[:1], 1)
+	assert.True(t, err == nil)
+	credit := pt.mutationCredits[offspring[0].Cortex.NodeId.UUID]
+	assert.Equals(t, credit.parentFitness, 1.0)
+
+}
@@ -18,3 +18,23 @@ func (r NullRecorder) AddGeneration(evaldCortexes []EvaluatedCortex) {
 func (r NullRecorder) AddFitnessScore(score float64, cortex *ng.Cortex, opponent *ng.Cortex) {
 
 }
+
+func (r NullRecorder) AddParetoFront(generation int, front []EvaluatedCortex) {
+
+}
+
+func (r NullRecorder) AddToNoveltyArchive(generation int, cortex *ng.Cortex, behavior []float64, novelty float64) {
+
+}
+
+func (r NullRecorder) AddStagnationEvent(event StagnationEvent) {
+
+}
+
+func (r NullRecorder) AddMutationStats(generation int, stats []MutationStats) {
+
+}
+
+func (r NullRecorder) AddLineage(event LineageEvent) {
+
+}
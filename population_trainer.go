@@ -1,9 +1,12 @@
 package neurvolve
 
 import (
+	"context"
 	"fmt"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/tleyden/neurgo"
+	"math"
+	"math/rand"
 	"sort"
 )
 
@@ -16,62 +19,358 @@ type PopulationTrainer struct {
 	MaxGenerations      int
 	CurrentGeneration   int
 	NumOpponents        int
-	SnapshotRequestChan chan chan EvaluatedCortexes
+	SnapshotRequestChan chan chan PopulationSnapshot
+
+	// The number of goroutines used to compute fitness scores.  Values
+	// of 0 or 1 will compute all fitness scores serially.  When greater
+	// than 1, the scape must be safe to call from multiple goroutines.
+	NumWorkers int
+
+	// Chooses the parents of each new generation.  Defaults to keeping
+	// the top half of the population (TruncationSelector with a 0.5 ratio).
+	Selector Selector
+
+	// The number of offspring each parent has.  Defaults to 1.
+	OffspringPerParent int
+
+	// The number of the fittest cortexes in each generation which are
+	// guaranteed to be carried over unchanged into the next generation.
+	NumElite int
+
+	// If set, keeps the fittest cortexes seen over all generations.
+	// It is included in snapshots, and can be inspected after training
+	// with HallOfFameMembers.
+	HallOfFame *HallOfFame
+
+	// The number of hall of fame members that each cortex is evaluated
+	// against, in addition to the NumOpponents chosen from the population.
+	// Only used when NumOpponents is greater than 0.
+	NumHallOfFameOpponents int
+
+	// If CheckpointInterval is greater than 0, the state of the run is
+	// saved to a new subdirectory of CheckpointDir every CheckpointInterval
+	// generations, so that it can be continued with ResumeFromCheckpoint.
+	CheckpointDir      string
+	CheckpointInterval int
+
+	// If set, the population is divided into species, and each species
+	// is culled and bred separately with a share of the next generation
+	// based on its shared fitness.  NumElite applies to each species.
+	Speciation *Speciation
+
+	// If Crossover is set, each offspring is made by crossing over two
+	// parents with probability CrossoverProbability, and is otherwise a
+	// copy of a single parent.  Either way the offspring is then mutated.
+	Crossover            CortexCrossover
+	CrossoverProbability float64
+
+	// If set, the scape must be a MultiObjectiveScape, and the population
+	// is ordered by Pareto rank and crowding distance as in NSGA-II rather
+	// than by fitness alone.  Selectors which compare fitness scores, such
+	// as TournamentSelector, should be replaced with one which relies on
+	// that order, such as TruncationSelector or CrowdedTournamentSelector.
+	// NumOpponents must be 0.
+	MultiObjective bool
+
+	// If set, the scape must be a BehaviorScape, and cortexes are
+	// selected by a blend of their fitness and the novelty of their
+	// behavior.  Cannot be combined with MultiObjective.
+	NoveltySearch *NoveltySearch
+
+	// If set, fitness is measured by playing the members of the population
+	// against each other in the matches it schedules, instead of against
+	// NumOpponents random opponents.  NumOpponents must be 0, and it
+	// cannot be combined with MultiObjective or NoveltySearch.
+	MatchScheduler MatchScheduler
+
+	// If set, keeps a rating for each cortex based on the results of its
+	// games against opponents, which needs NumOpponents or a
+	// MatchScheduler.  With RatingAsFitness, each cortex's rating is used
+	// as its fitness, so FitnessThreshold becomes a rating threshold.
+	Ratings         RatingSystem
+	RatingAsFitness bool
+
+	// Conditions under which the population counts as stagnant, each with
+	// an action such as stopping or reseeding part of the population.
+	// Their progress is saved in checkpoints.
+	StagnationCriteria []StagnationCriterion
+
+	// If set, each cortex carries its own mutation step size, which is
+	// inherited and adapted by its offspring.  See SelfAdaptation.
+	SelfAdaptation *SelfAdaptation
+
+	// If set, each fitness score is aggregated from several samples, for
+	// scapes whose fitness is noisy.  Only Samples and Aggregation apply,
+	// since the whole population, including the parents, is evaluated
+	// again every generation anyway.  Not used for MultiObjective scapes.
+	EvaluationPolicy *EvaluationPolicy
+
+	// If set, and the scape is deterministic, cortexes whose genotype has
+	// already been evaluated are not evaluated again.  Not used when
+	// cortexes are evaluated against opponents.
+	FitnessCache *FitnessCache
+
+	// If set, it chooses the mutation operator for each offspring instead
+	// of the CortexMutator, and is told which operators produced offspring
+	// fitter than their parents.  Its stats are passed to recorders which
+	// implement MutationStatsRecorder.
+	MutationPolicy *MutationPolicy
+
+	// If set, all random choices are drawn from a source seeded from
+	// Rand, so that runs with the same seed are reproducible.  Otherwise
+	// a source seeded from the clock is used.  Rand is not safe for
+	// concurrent use, so trainers which run at the same time each need
+	// their own.  A run resumed from a checkpoint carries on with the
+	// source saved in the checkpoint instead.
+	Rand *rand.Rand
+
+	random          *rand.Rand
+	source          *countingSource
+	populationSize  int
+	stagnation      []stagnationState
+	pendingReseed   float64
+	seeds           []*ng.Cortex
+	mutationCredits map[string]mutationCredit
+	pendingLineage  []LineageEvent
+	paretoFront     EvaluatedCortexes
+
+	// set by an IslandTrainer, which reports the lineage itself once
+	// migrants have replaced some of the offspring
+	holdLineage bool
 }
 
-func (pt *PopulationTrainer) Train(population []*ng.Cortex, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, succeeded bool) {
+func (pt *PopulationTrainer) Train(population []*ng.Cortex, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, succeeded bool, err error) {
 
-	evaldCortexes := pt.addEmptyFitnessScores(population)
+	trainedPopulation, stopReason, err := pt.TrainContext(context.Background(), population, scape, recorder)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
+
+}
+
+// Same as Train, but stops at the next fitness evaluation once ctx is done.
+// The generation being evaluated at that point is abandoned, and the most
+// recently evaluated population is returned, fittest first.
+func (pt *PopulationTrainer) TrainContext(ctx context.Context, population []*ng.Cortex, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, stopReason StopReason, err error) {
+
+	evaldCortexes, err := pt.start(population, randomOrNew(pt.Rand), scape, recorder)
+	if err != nil {
+		return
+	}
+
+	return pt.train(ctx, evaldCortexes, 0, scape, recorder)
+
+}
+
+// Validate the configuration and prepare the initial population for
+// training, drawing all random choices from random
+func (pt *PopulationTrainer) start(population []*ng.Cortex, random *rand.Rand, scape Scape, recorder Recorder) (evaldCortexes []EvaluatedCortex, err error) {
+
+	if err = pt.validate(len(population), scape); err != nil {
+		return
+	}
+
+	pt.seedRandom(random.Int63(), 0)
+	pt.populationSize = len(population)
+	pt.resetStagnation(population)
+
+	evaldCortexes = pt.addEmptyFitnessScores(population)
 	recorder.AddGeneration(evaldCortexes)
 
-	for i := 0; i < pt.MaxGenerations; i++ {
+	return
+
+}
+
+// Run generations starting at startGeneration, until either the fitness
+// threshold is exceeded, MaxGenerations is reached or ctx is done.
+func (pt *PopulationTrainer) train(ctx context.Context, evaldCortexes []EvaluatedCortex, startGeneration int, scape Scape, recorder Recorder) (trainedPopulation []EvaluatedCortex, stopReason StopReason, err error) {
 
-		pt.CurrentGeneration = i
-		pt.publishSnapshot(evaldCortexes)
+	trainedPopulation = evaldCortexes
 
-		evaldCortexes = pt.computeFitness(evaldCortexes, scape, recorder)
+	for i := startGeneration; i < pt.MaxGenerations; i++ {
 
-		if pt.exceededFitnessThreshold(evaldCortexes) {
-			succeeded = true
-			trainedPopulation = evaldCortexes
+		var evaluated []EvaluatedCortex
+		evaluated, evaldCortexes, stopReason, err = pt.runGeneration(ctx, evaldCortexes, i, scape, recorder)
+		if evaluated != nil {
+			trainedPopulation = evaluated
+		}
+		if stopReason != 0 || err != nil {
 			return
 		}
 
-		evaldCortexes = pt.cullPopulation(evaldCortexes)
+		// a failed checkpoint is not worth abandoning the run over
+		if pt.shouldCheckpoint(i + 1) {
+			if checkpointErr := pt.saveCheckpoint(i+1, evaldCortexes); checkpointErr != nil {
+				logg.LogTo("NEURVOLVE", "Unable to save checkpoint: %v", checkpointErr)
+			}
+		}
+	}
 
-		evaldCortexes = pt.generateOffspring(evaldCortexes)
+	stopReason = StopReasonBudgetExhausted
+	return
 
-		recorder.AddGeneration(evaldCortexes)
+}
 
-		trainedPopulation = evaldCortexes
+// Evaluate the population for the given generation and, unless training
+// should stop, breed the next generation from it.  evaluated is the
+// population with its fitness scores, fittest first, or nil if ctx was
+// done before the evaluation finished.  A non-zero stopReason means
+// training should stop.
+func (pt *PopulationTrainer) runGeneration(ctx context.Context, population []EvaluatedCortex, generation int, scape Scape, recorder Recorder) (evaluated []EvaluatedCortex, nextGeneration []EvaluatedCortex, stopReason StopReason, err error) {
+
+	pt.CurrentGeneration = generation
+	pt.publishSnapshot(population)
+
+	evaluated, err = pt.computeFitnessContext(ctx, population, scape, recorder)
+	if err != nil {
+		evaluated = nil
+		if stopReason = contextStopReason(ctx); stopReason != 0 {
+			logg.LogTo("NEURVOLVE", "Stopping in generation %v: %v", generation, stopReason)
+			err = nil
+		}
+		return
 	}
 
+	pt.creditMutations(evaluated, generation, recorder)
+
+	if pt.HallOfFame != nil {
+		pt.HallOfFame.Add(evaluated)
+	}
+
+	if pt.MultiObjective {
+		pt.paretoFront = EvaluatedCortexes(evaluated).ParetoFront()
+		if paretoRecorder, ok := recorder.(ParetoFrontRecorder); ok {
+			paretoRecorder.AddParetoFront(generation, pt.paretoFront)
+		}
+	}
+
+	if pt.exceededFitnessThreshold(evaluated) {
+		stopReason = StopReasonThresholdReached
+		return
+	}
+
+	if pt.detectStagnation(evaluated, generation, recorder) {
+		logg.LogTo("NEURVOLVE", "Stopping in generation %v: %v", generation, StopReasonStagnated)
+		stopReason = StopReasonStagnated
+		return
+	}
+
+	if nextGeneration, err = pt.breedNextGeneration(evaluated, generation, recorder); err != nil {
+		return
+	}
+	pt.retainRatings(nextGeneration)
 	return
 
 }
 
+// Choose the parents from the evaluated population and breed the next
+// generation from them
+func (pt *PopulationTrainer) breedNextGeneration(evaluated []EvaluatedCortex, generation int, recorder Recorder) (nextGeneration []EvaluatedCortex, err error) {
+
+	nextGeneration = evaluated
+	if pt.NoveltySearch != nil {
+		nextGeneration = pt.NoveltySearch.score(nextGeneration, generation, recorder)
+	}
+
+	if pt.Speciation != nil {
+		nextGeneration, err = pt.breedSpecies(nextGeneration)
+	} else {
+		nextGeneration = pt.cullPopulation(nextGeneration)
+		nextGeneration, err = pt.generateOffspring(nextGeneration)
+	}
+	if err != nil {
+		return
+	}
+
+	if nextGeneration, err = pt.reseed(nextGeneration); err != nil {
+		return
+	}
+
+	recorder.AddGeneration(nextGeneration)
+	if !pt.holdLineage {
+		pt.recordLineage(nextGeneration, recorder)
+	}
+
+	return
+
+}
+
+// The source of all of the trainer's random choices, which is Rand if it
+// is set
+func (pt *PopulationTrainer) randomSource() *rand.Rand {
+	if pt.random == nil {
+		pt.seedRandom(randomOrNew(pt.Rand).Int63(), 0)
+	}
+	return pt.random
+}
+
+// Draw the trainer's random choices from a new source with the given
+// seed, advanced past the given number of draws.  The source counts its
+// draws, so that a checkpoint can record how far the run has got.
+func (pt *PopulationTrainer) seedRandom(seed int64, draws int64) {
+	pt.source = newCountingSource(seed, draws)
+	pt.random = rand.New(pt.source)
+}
+
+// A copy of the state of a PopulationTrainer at the start of a generation
+type PopulationSnapshot struct {
+	Generation int
+	Population EvaluatedCortexes
+	HallOfFame EvaluatedCortexes
+
+	// The Pareto front of the last generation that was evaluated, ranked
+	// against the rest of that generation.  Empty unless MultiObjective.
+	ParetoFront EvaluatedCortexes
+}
+
 func (pt *PopulationTrainer) publishSnapshot(evaldPopulation EvaluatedCortexes) {
 	select {
 	case responseChan := <-pt.SnapshotRequestChan:
-		evaldPopulationCopy := make(EvaluatedCortexes, 0)
-		for _, evaldCortex := range evaldPopulation {
-			evaldCortexCopy := EvaluatedCortex{
-				Fitness: evaldCortex.Fitness,
-				Cortex:  evaldCortex.Cortex.Copy(),
-			}
-			evaldPopulationCopy = append(evaldPopulationCopy, evaldCortexCopy)
+		responseChan <- PopulationSnapshot{
+			Generation:  pt.CurrentGeneration,
+			Population:  copyEvaluatedCortexes(evaldPopulation),
+			HallOfFame:  pt.HallOfFameMembers(),
+			ParetoFront: copyEvaluatedCortexes(pt.paretoFront),
 		}
-		responseChan <- evaldPopulationCopy
 	default:
 	}
 }
 
-func (pt *PopulationTrainer) GetPopulationSnapshot() EvaluatedCortexes {
-	responseChan := make(chan EvaluatedCortexes)
+func copyEvaluatedCortexes(evaldCortexes EvaluatedCortexes) EvaluatedCortexes {
+	evaldCortexesCopy := make(EvaluatedCortexes, 0)
+	for _, evaldCortex := range evaldCortexes {
+		evaldCortexCopy := evaldCortex
+		evaldCortexCopy.Cortex = evaldCortex.Cortex.Copy()
+		evaldCortexCopy.Objectives = append([]float64{}, evaldCortex.Objectives...)
+		evaldCortexesCopy = append(evaldCortexesCopy, evaldCortexCopy)
+	}
+	return evaldCortexesCopy
+}
+
+// Wait for the start of the next generation and return a copy of the
+// population and the hall of fame.  The trainer must have a
+// SnapshotRequestChan.
+func (pt *PopulationTrainer) GetSnapshot() PopulationSnapshot {
+	responseChan := make(chan PopulationSnapshot)
 	pt.SnapshotRequestChan <- responseChan
 	return <-responseChan
 }
 
+func (pt *PopulationTrainer) GetPopulationSnapshot() EvaluatedCortexes {
+	return pt.GetSnapshot().Population
+}
+
+// A copy of the members of the hall of fame, fittest first, or an empty
+// list if the trainer has no HallOfFame.  After training, this goes with
+// the trained population as the result of the run.  ResumeFromCheckpoint
+// replaces the HallOfFame with the one saved in the checkpoint, so this
+// is the way to get at it.
+func (pt *PopulationTrainer) HallOfFameMembers() EvaluatedCortexes {
+	if pt.HallOfFame == nil {
+		return EvaluatedCortexes{}
+	}
+	return pt.HallOfFame.Members()
+}
+
 func (pt *PopulationTrainer) addEmptyFitnessScores(population []*ng.Cortex) (evaldPopulation []EvaluatedCortex) {
 
 	evaldPopulation = make([]EvaluatedCortex, 0)
@@ -81,6 +380,7 @@ func (pt *PopulationTrainer) addEmptyFitnessScores(population []*ng.Cortex) (eva
 			Cortex:   cortex,
 			ParentId: cortex.NodeId.UUID, // no parent, set to self
 			Fitness:  0.0,
+			StepSize: pt.initialStepSize(),
 		}
 		evaldPopulation = append(evaldPopulation, evaldCortex)
 
@@ -90,66 +390,196 @@ func (pt *PopulationTrainer) addEmptyFitnessScores(population []*ng.Cortex) (eva
 }
 
 func (pt *PopulationTrainer) computeFitness(population []EvaluatedCortex, scape Scape, recorder Recorder) (evaldCortexes []EvaluatedCortex) {
+	evaldCortexes, _ = pt.computeFitnessContext(context.Background(), population, scape, recorder)
+	return
+}
 
-	evaldCortexes = make([]EvaluatedCortex, len(population))
-	for i, evaldCortex := range population {
-		cortex := evaldCortex.Cortex
+// Compute the fitness of each cortex in the population and sort the
+// population by fitness.  If ctx is done before all of the fitness
+// scores are computed, ctx.Err() is returned.
+func (pt *PopulationTrainer) computeFitnessContext(ctx context.Context, population []EvaluatedCortex, scape Scape, recorder Recorder) (evaldCortexes []EvaluatedCortex, err error) {
 
-		averageFitness := 0.0
+	if pt.MatchScheduler != nil {
+		return pt.computeScheduledFitness(ctx, population, scape, recorder)
+	}
+
+	// choose all opponents up front, in order, so that the random
+	// choices do not depend on how many workers are running
+	jobs := make([]*fitnessJob, len(population))
+	for i, evaldCortex := range population {
+		job := &fitnessJob{
+			cortex:          evaldCortex.Cortex,
+			multiObjective:  pt.MultiObjective,
+			measureBehavior: pt.NoveltySearch != nil,
+		}
+		if pt.EvaluationPolicy != nil {
+			job.policy = *pt.EvaluationPolicy
+		}
 		if pt.NumOpponents > 0 {
+			job.opponents, err = pt.chooseRandomOpponents(job.cortex, population, pt.NumOpponents)
+			if err != nil {
+				return
+			}
+		}
+		if pt.NumOpponents > 0 && pt.HallOfFame != nil && pt.NumHallOfFameOpponents > 0 {
+			hallOfFameOpponents := pt.HallOfFame.chooseRandomOpponents(pt.randomSource(), job.cortex, pt.NumHallOfFameOpponents)
+			job.opponents = append(job.opponents, hallOfFameOpponents...)
+		}
+		jobs[i] = job
+	}
 
-			opponents := pt.chooseRandomOpponents(cortex, population, pt.NumOpponents)
+	if pt.usesFitnessCache(scape) {
+		pending, hashes, duplicates := pt.FitnessCache.lookup(jobs)
+		err = pt.runFitnessJobs(ctx, pending, scape)
+		pt.FitnessCache.store(pending, hashes, duplicates)
+		if err != nil {
+			return
+		}
+	} else if err = pt.runFitnessJobs(ctx, jobs, scape); err != nil {
+		return
+	}
 
-			fitnessScores := make([]float64, len(opponents))
-			for j, opponent := range opponents {
-				score := scape.FitnessAgainst(cortex, opponent)
-				fitnessScores[j] = score
-				recorder.AddFitnessScore(score, cortex, opponent)
-			}
-			averageFitness = ng.Average(fitnessScores)
+	evaldCortexes = make([]EvaluatedCortex, len(population))
+	for i, evaldCortex := range population {
+		job := jobs[i]
 
+		averageFitness := 0.0
+		if len(job.opponents) > 0 {
+			for j, opponent := range job.opponents {
+				pt.addFitnessScore(recorder, job.scores[j], job.cortex, opponent)
+			}
+			averageFitness = ng.Average(job.scores)
 		} else {
-			averageFitness = scape.Fitness(cortex)
+			averageFitness = job.scores[0]
 		}
 
 		evaldCortexUpdated := EvaluatedCortex{
-			Cortex:   cortex,
-			ParentId: evaldCortex.ParentId,
-			Fitness:  averageFitness,
+			Cortex:     evaldCortex.Cortex,
+			ParentId:   evaldCortex.ParentId,
+			Fitness:    averageFitness,
+			Objectives: job.objectives,
+			Behavior:   job.behavior,
+			StepSize:   evaldCortex.StepSize,
 		}
 		evaldCortexes[i] = evaldCortexUpdated
 
 	}
 
+	pt.applyRatings(evaldCortexes)
 	evaldCortexes = pt.sortByFitness(evaldCortexes)
 
 	return
 }
 
-func (pt *PopulationTrainer) chooseRandomOpponents(cortex *ng.Cortex, population []EvaluatedCortex, numOpponents int) (opponents []*ng.Cortex) {
+// The cache is only used when each cortex is evaluated on its own by a
+// deterministic scape
+func (pt *PopulationTrainer) usesFitnessCache(scape Scape) bool {
+	return pt.FitnessCache != nil && pt.NumOpponents == 0 && isDeterministic(scape)
+}
+
+// Compute the fitness of each cortex by playing the matches chosen by
+// pt.MatchScheduler, and sort the population by fitness
+func (pt *PopulationTrainer) computeScheduledFitness(ctx context.Context, population []EvaluatedCortex, scape Scape, recorder Recorder) (evaldCortexes []EvaluatedCortex, err error) {
+
+	fitness, err := pt.computeTournamentFitness(ctx, population, scape, recorder)
+	if err != nil {
+		return
+	}
+
+	evaldCortexes = make([]EvaluatedCortex, len(population))
+	for i, evaldCortex := range population {
+		evaldCortexes[i] = EvaluatedCortex{
+			Cortex:   evaldCortex.Cortex,
+			ParentId: evaldCortex.ParentId,
+			Fitness:  fitness[i],
+			StepSize: evaldCortex.StepSize,
+		}
+	}
+
+	pt.applyRatings(evaldCortexes)
+	evaldCortexes = pt.sortByFitness(evaldCortexes)
+
+	return
+}
+
+// Pass the score on to the recorder, and to the ratings if there are any
+func (pt *PopulationTrainer) addFitnessScore(recorder Recorder, score float64, cortex *ng.Cortex, opponent *ng.Cortex) {
+	if pt.Ratings != nil {
+		pt.Ratings.AddResult(cortex, opponent, score)
+	}
+	recorder.AddFitnessScore(score, cortex, opponent)
+}
+
+// Once all the scores for a generation are in, end the rating period and
+// copy each cortex's rating into the population
+func (pt *PopulationTrainer) applyRatings(evaldCortexes []EvaluatedCortex) {
+
+	if pt.Ratings == nil {
+		return
+	}
+
+	pt.Ratings.EndRatingPeriod()
+	for i, evaldCortex := range evaldCortexes {
+		evaldCortexes[i].Rating = pt.Ratings.Rating(evaldCortex.Cortex.NodeId.UUID).Rating
+		if pt.RatingAsFitness {
+			evaldCortexes[i].Fitness = evaldCortexes[i].Rating
+		}
+	}
+
+}
+
+// Forget the ratings of the cortexes which are neither in the next
+// generation nor in the hall of fame, since they will never play again
+func (pt *PopulationTrainer) retainRatings(nextGeneration []EvaluatedCortex) {
+
+	if pt.Ratings == nil {
+		return
+	}
+
+	uuids := make(map[string]bool)
+	for _, evaldCortex := range nextGeneration {
+		uuids[evaldCortex.Cortex.NodeId.UUID] = true
+	}
+	if pt.HallOfFame != nil {
+		for _, uuid := range pt.HallOfFame.uuids() {
+			uuids[uuid] = true
+		}
+	}
+	pt.Ratings.Retain(uuids)
+
+}
+
+func (pt *PopulationTrainer) chooseRandomOpponents(cortex *ng.Cortex, population []EvaluatedCortex, numOpponents int) (opponents []*ng.Cortex, err error) {
 
 	if numOpponents >= len(population) {
-		logg.LogPanic("Not enough members of population to choose %d opponents", numOpponents)
+		err = fmt.Errorf("%w: need %d opponents, population size is %d", ErrInsufficientOpponents, numOpponents, len(population))
+		return
 	}
 
+	// no opponent is chosen twice
 	opponents = make([]*ng.Cortex, 0)
-	for i := 0; i < numOpponents; i++ {
-		for {
-			randInt := RandomIntInRange(0, len(population))
-			randomEvaluatedCortex := population[randInt]
-			if randomEvaluatedCortex.Cortex == cortex {
-				continue
-			}
-			opponents = append(opponents, randomEvaluatedCortex.Cortex)
+	for _, randInt := range pt.randomSource().Perm(len(population)) {
+		if len(opponents) == numOpponents {
 			break
 		}
-
+		randomEvaluatedCortex := population[randInt]
+		if randomEvaluatedCortex.Cortex == cortex {
+			continue
+		}
+		opponents = append(opponents, randomEvaluatedCortex.Cortex)
 	}
 	return
 
 }
 
+// Sort the population fittest first, or by Pareto rank when MultiObjective
+// is set
 func (pt *PopulationTrainer) sortByFitness(population EvaluatedCortexes) (sortedPopulation []EvaluatedCortex) {
+	if pt.MultiObjective {
+		sortByParetoRank(population)
+		sortedPopulation = population
+		return
+	}
 	sort.Sort(population)
 	sortedPopulation = population
 	return
@@ -164,53 +594,141 @@ func (pt *PopulationTrainer) exceededFitnessThreshold(evaldCortexes []EvaluatedC
 	return false
 }
 
-func (pt *PopulationTrainer) cullPopulation(population []EvaluatedCortex) (culledPopulation []EvaluatedCortex) {
+// Choose the parents of the next generation using pt.Selector.  The
+// parents are carried over into the next generation unchanged, along
+// with their offspring.
+func (pt *PopulationTrainer) cullPopulation(population []EvaluatedCortex) (parents []EvaluatedCortex) {
 
 	population = pt.sortByFitness(population)
 
-	if len(population)%2 != 0 {
-		logg.LogPanic("population size must be even")
+	numParents := pt.numParents(len(population))
+	parents = pt.selector().SelectParents(pt.randomSource(), population, numParents)
+
+	parents = pt.addElites(population, parents)
+
+	return
+}
+
+// Make sure the top NumElite members of the sorted population are among
+// the parents, so that they survive into the next generation.
+func (pt *PopulationTrainer) addElites(population []EvaluatedCortex, parents []EvaluatedCortex) []EvaluatedCortex {
+
+	numElite := pt.NumElite
+	if numElite > len(population) {
+		numElite = len(population)
 	}
 
-	culledPopulationSize := len(population) / 2
-	culledPopulation = make([]EvaluatedCortex, 0)
+	isParent := make(map[*ng.Cortex]bool)
+	for _, parent := range parents {
+		isParent[parent.Cortex] = true
+	}
 
-	for i, evaldCortex := range population {
-		culledPopulation = append(culledPopulation, evaldCortex)
-		if i >= (culledPopulationSize - 1) {
-			break
+	elites := make([]EvaluatedCortex, 0)
+	for _, evaldCortex := range population[:numElite] {
+		if !isParent[evaldCortex.Cortex] {
+			elites = append(elites, evaldCortex)
 		}
 	}
 
+	return append(elites, parents...)
+}
+
+// The number of parents needed so that the parents plus
+// OffspringPerParent children each add up to the population size.
+func (pt *PopulationTrainer) numParents(populationSize int) int {
+	numParents := int(math.Ceil(float64(populationSize) / float64(1+pt.offspringPerParent())))
+	if numParents < 1 {
+		numParents = 1
+	}
+	return numParents
+}
+
+func (pt *PopulationTrainer) selector() Selector {
+	if pt.Selector == nil {
+		return TruncationSelector{Ratio: 0.5}
+	}
+	return pt.Selector
+}
+
+func (pt *PopulationTrainer) offspringPerParent() int {
+	if pt.OffspringPerParent <= 0 {
+		return 1
+	}
+	return pt.OffspringPerParent
+}
+
+// Carry the parents over into the next generation and add their offspring.
+// Parents are bred in turn until the population is back to its original
+// size, so a parent chosen more than once has more than one offspring.
+// If the parents alone fill the population, the elites come first and
+// there are no offspring.  Outside of Train, where the population size is
+// not known, each parent has OffspringPerParent offspring.
+func (pt *PopulationTrainer) generateOffspring(parents []EvaluatedCortex) (withOffspring []EvaluatedCortex, err error) {
+
+	withOffspring = uniqueEvaluatedCortexes(parents)
+
+	numOffspring := len(parents) * pt.offspringPerParent()
+	if pt.populationSize > 0 {
+		if len(withOffspring) > pt.populationSize {
+			withOffspring = withOffspring[:pt.populationSize]
+		}
+		numOffspring = pt.populationSize - len(withOffspring)
+	}
+
+	offspring, err := pt.breed(parents, numOffspring)
+	if err != nil {
+		return
+	}
+	withOffspring = append(withOffspring, offspring...)
+
 	return
+
 }
 
-func (pt *PopulationTrainer) generateOffspring(population []EvaluatedCortex) (withOffspring []EvaluatedCortex) {
+// Make numOffspring mutated offspring of the parents, cycling through them
+func (pt *PopulationTrainer) breed(parents []EvaluatedCortex, numOffspring int) (offspring []EvaluatedCortex, err error) {
 
-	withOffspring = make([]EvaluatedCortex, 0)
-	withOffspring = append(withOffspring, population...)
+	offspring = make([]EvaluatedCortex, 0)
 
-	for _, evaldCortex := range population {
+	for i := 0; i < numOffspring; i++ {
 
-		cortex := evaldCortex.Cortex
-		offspringCortex := cortex.Copy()
+		// after crossover, the offspring is a copy of the fitter of the
+		// parent and its mate, so that is the parent it is credited to
+		parent := parents[i%len(parents)]
+		offspringCortex, parent, mateId := pt.crossoverOrCopy(parent, parents)
 
-		offspringNodeIdStr := fmt.Sprintf("cortex-%s", ng.NewUuid())
+		offspringNodeIdStr := fmt.Sprintf("cortex-%s", newUuid(pt.randomSource()))
 		offspringCortex.NodeId = ng.NewCortexId(offspringNodeIdStr)
 
-		succeeded, _ := pt.CortexMutator(offspringCortex)
-		if !succeeded {
-			logg.LogPanic("Unable to mutate cortex: %v", offspringCortex)
+		var operators []int
+		var mutations []*MutationRecord
+		if operators, mutations, err = pt.mutate(offspringCortex); err != nil {
+			return
+		}
+		pt.expectMutationCredit(offspringCortex, pt.rawFitness(parent), operators)
+
+		stepSize := parent.StepSize
+		if pt.SelfAdaptation != nil {
+			stepSize = pt.SelfAdaptation.adapt(pt.randomSource(), parent.StepSize, offspringCortex)
+			var record *MutationRecord
+			if record, err = pt.SelfAdaptation.mutate(pt.randomSource(), offspringCortex, stepSize); err != nil {
+				return
+			}
+			if record != nil {
+				mutations = append(mutations, record)
+			}
 		}
 
 		evaldCortexOffspring := EvaluatedCortex{
 			Cortex:              offspringCortex,
-			ParentId:            cortex.NodeId.UUID,
+			ParentId:            parent.Cortex.NodeId.UUID,
 			CreatedInGeneration: pt.CurrentGeneration,
 			Fitness:             0.0,
+			StepSize:            stepSize,
 		}
 
-		withOffspring = append(withOffspring, evaldCortexOffspring)
+		offspring = append(offspring, evaldCortexOffspring)
+		pt.expectLineage(evaldCortexOffspring, mateId, mutations)
 
 	}
 
@@ -218,6 +736,171 @@ func (pt *PopulationTrainer) generateOffspring(population []EvaluatedCortex) (wi
 
 }
 
+// Cross the parent over with another of the parents, if the trainer is
+// configured to, or otherwise copy it.  Parents which can't be crossed
+// over are copied instead.  Returns the child, the parent it was copied
+// from, which is the fitter of the two when crossed over, and the id of
+// the other parent, or an empty string if there was no crossover.
+func (pt *PopulationTrainer) crossoverOrCopy(parent EvaluatedCortex, parents []EvaluatedCortex) (child *ng.Cortex, copiedFrom EvaluatedCortex, mateId string) {
+
+	random := pt.randomSource()
+	if pt.Crossover == nil || random.Float64() >= pt.CrossoverProbability {
+		return parent.Cortex.Copy(), parent, ""
+	}
+
+	mates := make([]EvaluatedCortex, 0)
+	for _, candidate := range uniqueEvaluatedCortexes(parents) {
+		if candidate.Cortex != parent.Cortex {
+			mates = append(mates, candidate)
+		}
+	}
+	if len(mates) == 0 {
+		return parent.Cortex.Copy(), parent, ""
+	}
+	mate := mates[RandomIntInRange(random, 0, len(mates))]
+
+	// the fitter parent goes first
+	fitter, other := parent, mate
+	if mate.Fitness > parent.Fitness {
+		fitter, other = mate, parent
+	}
+
+	child, err := pt.Crossover(random, fitter.Cortex, other.Cortex)
+	if err != nil {
+		logg.LogTo("NEURVOLVE", "Crossover failed, copying parent instead: %v", err)
+		return parent.Cortex.Copy(), parent, ""
+	}
+	return child, fitter, other.Cortex.NodeId.UUID
+
+}
+
+// Divide the population into species and breed each species separately.
+// A species keeps its fittest parents and fills the rest of its quota of
+// the next generation with their offspring.
+func (pt *PopulationTrainer) breedSpecies(population []EvaluatedCortex) (nextGeneration []EvaluatedCortex, err error) {
+
+	population = pt.sortByFitness(population)
+
+	populationSize := pt.populationSize
+	if populationSize <= 0 {
+		populationSize = len(population)
+	}
+
+	pt.Speciation.speciate(population, pt.CurrentGeneration)
+	quotas := pt.Speciation.offspringQuotas(populationSize)
+
+	nextGeneration = make([]EvaluatedCortex, 0)
+	for i, species := range pt.Speciation.Species() {
+
+		quota := quotas[i]
+		if quota == 0 {
+			continue
+		}
+
+		parents := pt.selector().SelectParents(pt.randomSource(), species.Members, pt.numParents(quota))
+		parents = pt.addElites(species.Members, parents)
+
+		survivors := uniqueEvaluatedCortexes(parents)
+		if len(survivors) > quota {
+			survivors = survivors[:quota]
+		}
+
+		var offspring []EvaluatedCortex
+		offspring, err = pt.breed(parents, quota-len(survivors))
+		if err != nil {
+			return
+		}
+
+		nextGeneration = append(nextGeneration, survivors...)
+		nextGeneration = append(nextGeneration, offspring...)
+	}
+
+	return
+
+}
+
+// Remove any cortexes that appear more than once, keeping the first
+func uniqueEvaluatedCortexes(population []EvaluatedCortex) (unique []EvaluatedCortex) {
+	unique = make([]EvaluatedCortex, 0)
+	seen := make(map[*ng.Cortex]bool)
+	for _, evaldCortex := range population {
+		if seen[evaldCortex.Cortex] {
+			continue
+		}
+		seen[evaldCortex.Cortex] = true
+		unique = append(unique, evaldCortex)
+	}
+	return
+}
+
+// Check the configuration before training a population of the given size
+func (pt *PopulationTrainer) validate(populationSize int, scape Scape) error {
+
+	if populationSize < 1 {
+		return fmt.Errorf("%w: population is empty", ErrPopulationSize)
+	}
+	if pt.NumOpponents >= populationSize {
+		return fmt.Errorf("%w: need %d opponents, population size is %d", ErrInsufficientOpponents, pt.NumOpponents, populationSize)
+	}
+	if pt.NumElite < 0 || pt.NumElite >= populationSize {
+		return fmt.Errorf("%w: NumElite must be at least 0 and less than the population size of %d", ErrInvalidConfig, populationSize)
+	}
+	if pt.NumOpponents > 0 && !supportsFitnessAgainst(scape) {
+		return ErrFitnessAgainstUnsupported
+	}
+	if pt.CortexMutator == nil && pt.MutationPolicy == nil {
+		return fmt.Errorf("%w: no CortexMutator", ErrInvalidConfig)
+	}
+	if pt.MutationPolicy != nil {
+		if err := pt.MutationPolicy.validate(); err != nil {
+			return err
+		}
+	}
+	if pt.MultiObjective {
+		if _, ok := scape.(MultiObjectiveScape); !ok {
+			return fmt.Errorf("%w: MultiObjective needs a MultiObjectiveScape", ErrInvalidConfig)
+		}
+		if pt.NumOpponents > 0 {
+			return fmt.Errorf("%w: MultiObjective cannot be used with opponents", ErrInvalidConfig)
+		}
+	}
+	if pt.NoveltySearch != nil {
+		if _, ok := scape.(BehaviorScape); !ok {
+			return fmt.Errorf("%w: NoveltySearch needs a BehaviorScape", ErrInvalidConfig)
+		}
+		if pt.MultiObjective {
+			return fmt.Errorf("%w: NoveltySearch cannot be combined with MultiObjective", ErrInvalidConfig)
+		}
+	}
+	if pt.MatchScheduler != nil {
+		if pt.NumOpponents > 0 || pt.MultiObjective || pt.NoveltySearch != nil {
+			return fmt.Errorf("%w: MatchScheduler cannot be combined with NumOpponents, MultiObjective or NoveltySearch", ErrInvalidConfig)
+		}
+		if !supportsFitnessAgainst(scape) {
+			return ErrFitnessAgainstUnsupported
+		}
+	}
+	if pt.Ratings != nil && pt.NumOpponents == 0 && pt.MatchScheduler == nil {
+		return fmt.Errorf("%w: Ratings need NumOpponents or a MatchScheduler", ErrInvalidConfig)
+	}
+	if pt.HallOfFame != nil && pt.HallOfFame.MaxSize < 0 {
+		return fmt.Errorf("%w: HallOfFame MaxSize cannot be negative", ErrInvalidConfig)
+	}
+	if selector, ok := pt.Selector.(TruncationSelector); ok && (selector.Ratio < 0 || selector.Ratio > 1) {
+		return fmt.Errorf("%w: TruncationSelector Ratio must be between 0 and 1", ErrInvalidConfig)
+	}
+	if pt.CrossoverProbability < 0 || pt.CrossoverProbability > 1 {
+		return fmt.Errorf("%w: CrossoverProbability must be between 0 and 1", ErrInvalidConfig)
+	}
+	if pt.EvaluationPolicy != nil {
+		if err := pt.EvaluationPolicy.validate(); err != nil {
+			return err
+		}
+	}
+	return pt.validateStagnationCriteria()
+
+}
+
 func (pt *PopulationTrainer) dumpPopulationToLog(population []EvaluatedCortex) {
 
 	for _, evaluatedCortex := range population {
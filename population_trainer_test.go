@@ -1,10 +1,17 @@
 package neurvolve
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/couchbaselabs/go.assert"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/tleyden/neurgo"
+	"math/rand"
+	"sync"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -22,11 +29,11 @@ func init() {
 
 func TestTrain(t *testing.T) {
 
-	fakeCortexMutator := func(cortex *ng.Cortex) (success bool, result MutateResult) {
+	fakeCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
 		for _, neuron := range cortex.Neurons {
 			neuron.Bias += 1
 		}
-		result = "nothing"
+		result = newMutationRecord("fake")
 		success = true
 		return
 	}
@@ -53,7 +60,8 @@ func TestTrain(t *testing.T) {
 		examples: examples,
 	}
 	recorder := NewNullRecorder()
-	trainedPopulation, succeeded := pt.Train(population, scape, recorder)
+	trainedPopulation, succeeded, err := pt.Train(population, scape, recorder)
+	assert.True(t, err == nil)
 	logg.LogTo("TEST", "succeeded: %v", succeeded)
 	logg.LogTo("TEST", "trainedPopulation: %v", trainedPopulation)
 
@@ -90,10 +98,14 @@ func TestChooseRandomOpponents(t *testing.T) {
 
 	population := []EvaluatedCortex{evaldCortex, fitOpponent}
 
-	opponents := pt.chooseRandomOpponents(cortex, population, 1)
+	opponents, err := pt.chooseRandomOpponents(cortex, population, 1)
+	assert.True(t, err == nil)
 	assert.Equals(t, len(opponents), 1)
 	assert.Equals(t, opponents[0], opponent)
 
+	_, err = pt.chooseRandomOpponents(cortex, population, 2)
+	assert.True(t, errors.Is(err, ErrInsufficientOpponents))
+
 }
 
 func TestSortByFitness(t *testing.T) {
@@ -123,11 +135,53 @@ func TestCullPopulation(t *testing.T) {
 
 }
 
+func TestCullPopulationOddSize(t *testing.T) {
+	population := fitnessPopulation(1, 5, 3, 4, 2)
+
+	pt := &PopulationTrainer{}
+	culledPopulation := pt.cullPopulation(population)
+	assert.Equals(t, len(culledPopulation), 3)
+	assert.Equals(t, culledPopulation[0].Fitness, 5.0)
+	assert.Equals(t, culledPopulation[1].Fitness, 4.0)
+	assert.Equals(t, culledPopulation[2].Fitness, 3.0)
+}
+
+func TestGenerateOffspringRefillsPopulation(t *testing.T) {
+
+	fakeCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+		result = newMutationRecord("fake")
+		success = true
+		return
+	}
+
+	pt := &PopulationTrainer{
+		CortexMutator:      fakeCortexMutator,
+		OffspringPerParent: 2,
+		populationSize:     7,
+	}
+
+	cortex1 := BasicCortex()
+	cortex2 := BasicCortex()
+
+	// cortex1 was chosen twice, so it should only be carried over once
+	parents := []EvaluatedCortex{
+		{Fitness: 100.0, Cortex: cortex1},
+		{Fitness: 100.0, Cortex: cortex1},
+		{Fitness: -100.0, Cortex: cortex2},
+	}
+	withOffspring, err := pt.generateOffspring(parents)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(withOffspring), 7)
+	assert.Equals(t, withOffspring[0].Cortex, cortex1)
+	assert.Equals(t, withOffspring[1].Cortex, cortex2)
+
+}
+
 func TestGenerateOffspring(t *testing.T) {
 
-	fakeCortexMutator := func(cortex *ng.Cortex) (success bool, result MutateResult) {
+	fakeCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
 		cortex.SetSensors(make([]*ng.Sensor, 0))
-		result = "nothing"
+		result = newMutationRecord("fake")
 		success = true
 		return
 	}
@@ -143,7 +197,8 @@ func TestGenerateOffspring(t *testing.T) {
 	evaldCortex2 := EvaluatedCortex{Fitness: -100.0, Cortex: cortex2}
 
 	population := []EvaluatedCortex{evaldCortex1, evaldCortex2}
-	offspringPopulation := pt.generateOffspring(population)
+	offspringPopulation, err := pt.generateOffspring(population)
+	assert.True(t, err == nil)
 	assert.Equals(t, len(offspringPopulation), 2*len(population))
 
 	offspringEvaluatedCortex := offspringPopulation[3]
@@ -155,11 +210,11 @@ func TestGenerateOffspring(t *testing.T) {
 // Regression test for issue in which evaldcortexes didn't have correct parents
 func TestRetainParent(t *testing.T) {
 
-	fakeCortexMutator := func(cortex *ng.Cortex) (success bool, result MutateResult) {
+	fakeCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
 		for _, neuron := range cortex.Neurons {
 			neuron.Bias += 1
 		}
-		result = "nothing"
+		result = newMutationRecord("fake")
 		success = true
 		return
 	}
@@ -183,7 +238,8 @@ func TestRetainParent(t *testing.T) {
 
 	scape := FakeScapeTwoPlayer{}
 
-	population = pt.generateOffspring(population)
+	population, err := pt.generateOffspring(population)
+	assert.True(t, err == nil)
 
 	// all evaldcortexes should have parentid == "cortex1"
 	for _, evaldCortex := range population {
@@ -199,3 +255,285 @@ func TestRetainParent(t *testing.T) {
 	}
 
 }
+
+type FakeScapeBiasSum struct{}
+
+func (scape FakeScapeBiasSum) Fitness(cortex *ng.Cortex) float64 {
+	fitness := 0.0
+	for _, neuron := range cortex.Neurons {
+		fitness += neuron.Bias
+	}
+	return fitness
+}
+
+func (scape FakeScapeBiasSum) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.Fitness(cortex) - scape.Fitness(opponent)
+}
+
+// A scape which notices when a cortex is run in two simulations at once
+type FakeExclusiveScape struct {
+	FakeScapeBiasSum
+	mutex    *sync.Mutex
+	running  map[*ng.Cortex]bool
+	shared   *bool
+	numCalls *int
+}
+
+func (scape FakeExclusiveScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+
+	scape.mutex.Lock()
+	for _, c := range []*ng.Cortex{cortex, opponent} {
+		if scape.running[c] {
+			*scape.shared = true
+		}
+		scape.running[c] = true
+	}
+	*scape.numCalls += 1
+	scape.mutex.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	scape.mutex.Lock()
+	delete(scape.running, cortex)
+	delete(scape.running, opponent)
+	scape.mutex.Unlock()
+
+	return scape.FakeScapeBiasSum.FitnessAgainst(cortex, opponent)
+}
+
+func TestComputeFitnessParallelOpponents(t *testing.T) {
+
+	cortexes := []*ng.Cortex{}
+	for i := 0; i < 20; i++ {
+		cortexes = append(cortexes, SingleNeuronCortex(fmt.Sprintf("cortex-%d", i)))
+	}
+
+	shared := false
+	numCalls := 0
+	scape := FakeExclusiveScape{
+		mutex:    &sync.Mutex{},
+		running:  make(map[*ng.Cortex]bool),
+		shared:   &shared,
+		numCalls: &numCalls,
+	}
+
+	// every cortex is an opponent in several jobs, which run at the same
+	// time, so each job has to use its own copies
+	pt := &PopulationTrainer{NumWorkers: 8, NumOpponents: 3}
+	population := pt.addEmptyFitnessScores(cortexes)
+	_, err := pt.computeFitnessContext(context.Background(), population, scape, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, numCalls, 20*3)
+	assert.False(t, shared)
+
+}
+
+func TestComputeFitnessParallel(t *testing.T) {
+
+	cortexes := []*ng.Cortex{}
+	for i := 0; i < 20; i++ {
+		cortex := SingleNeuronCortex(fmt.Sprintf("cortex-%d", i))
+		cortex.Neurons[0].Bias = float64(i % 7)
+		cortexes = append(cortexes, cortex)
+	}
+
+	scape := FakeScapeBiasSum{}
+	recorder := NullRecorder{}
+
+	serialTrainer := &PopulationTrainer{NumWorkers: 1}
+	population := serialTrainer.addEmptyFitnessScores(cortexes)
+	serialResult := serialTrainer.computeFitness(population, scape, recorder)
+
+	parallelTrainer := &PopulationTrainer{NumWorkers: 8}
+	population = parallelTrainer.addEmptyFitnessScores(cortexes)
+	parallelResult := parallelTrainer.computeFitness(population, scape, recorder)
+
+	assert.Equals(t, len(parallelResult), len(serialResult))
+	for i, evaldCortex := range serialResult {
+		assert.Equals(t, parallelResult[i].Cortex, evaldCortex.Cortex)
+		assert.Equals(t, parallelResult[i].Fitness, evaldCortex.Fitness)
+	}
+	assert.Equals(t, serialResult[0].Fitness, 6.0)
+
+}
+
+func TestTrainContextCancelled(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   1000000,
+		CortexMutator:    NoOpMutator,
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	trainedPopulation, stopReason, err := pt.TrainContext(ctx, population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonCancelled)
+	assert.Equals(t, len(trainedPopulation), 2)
+
+}
+
+func TestTrainBudgetExhausted(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	trainedPopulation, stopReason, err := pt.TrainContext(ctx, population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonBudgetExhausted)
+	assert.Equals(t, len(trainedPopulation), 2)
+
+}
+
+func TestTrainInvalidConfig(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   10,
+		CortexMutator:    NoOpMutator,
+		NumOpponents:     2,
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+	}
+	_, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrInsufficientOpponents))
+
+	pt.NumOpponents = 1
+	_, _, err = pt.Train(population, TrainingSampleScape{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrFitnessAgainstUnsupported))
+
+	_, _, err = pt.Train([]*ng.Cortex{}, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrPopulationSize))
+
+}
+
+func TestNumEliteFillsPopulation(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   1,
+		CortexMutator:    MutateWeights,
+		NumElite:         2,
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+	}
+	_, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+	// the elites and the other parents already fill the population, so
+	// there is no room for offspring
+	pt = &PopulationTrainer{
+		CortexMutator:  MutateWeights,
+		populationSize: 2,
+	}
+	parents := []EvaluatedCortex{
+		{Cortex: SingleNeuronCortex("cortex1"), Fitness: 3.0},
+		{Cortex: SingleNeuronCortex("cortex2"), Fitness: 2.0},
+		{Cortex: SingleNeuronCortex("cortex3"), Fitness: 1.0},
+	}
+	withOffspring, err := pt.generateOffspring(parents)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(withOffspring), 2)
+	assert.Equals(t, withOffspring[0].Cortex.NodeId.UUID, "cortex1")
+	assert.Equals(t, withOffspring[1].Cortex.NodeId.UUID, "cortex2")
+
+}
+
+func TestGenerateOffspringMutationFailed(t *testing.T) {
+
+	failingCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+		return false, nil
+	}
+	panickingCortexMutator := func(random *rand.Rand, cortex *ng.Cortex) (success bool, result MutateResult) {
+		panic("oops")
+	}
+
+	parents := []EvaluatedCortex{{Cortex: SingleNeuronCortex("cortex1")}}
+
+	pt := &PopulationTrainer{CortexMutator: failingCortexMutator}
+	_, err := pt.generateOffspring(parents)
+	assert.True(t, errors.Is(err, ErrMutationFailed))
+
+	pt = &PopulationTrainer{CortexMutator: panickingCortexMutator}
+	_, err = pt.generateOffspring(parents)
+	assert.True(t, errors.Is(err, ErrMutationFailed))
+
+}
+
+func trainWithSeed(t *testing.T, seed int64) []byte {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   10,
+		CortexMutator:    TopologyOrWeightMutator,
+		Rand:             rand.New(rand.NewSource(seed)),
+	}
+
+	population := []*ng.Cortex{
+		SingleNeuronCortex("cortex1"),
+		SingleNeuronCortex("cortex2"),
+		SingleNeuronCortex("cortex3"),
+		SingleNeuronCortex("cortex4"),
+	}
+
+	trainedPopulation, _, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	cortexes := []*ng.Cortex{}
+	for _, evaldCortex := range trainedPopulation {
+		cortexes = append(cortexes, evaldCortex.Cortex)
+	}
+	bytes, err := json.Marshal(cortexes)
+	assert.True(t, err == nil)
+	return bytes
+
+}
+
+func TestTrainReproducibleWithSeed(t *testing.T) {
+
+	first := trainWithSeed(t, 42)
+	second := trainWithSeed(t, 42)
+	assert.Equals(t, string(first), string(second))
+
+}
+
+func TestTrainConcurrentlyReproducibleWithSeed(t *testing.T) {
+
+	expected := trainWithSeed(t, 42)
+
+	// each trainer draws from its own Rand, so another run going on at
+	// the same time can't change the outcome
+	results := make(chan []byte)
+	go func() {
+		results <- trainWithSeed(t, 7)
+	}()
+	actual := trainWithSeed(t, 42)
+	<-results
+
+	assert.Equals(t, string(actual), string(expected))
+
+}
@@ -0,0 +1,346 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"sync"
+)
+
+// The rating of a single cortex.  Deviation and Volatility are only
+// used by Glicko-2.
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+	NumGames   int
+}
+
+// Keeps a rating for each cortex, by uuid, which takes the strength of
+// its opponents into account.  Ratings carry over from one generation to
+// the next, so cortexes from different generations can be compared.
+// A cortex that has not played yet has the initial rating.
+type RatingSystem interface {
+
+	// Update the ratings with the score the cortex got against the opponent.
+	// Only the rating of the cortex is updated, since the opponent reports
+	// its own scores.
+	AddResult(cortex *ng.Cortex, opponent *ng.Cortex, score float64)
+
+	// Called once all of the results for a generation have been added
+	EndRatingPeriod()
+
+	Rating(uuid string) Rating
+
+	// A copy of all the ratings, for saving in a checkpoint
+	Ratings() map[string]Rating
+
+	// Replace all the ratings, when resuming from a checkpoint
+	SetRatings(ratings map[string]Rating)
+
+	// Forget the rating of every cortex whose uuid is not in uuids.  The
+	// PopulationTrainer calls it each generation with the uuids of the
+	// cortexes which are still alive, so that the ratings don't grow
+	// without bound.
+	Retain(uuids map[string]bool)
+}
+
+// Converts a fitness score into a game result: 1 for a win, 0.5 for a
+// draw and 0 for a loss
+type ScoreOutcome func(score float64) float64
+
+// Positive scores are wins, negative scores are losses, and 0 is a draw
+func SignOutcome(score float64) float64 {
+	switch {
+	case score > 0:
+		return 1.0
+	case score < 0:
+		return 0.0
+	default:
+		return 0.5
+	}
+}
+
+// The ratings of all the cortexes, by uuid
+type ratingTable struct {
+	ratings map[string]Rating
+	mutex   sync.RWMutex
+}
+
+func (t *ratingTable) get(uuid string, initial Rating) Rating {
+	if rating, ok := t.ratings[uuid]; ok {
+		return rating
+	}
+	return initial
+}
+
+func (t *ratingTable) copyRatings() map[string]Rating {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	ratings := make(map[string]Rating)
+	for uuid, rating := range t.ratings {
+		ratings[uuid] = rating
+	}
+	return ratings
+}
+
+func (t *ratingTable) setRatings(ratings map[string]Rating) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ratings = make(map[string]Rating)
+	for uuid, rating := range ratings {
+		t.ratings[uuid] = rating
+	}
+}
+
+func (t *ratingTable) retain(uuids map[string]bool) {
+	for uuid := range t.ratings {
+		if !uuids[uuid] {
+			delete(t.ratings, uuid)
+		}
+	}
+}
+
+// The Elo rating system, updated after every result
+type EloRatings struct {
+	K             float64
+	InitialRating float64
+	Outcome       ScoreOutcome
+	ratingTable
+}
+
+func NewEloRatings() *EloRatings {
+	return &EloRatings{
+		K:             32,
+		InitialRating: 1500,
+		Outcome:       SignOutcome,
+		ratingTable:   ratingTable{ratings: make(map[string]Rating)},
+	}
+}
+
+func (e *EloRatings) AddResult(cortex *ng.Cortex, opponent *ng.Cortex, score float64) {
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	initial := Rating{Rating: e.InitialRating}
+	rating := e.get(cortex.NodeId.UUID, initial)
+	opponentRating := e.get(opponent.NodeId.UUID, initial)
+
+	expected := 1.0 / (1.0 + math.Pow(10, (opponentRating.Rating-rating.Rating)/400))
+	rating.Rating += e.K * (e.Outcome(score) - expected)
+	rating.NumGames += 1
+	e.ratings[cortex.NodeId.UUID] = rating
+
+}
+
+func (e *EloRatings) EndRatingPeriod() {
+}
+
+func (e *EloRatings) Rating(uuid string) Rating {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.get(uuid, Rating{Rating: e.InitialRating})
+}
+
+func (e *EloRatings) Ratings() map[string]Rating {
+	return e.copyRatings()
+}
+
+func (e *EloRatings) SetRatings(ratings map[string]Rating) {
+	e.setRatings(ratings)
+}
+
+func (e *EloRatings) Retain(uuids map[string]bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.retain(uuids)
+}
+
+// The Glicko-2 rating system.  Results are collected over a rating
+// period, which is one generation, and all ratings are updated together
+// at the end of it.  The deviation of a cortex that did not play grows,
+// to reflect that its rating is less certain.
+type Glicko2Ratings struct {
+	InitialRating     float64
+	InitialDeviation  float64
+	InitialVolatility float64
+
+	// Constrains how much the volatility can change in a rating period
+	Tau float64
+
+	Outcome ScoreOutcome
+
+	results map[string][]glicko2Result
+	ratingTable
+}
+
+type glicko2Result struct {
+	opponentUuid string
+	outcome      float64
+}
+
+// The ratio between the Glicko and Glicko-2 scales
+const glicko2Scale = 173.7178
+
+func NewGlicko2Ratings() *Glicko2Ratings {
+	return &Glicko2Ratings{
+		InitialRating:     1500,
+		InitialDeviation:  350,
+		InitialVolatility: 0.06,
+		Tau:               0.5,
+		Outcome:           SignOutcome,
+		results:           make(map[string][]glicko2Result),
+		ratingTable:       ratingTable{ratings: make(map[string]Rating)},
+	}
+}
+
+func (g *Glicko2Ratings) initial() Rating {
+	return Rating{
+		Rating:     g.InitialRating,
+		Deviation:  g.InitialDeviation,
+		Volatility: g.InitialVolatility,
+	}
+}
+
+func (g *Glicko2Ratings) AddResult(cortex *ng.Cortex, opponent *ng.Cortex, score float64) {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	uuid := cortex.NodeId.UUID
+	result := glicko2Result{
+		opponentUuid: opponent.NodeId.UUID,
+		outcome:      g.Outcome(score),
+	}
+	g.results[uuid] = append(g.results[uuid], result)
+
+	// make sure both are rated, so they are updated at the end of the period
+	g.ratings[uuid] = g.get(uuid, g.initial())
+	g.ratings[opponent.NodeId.UUID] = g.get(opponent.NodeId.UUID, g.initial())
+
+}
+
+func (g *Glicko2Ratings) EndRatingPeriod() {
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	// every rating is updated from the ratings as they were at the start
+	// of the period
+	updated := make(map[string]Rating)
+	for uuid, rating := range g.ratings {
+		updated[uuid] = g.update(rating, g.results[uuid])
+	}
+	g.ratings = updated
+	g.results = make(map[string][]glicko2Result)
+
+}
+
+// The new rating after the results of a rating period, following
+// Glickman's description of the Glicko-2 algorithm
+func (g *Glicko2Ratings) update(rating Rating, results []glicko2Result) Rating {
+
+	mu := (rating.Rating - 1500) / glicko2Scale
+	phi := rating.Deviation / glicko2Scale
+	sigma := rating.Volatility
+
+	if len(results) == 0 {
+		rating.Deviation = math.Sqrt(phi*phi+sigma*sigma) * glicko2Scale
+		return rating
+	}
+
+	varianceInverse := 0.0
+	improvement := 0.0
+	for _, result := range results {
+		opponent := g.get(result.opponentUuid, g.initial())
+		opponentMu := (opponent.Rating - 1500) / glicko2Scale
+		opponentPhi := opponent.Deviation / glicko2Scale
+
+		gPhi := 1.0 / math.Sqrt(1+3*opponentPhi*opponentPhi/(math.Pi*math.Pi))
+		expected := 1.0 / (1.0 + math.Exp(-gPhi*(mu-opponentMu)))
+		varianceInverse += gPhi * gPhi * expected * (1 - expected)
+		improvement += gPhi * (result.outcome - expected)
+	}
+	variance := 1.0 / varianceInverse
+	delta := variance * improvement
+
+	sigma = g.newVolatility(phi, sigma, variance, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1.0 / math.Sqrt(1/(phiStar*phiStar)+1/variance)
+	mu += phi * phi * improvement
+
+	return Rating{
+		Rating:     mu*glicko2Scale + 1500,
+		Deviation:  phi * glicko2Scale,
+		Volatility: sigma,
+		NumGames:   rating.NumGames + len(results),
+	}
+
+}
+
+// Solve for the new volatility with the Illinois algorithm
+func (g *Glicko2Ratings) newVolatility(phi, sigma, variance, delta float64) float64 {
+
+	const epsilon = 0.000001
+
+	a := math.Log(sigma * sigma)
+	tau := g.Tau
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		denominator := phi*phi + variance + ex
+		return ex*(delta*delta-phi*phi-variance-ex)/(2*denominator*denominator) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+variance {
+		B = math.Log(delta*delta - phi*phi - variance)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k += 1
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+
+}
+
+func (g *Glicko2Ratings) Rating(uuid string) Rating {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.get(uuid, g.initial())
+}
+
+func (g *Glicko2Ratings) Ratings() map[string]Rating {
+	return g.copyRatings()
+}
+
+func (g *Glicko2Ratings) SetRatings(ratings map[string]Rating) {
+	g.setRatings(ratings)
+}
+
+func (g *Glicko2Ratings) Retain(uuids map[string]bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.retain(uuids)
+	for uuid := range g.results {
+		if !uuids[uuid] {
+			delete(g.results, uuid)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package neurvolve
+
+import (
+	"errors"
+	"github.com/couchbaselabs/go.assert"
+	"math"
+	"testing"
+)
+
+func TestEloRatings(t *testing.T) {
+
+	ratings := NewEloRatings()
+	winner := SingleNeuronCortex("winner")
+	loser := SingleNeuronCortex("loser")
+
+	ratings.AddResult(winner, loser, 1.0)
+	ratings.AddResult(loser, winner, -1.0)
+	ratings.EndRatingPeriod()
+
+	// the loser is rated against the winner's new rating
+	assert.Equals(t, ratings.Rating("winner").Rating, 1516.0)
+	assert.True(t, ratings.Rating("loser").Rating < 1500.0)
+	assert.Equals(t, ratings.Rating("winner").NumGames, 1)
+	assert.Equals(t, ratings.Rating("unknown").Rating, 1500.0)
+
+}
+
+func TestGlicko2Ratings(t *testing.T) {
+
+	// the worked example from Glickman's description of Glicko-2
+	ratings := NewGlicko2Ratings()
+	ratings.SetRatings(map[string]Rating{
+		"player":    {Rating: 1500, Deviation: 200, Volatility: 0.06},
+		"opponent1": {Rating: 1400, Deviation: 30, Volatility: 0.06},
+		"opponent2": {Rating: 1550, Deviation: 100, Volatility: 0.06},
+		"opponent3": {Rating: 1700, Deviation: 300, Volatility: 0.06},
+	})
+
+	player := SingleNeuronCortex("player")
+	ratings.AddResult(player, SingleNeuronCortex("opponent1"), 1.0)
+	ratings.AddResult(player, SingleNeuronCortex("opponent2"), -1.0)
+	ratings.AddResult(player, SingleNeuronCortex("opponent3"), -1.0)
+	ratings.EndRatingPeriod()
+
+	rating := ratings.Rating("player")
+	assert.True(t, math.Abs(rating.Rating-1464.06) < 0.01)
+	assert.True(t, math.Abs(rating.Deviation-151.52) < 0.01)
+	assert.True(t, math.Abs(rating.Volatility-0.05999) < 0.00001)
+	assert.Equals(t, rating.NumGames, 3)
+
+	// an opponent that did not play becomes less certain
+	assert.True(t, ratings.Rating("opponent1").Deviation > 30)
+
+}
+
+func TestTrainWithRatings(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000000,
+		MaxGenerations:   3,
+		CortexMutator:    NoOpMutator,
+		MatchScheduler:   RoundRobinScheduler{},
+		Ratings:          NewEloRatings(),
+		RatingAsFitness:  true,
+	}
+
+	trainedPopulation, _, err := pt.Train(biasPopulation("cortex", 0, 1, 2), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.Equals(t, trainedPopulation[0].Cortex.Neurons[0].Bias, 2.0)
+	assert.True(t, trainedPopulation[0].Rating > 1500)
+	assert.Equals(t, trainedPopulation[0].Fitness, trainedPopulation[0].Rating)
+
+	pt.MatchScheduler = nil
+	_, _, err = pt.Train(biasPopulation("cortex", 0, 1, 2), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+
+}
+
+func TestRatingsRetain(t *testing.T) {
+
+	for _, ratings := range []RatingSystem{NewEloRatings(), NewGlicko2Ratings()} {
+		ratings.AddResult(SingleNeuronCortex("alive"), SingleNeuronCortex("dead"), 1.0)
+		ratings.EndRatingPeriod()
+		ratings.Retain(map[string]bool{"alive": true})
+
+		_, ok := ratings.Ratings()["dead"]
+		assert.False(t, ok)
+		assert.True(t, ratings.Rating("alive").Rating > 1500)
+	}
+
+}
+
+func TestTrainForgetsRatingsOfCulledCortexes(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000000,
+		MaxGenerations:   6,
+		CortexMutator:    NoOpMutator,
+		MatchScheduler:   RoundRobinScheduler{},
+		Ratings:          NewGlicko2Ratings(),
+	}
+
+	_, _, err := pt.Train(biasPopulation("cortex", 0, 1, 2, 3), FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+
+	// new offspring are bred every generation, but only the ratings of
+	// the survivors are kept
+	assert.True(t, len(pt.Ratings.Ratings()) <= 4)
+
+}
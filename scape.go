@@ -20,3 +20,40 @@ type Scape interface {
 	// Calculate the fitness against an actual opponent
 	FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64
 }
+
+// Scapes which can only evaluate a cortex on its own, such as those
+// based on training samples, can implement this to report that
+// FitnessAgainst is not supported.  Trainers will then return
+// ErrFitnessAgainstUnsupported rather than calling it.
+type FitnessAgainstReporter interface {
+	SupportsFitnessAgainst() bool
+}
+
+func supportsFitnessAgainst(scape Scape) bool {
+	if reporter, ok := scape.(FitnessAgainstReporter); ok {
+		return reporter.SupportsFitnessAgainst()
+	}
+	return true
+}
+
+// Scapes whose evaluations can fail, such as the FitnessCoordinator, can
+// implement this so that trainers stop with the error rather than taking
+// the failed evaluation to have a fitness of 0.
+type FallibleScape interface {
+	TryFitness(cortex *ng.Cortex) (float64, error)
+	TryFitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) (float64, error)
+}
+
+func tryFitness(scape Scape, cortex *ng.Cortex) (float64, error) {
+	if fallible, ok := scape.(FallibleScape); ok {
+		return fallible.TryFitness(cortex)
+	}
+	return scape.Fitness(cortex), nil
+}
+
+func tryFitnessAgainst(scape Scape, cortex *ng.Cortex, opponent *ng.Cortex) (float64, error) {
+	if fallible, ok := scape.(FallibleScape); ok {
+		return fallible.TryFitnessAgainst(cortex, opponent)
+	}
+	return scape.FitnessAgainst(cortex, opponent), nil
+}
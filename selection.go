@@ -0,0 +1,181 @@
+package neurvolve
+
+import (
+	"math"
+	"math/rand"
+)
+
+// A Selector chooses which members of an evaluated population will be
+// parents of the next generation.  The population passed in is sorted by
+// fitness, fittest first.  The same cortex may be chosen more than once,
+// in which case it will produce more than its share of offspring.  Any
+// random choices are drawn from the trainer's random source.
+type Selector interface {
+	SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex)
+}
+
+// Keep the top Ratio of the population and discard the rest.  The
+// survivors take turns being parents, fittest first.  A Ratio of 0
+// is treated as 0.5, which keeps the top half.  A PopulationTrainer
+// rejects a Ratio below 0 or above 1.
+type TruncationSelector struct {
+	Ratio float64
+}
+
+func (s TruncationSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+
+	ratio := s.Ratio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 0.5
+	}
+	poolSize := int(math.Ceil(ratio * float64(len(population))))
+	if poolSize < 1 {
+		poolSize = 1
+	}
+
+	parents = make([]EvaluatedCortex, 0)
+	for i := 0; i < numParents; i++ {
+		parents = append(parents, population[i%poolSize])
+	}
+	return
+}
+
+// Choose each parent by picking TournamentSize members of the population
+// at random and taking the fittest.  A TournamentSize of 0 is treated as 2.
+type TournamentSelector struct {
+	TournamentSize int
+}
+
+func (s TournamentSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+
+	tournamentSize := s.TournamentSize
+	if tournamentSize <= 0 {
+		tournamentSize = 2
+	}
+
+	parents = make([]EvaluatedCortex, 0)
+	for i := 0; i < numParents; i++ {
+		winner := population[RandomIntInRange(random, 0, len(population))]
+		for j := 1; j < tournamentSize; j++ {
+			contender := population[RandomIntInRange(random, 0, len(population))]
+			if contender.Fitness > winner.Fitness {
+				winner = contender
+			}
+		}
+		parents = append(parents, winner)
+	}
+	return
+}
+
+// Fitness-proportionate (roulette wheel) selection.  Since fitness can
+// be negative, fitness values are shifted so that the least fit member
+// of the population has a zero chance of being chosen.
+type RouletteSelector struct{}
+
+func (s RouletteSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+	weights := fitnessWeights(population)
+	parents = make([]EvaluatedCortex, 0)
+	for i := 0; i < numParents; i++ {
+		parents = append(parents, population[spinRoulette(random, weights)])
+	}
+	return
+}
+
+// Rank-based selection.  The chance of being chosen is proportional to
+// the rank in the population rather than to the fitness itself, so a
+// single very fit cortex cannot take over the population.
+type RankSelector struct{}
+
+func (s RankSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+	weights := make([]float64, len(population))
+	for i := range population {
+		weights[i] = float64(len(population) - i)
+	}
+	parents = make([]EvaluatedCortex, 0)
+	for i := 0; i < numParents; i++ {
+		parents = append(parents, population[spinRoulette(random, weights)])
+	}
+	return
+}
+
+// Stochastic universal sampling.  Like roulette selection, but all parents
+// are chosen with a single spin using evenly spaced pointers, which keeps
+// the number of offspring of each cortex close to its expected value.
+type StochasticUniversalSelector struct{}
+
+func (s StochasticUniversalSelector) SelectParents(random *rand.Rand, population []EvaluatedCortex, numParents int) (parents []EvaluatedCortex) {
+
+	parents = make([]EvaluatedCortex, 0)
+	if numParents <= 0 {
+		return
+	}
+
+	weights := fitnessWeights(population)
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	step := total / float64(numParents)
+	pointer := random.Float64() * step
+
+	cumulative := 0.0
+	index := 0
+	for i := 0; i < numParents; i++ {
+		for index < len(weights)-1 && cumulative+weights[index] <= pointer {
+			cumulative += weights[index]
+			index += 1
+		}
+		parents = append(parents, population[index])
+		pointer += step
+	}
+	return
+}
+
+// Turn fitness values into non-negative selection weights by shifting them
+// so that the lowest fitness maps to zero.  If every member has the same
+// fitness, they all get the same weight.
+func fitnessWeights(population []EvaluatedCortex) []float64 {
+
+	weights := make([]float64, len(population))
+	if len(population) == 0 {
+		return weights
+	}
+
+	minFitness := population[0].Fitness
+	for _, evaldCortex := range population {
+		minFitness = math.Min(minFitness, evaldCortex.Fitness)
+	}
+
+	total := 0.0
+	for i, evaldCortex := range population {
+		weights[i] = evaldCortex.Fitness - minFitness
+		total += weights[i]
+	}
+
+	if total == 0 {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// Choose an index with probability proportional to its weight
+func spinRoulette(random *rand.Rand, weights []float64) int {
+
+	total := 0.0
+	for _, weight := range weights {
+		total += weight
+	}
+
+	pointer := random.Float64() * total
+	cumulative := 0.0
+	for i, weight := range weights {
+		cumulative += weight
+		if pointer < cumulative {
+			return i
+		}
+	}
+	return len(weights) - 1
+}
@@ -0,0 +1,106 @@
+package neurvolve
+
+import (
+	"errors"
+	"github.com/couchbaselabs/go.assert"
+	"testing"
+)
+
+func fitnessPopulation(fitnessValues ...float64) []EvaluatedCortex {
+	population := make([]EvaluatedCortex, 0)
+	for _, fitness := range fitnessValues {
+		population = append(population, EvaluatedCortex{Fitness: fitness})
+	}
+	return population
+}
+
+func TestTruncationSelector(t *testing.T) {
+
+	random := newRandom()
+
+	population := fitnessPopulation(8, 7, 6, 5, 4, 3, 2, 1)
+
+	selector := TruncationSelector{Ratio: 0.25}
+	parents := selector.SelectParents(random, population, 4)
+	assert.Equals(t, len(parents), 4)
+	assert.Equals(t, parents[0].Fitness, 8.0)
+	assert.Equals(t, parents[1].Fitness, 7.0)
+	assert.Equals(t, parents[2].Fitness, 8.0)
+	assert.Equals(t, parents[3].Fitness, 7.0)
+
+}
+
+func TestTruncationSelectorRatioValidated(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		CortexMutator: NoOpMutator,
+		Selector:      TruncationSelector{},
+	}
+	assert.True(t, pt.validate(4, FakeScapeBiasSum{}) == nil)
+
+	pt.Selector = TruncationSelector{Ratio: -0.5}
+	assert.True(t, errors.Is(pt.validate(4, FakeScapeBiasSum{}), ErrInvalidConfig))
+
+	pt.Selector = TruncationSelector{Ratio: 1.5}
+	assert.True(t, errors.Is(pt.validate(4, FakeScapeBiasSum{}), ErrInvalidConfig))
+
+}
+
+func TestTournamentSelector(t *testing.T) {
+
+	random := newRandom()
+
+	population := fitnessPopulation(3, 2, 1)
+
+	// with a single entrant, every member can win
+	selector := TournamentSelector{TournamentSize: 1}
+	parents := selector.SelectParents(random, population, 100)
+	assert.Equals(t, len(parents), 100)
+	for _, parent := range parents {
+		assert.True(t, parent.Fitness >= 1 && parent.Fitness <= 3)
+	}
+
+}
+
+func TestRouletteSelector(t *testing.T) {
+
+	random := newRandom()
+
+	// the least fit member has a zero weight, so it should never be chosen
+	population := fitnessPopulation(10, 5, -5)
+	parents := RouletteSelector{}.SelectParents(random, population, 100)
+	assert.Equals(t, len(parents), 100)
+	for _, parent := range parents {
+		assert.True(t, parent.Fitness != -5)
+	}
+
+	// if everyone has the same fitness, anyone can be chosen
+	population = fitnessPopulation(1, 1)
+	parents = RouletteSelector{}.SelectParents(random, population, 10)
+	assert.Equals(t, len(parents), 10)
+
+}
+
+func TestRankSelector(t *testing.T) {
+	random := newRandom()
+	population := fitnessPopulation(-100, -200, -300)
+	parents := RankSelector{}.SelectParents(random, population, 50)
+	assert.Equals(t, len(parents), 50)
+}
+
+func TestStochasticUniversalSelector(t *testing.T) {
+
+	random := newRandom()
+
+	// weights after shifting are 3, 1, 0, so with 4 evenly spaced
+	// pointers the first member must be chosen 3 times and the
+	// second exactly once.
+	population := fitnessPopulation(3, 1, 0)
+	parents := StochasticUniversalSelector{}.SelectParents(random, population, 4)
+	assert.Equals(t, len(parents), 4)
+	assert.Equals(t, parents[0].Fitness, 3.0)
+	assert.Equals(t, parents[1].Fitness, 3.0)
+	assert.Equals(t, parents[2].Fitness, 3.0)
+	assert.Equals(t, parents[3].Fitness, 1.0)
+
+}
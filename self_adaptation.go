@@ -0,0 +1,118 @@
+package neurvolve
+
+import (
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"math/rand"
+)
+
+// A mutator which perturbs a cortex by an amount that scales with stepSize
+type StepSizeMutator func(random *rand.Rand, cortex *ng.Cortex, stepSize float64) (success bool, result MutateResult)
+
+// Evolution strategies style self-adaptation of the mutation step size.
+// Each cortex carries its own step size, in the StepSize of its
+// EvaluatedCortex.  An offspring inherits the step size of its parent,
+// multiplied by exp(LearningRate * N(0, 1)), and is then perturbed by
+// the Mutator with that step size.  Offspring with step sizes that suit
+// the problem tend to be fitter, so the step size tunes itself.
+//
+// The step size can't be stored in the cortex itself, since the Cortex
+// and its json form belong to neurgo, so it travels alongside the cortex
+// instead: in the EvaluatedCortex, and in the CheckpointedCortex when the
+// population is checkpointed.  A cortex saved on its own, or trained
+// again from its json, starts from InitialStepSize.
+//
+// Set it as the SelfAdaptation of a PopulationTrainer to use it.  The
+// trainer's CortexMutator is still applied first, so it can be used for
+// topology mutations, or set to NoOpMutator.  It can also be set as the
+// SelfAdaptation of a StochasticHillClimber.
+type SelfAdaptation struct {
+
+	// The step size of the initial population.  Defaults to
+	// DEFAULT_STD_DEVIATION.
+	InitialStepSize float64
+
+	// The step size is kept within these bounds, so that it can neither
+	// vanish nor blow up
+	MinStepSize float64
+	MaxStepSize float64
+
+	// Often called tau.  0 means 1/sqrt(n), where n is the number of
+	// weights and biases in the cortex.
+	LearningRate float64
+
+	// Defaults to MutateAllWeightsWithStepSize
+	Mutator StepSizeMutator
+}
+
+func NewSelfAdaptation() *SelfAdaptation {
+	return &SelfAdaptation{
+		InitialStepSize: DEFAULT_STD_DEVIATION,
+		MinStepSize:     0.001,
+		MaxStepSize:     2 * math.Pi,
+		Mutator:         MutateAllWeightsWithStepSize,
+	}
+}
+
+// The step size of an offspring of a parent with the given step size.
+// Cortexes without a step size, such as those loaded from a checkpoint
+// saved without self-adaptation, start from InitialStepSize.
+func (s *SelfAdaptation) adapt(random *rand.Rand, stepSize float64, cortex *ng.Cortex) float64 {
+
+	if stepSize <= 0 {
+		stepSize = s.initialStepSize()
+	}
+
+	learningRate := s.LearningRate
+	if learningRate == 0 {
+		learningRate = 1 / math.Sqrt(math.Max(float64(numParameters(cortex)), 1))
+	}
+
+	stepSize *= math.Exp(learningRate * random.NormFloat64())
+	if s.MinStepSize > 0 {
+		stepSize = math.Max(stepSize, s.MinStepSize)
+	}
+	if s.MaxStepSize > 0 {
+		stepSize = math.Min(stepSize, s.MaxStepSize)
+	}
+	return stepSize
+
+}
+
+// Perturb the cortex with the given step size
+func (s *SelfAdaptation) mutate(random *rand.Rand, cortex *ng.Cortex, stepSize float64) (record *MutationRecord, err error) {
+	mutator := s.Mutator
+	if mutator == nil {
+		mutator = MutateAllWeightsWithStepSize
+	}
+	record, err = ApplyMutator(random, cortex, func(random *rand.Rand, cortex *ng.Cortex) (bool, MutateResult) {
+		return mutator(random, cortex, stepSize)
+	})
+	return
+}
+
+func (s *SelfAdaptation) initialStepSize() float64 {
+	if s.InitialStepSize <= 0 {
+		return DEFAULT_STD_DEVIATION
+	}
+	return s.InitialStepSize
+}
+
+// The number of weights and biases in the cortex
+func numParameters(cortex *ng.Cortex) (n int) {
+	for _, neuron := range cortex.Neurons {
+		n += 1
+		for _, inbound := range neuron.Inbound {
+			n += len(inbound.Weights)
+		}
+	}
+	return
+}
+
+// The initial step size for each cortex, if the trainer self-adapts
+func (pt *PopulationTrainer) initialStepSize() float64 {
+	if pt.SelfAdaptation == nil {
+		return 0
+	}
+	return pt.SelfAdaptation.initialStepSize()
+}
@@ -0,0 +1,67 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	"testing"
+)
+
+func TestSelfAdaptationStepSizeBounds(t *testing.T) {
+
+	random := newRandom()
+
+	selfAdaptation := NewSelfAdaptation()
+	selfAdaptation.LearningRate = 5.0
+	selfAdaptation.MinStepSize = 0.5
+	selfAdaptation.MaxStepSize = 2.0
+
+	cortex := BasicCortex()
+	for i := 0; i < 100; i++ {
+		stepSize := selfAdaptation.adapt(random, 1.0, cortex)
+		assert.True(t, stepSize >= 0.5)
+		assert.True(t, stepSize <= 2.0)
+	}
+
+}
+
+func TestSelfAdaptationMissingStepSize(t *testing.T) {
+
+	random := newRandom()
+
+	selfAdaptation := NewSelfAdaptation()
+	selfAdaptation.LearningRate = 0.000001
+
+	// a cortex without a step size starts from the initial one
+	stepSize := selfAdaptation.adapt(random, 0, BasicCortex())
+	assert.True(t, stepSize > DEFAULT_STD_DEVIATION*0.99)
+	assert.True(t, stepSize < DEFAULT_STD_DEVIATION*1.01)
+
+}
+
+func TestSelfAdaptationOffspringInheritStepSize(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		CortexMutator:  NoOpMutator,
+		SelfAdaptation: NewSelfAdaptation(),
+	}
+	pt.SelfAdaptation.InitialStepSize = 0.1
+
+	parents := pt.addEmptyFitnessScores(biasPopulation("cortex", 1))
+	assert.Equals(t, parents[0].StepSize, 0.1)
+
+	offspring, err := pt.breed(parents, 20)
+	assert.True(t, err == nil)
+
+	numChanged := 0
+	for _, child := range offspring {
+		assert.True(t, child.StepSize > 0)
+		if child.StepSize != 0.1 {
+			numChanged += 1
+		}
+		// the bias was perturbed, by an amount on the scale of the step size
+		bias := child.Cortex.Neurons[0].Bias
+		assert.True(t, bias != 1.0)
+		assert.True(t, bias > -1 && bias < 3)
+	}
+	assert.Equals(t, numChanged, 20)
+
+}
@@ -0,0 +1,256 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math"
+	"sort"
+	"sync"
+)
+
+// A group of cortexes with similar genotypes.  Cortexes only compete for
+// offspring with the other members of their species, which gives a new
+// topology a few generations to tune its weights before it has to compete
+// with the rest of the population.
+type Species struct {
+	Id                  int
+	Representative      *ng.Cortex
+	Members             []EvaluatedCortex
+	BestFitness         float64
+	LastImproved        int
+	CreatedInGeneration int
+}
+
+// Divides a population into species by compatibility distance.  Set it
+// as the Speciation of a PopulationTrainer to breed each species
+// separately, with a share of the next generation proportional to the
+// shared fitness of its members.
+//
+// The species and the adjusted threshold are saved in checkpoints, and
+// restored when the run is resumed.
+type Speciation struct {
+
+	// Used to compute the compatibility distance between two cortexes
+	Coefficients CompatibilityCoefficients
+
+	// Two cortexes closer than this are in the same species.  When
+	// TargetNumSpecies is set this is adjusted every generation.
+	CompatibilityThreshold float64
+
+	// If greater than 0, the compatibility threshold is raised or lowered
+	// by ThresholdStep each generation to move towards this many species
+	TargetNumSpecies int
+	ThresholdStep    float64
+
+	// A species whose best fitness has not improved for this many
+	// generations goes extinct, unless it holds the fittest cortex in the
+	// population.  0 means species never go extinct.
+	StagnationLimit int
+
+	species       []*Species
+	nextSpeciesId int
+	mutex         sync.RWMutex
+}
+
+func NewSpeciation(compatibilityThreshold float64) *Speciation {
+	return &Speciation{
+		Coefficients:           DefaultCompatibilityCoefficients,
+		CompatibilityThreshold: compatibilityThreshold,
+		ThresholdStep:          0.1,
+		StagnationLimit:        15,
+		species:                make([]*Species, 0),
+	}
+}
+
+// A copy of the current species, most recently created last.  The member
+// cortexes are shared with the trainer and should not be modified.
+func (s *Speciation) Species() []Species {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	species := make([]Species, 0)
+	for _, existing := range s.species {
+		speciesCopy := *existing
+		speciesCopy.Members = append([]EvaluatedCortex{}, existing.Members...)
+		species = append(species, speciesCopy)
+	}
+	return species
+
+}
+
+// Restore the species saved in a checkpoint.  Their members are filled
+// in when the population is next speciated.
+func (s *Speciation) restore(species []*Species, nextSpeciesId int, compatibilityThreshold float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.species = species
+	s.nextSpeciesId = nextSpeciesId
+	s.CompatibilityThreshold = compatibilityThreshold
+}
+
+// The id the next new species will get, and the current threshold
+func (s *Speciation) state() (nextSpeciesId int, compatibilityThreshold float64) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.nextSpeciesId, s.CompatibilityThreshold
+}
+
+// Assign each member of the population, which must be sorted by fitness,
+// to the first species whose representative it is compatible with, or to
+// a new species if there is none.  Afterwards stagnant species go extinct
+// and the compatibility threshold is adjusted.
+func (s *Speciation) speciate(population []EvaluatedCortex, generation int) {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, species := range s.species {
+		species.Members = make([]EvaluatedCortex, 0)
+	}
+
+	for _, evaldCortex := range population {
+		species := s.findCompatibleSpecies(evaldCortex.Cortex)
+		if species == nil {
+			s.nextSpeciesId += 1
+			species = &Species{
+				Id:                  s.nextSpeciesId,
+				Representative:      evaldCortex.Cortex,
+				Members:             make([]EvaluatedCortex, 0),
+				BestFitness:         math.Inf(-1),
+				LastImproved:        generation,
+				CreatedInGeneration: generation,
+			}
+			s.species = append(s.species, species)
+		}
+		species.Members = append(species.Members, evaldCortex)
+	}
+
+	var fittestCortex *ng.Cortex
+	if len(population) > 0 {
+		fittestCortex = population[0].Cortex
+	}
+
+	survivors := make([]*Species, 0)
+	for _, species := range s.species {
+
+		if len(species.Members) == 0 {
+			continue
+		}
+
+		// the fittest member represents the species in the next generation
+		species.Representative = species.Members[0].Cortex
+		if species.Members[0].Fitness > species.BestFitness {
+			species.BestFitness = species.Members[0].Fitness
+			species.LastImproved = generation
+		}
+
+		stagnant := s.StagnationLimit > 0 && generation-species.LastImproved >= s.StagnationLimit
+		if stagnant && species.Representative != fittestCortex {
+			logg.LogTo("NEURVOLVE", "Species %v is extinct after %v generations without improvement", species.Id, generation-species.LastImproved)
+			continue
+		}
+
+		survivors = append(survivors, species)
+	}
+	s.species = survivors
+
+	s.adjustThreshold()
+
+}
+
+func (s *Speciation) findCompatibleSpecies(cortex *ng.Cortex) *Species {
+	for _, species := range s.species {
+		distance := CompatibilityDistance(species.Representative, cortex, s.Coefficients)
+		if distance < s.CompatibilityThreshold {
+			return species
+		}
+	}
+	return nil
+}
+
+// Move the compatibility threshold towards TargetNumSpecies
+func (s *Speciation) adjustThreshold() {
+
+	if s.TargetNumSpecies <= 0 {
+		return
+	}
+
+	switch {
+	case len(s.species) < s.TargetNumSpecies:
+		s.CompatibilityThreshold -= s.ThresholdStep
+	case len(s.species) > s.TargetNumSpecies:
+		s.CompatibilityThreshold += s.ThresholdStep
+	}
+	if s.CompatibilityThreshold < s.ThresholdStep {
+		s.CompatibilityThreshold = s.ThresholdStep
+	}
+
+}
+
+// The number of places in a next generation of populationSize that each
+// species gets.  Each member's fitness is shared with the rest of its
+// species by dividing it by the size of the species, and each species gets
+// a share of the places in proportion to the total shared fitness of its
+// members.  Fitness is measured from the least fit cortex in the
+// population, so that negative fitness scores can be shared as well.
+func (s *Speciation) offspringQuotas(populationSize int) (quotas []int) {
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	quotas = make([]int, len(s.species))
+	if len(s.species) == 0 {
+		return
+	}
+
+	minFitness := math.Inf(1)
+	for _, species := range s.species {
+		for _, member := range species.Members {
+			minFitness = math.Min(minFitness, member.Fitness)
+		}
+	}
+
+	sharedFitness := make([]float64, len(s.species))
+	totalSharedFitness := 0.0
+	for i, species := range s.species {
+		for _, member := range species.Members {
+			sharedFitness[i] += (member.Fitness - minFitness) / float64(len(species.Members))
+		}
+		totalSharedFitness += sharedFitness[i]
+	}
+
+	// when every cortex is equally fit, share the places by species size
+	if totalSharedFitness == 0 {
+		for i, species := range s.species {
+			sharedFitness[i] = float64(len(species.Members))
+			totalSharedFitness += sharedFitness[i]
+		}
+	}
+
+	// hand out the whole places first, then the remaining ones to the
+	// species with the largest fractions left over
+	remainders := make([]float64, len(s.species))
+	numAssigned := 0
+	for i := range s.species {
+		share := float64(populationSize) * sharedFitness[i] / totalSharedFitness
+		quotas[i] = int(math.Floor(share))
+		remainders[i] = share - float64(quotas[i])
+		numAssigned += quotas[i]
+	}
+
+	order := make([]int, len(s.species))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; numAssigned < populationSize; i++ {
+		quotas[order[i%len(order)]] += 1
+		numAssigned += 1
+	}
+
+	return
+
+}
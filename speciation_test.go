@@ -0,0 +1,108 @@
+package neurvolve
+
+import (
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+func TestSpeciate(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: BasicCortex(), Fitness: 3},
+		{Cortex: SingleNeuronCortex("single1"), Fitness: 2},
+		{Cortex: BasicCortex(), Fitness: 1},
+		{Cortex: SingleNeuronCortex("single2"), Fitness: 0},
+	}
+
+	speciation := NewSpeciation(1.0)
+	speciation.speciate(population, 0)
+
+	species := speciation.Species()
+	assert.Equals(t, len(species), 2)
+	assert.Equals(t, len(species[0].Members), 2)
+	assert.Equals(t, species[0].BestFitness, 3.0)
+	assert.Equals(t, species[1].Members[0].Cortex, population[1].Cortex)
+
+}
+
+func TestSpeciationStagnation(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: BasicCortex(), Fitness: 3},
+		{Cortex: SingleNeuronCortex("single"), Fitness: 2},
+	}
+
+	speciation := NewSpeciation(1.0)
+	speciation.StagnationLimit = 3
+	for generation := 0; generation < 3; generation++ {
+		speciation.speciate(population, generation)
+		assert.Equals(t, len(speciation.Species()), 2)
+	}
+
+	// the species holding the fittest cortex is never culled
+	speciation.speciate(population, 3)
+	species := speciation.Species()
+	assert.Equals(t, len(species), 1)
+	assert.Equals(t, species[0].Representative, population[0].Cortex)
+
+}
+
+func TestSpeciationDynamicThreshold(t *testing.T) {
+
+	population := []EvaluatedCortex{
+		{Cortex: BasicCortex(), Fitness: 3},
+		{Cortex: SingleNeuronCortex("single"), Fitness: 2},
+	}
+
+	speciation := NewSpeciation(1.0)
+	speciation.TargetNumSpecies = 1
+	speciation.ThresholdStep = 0.5
+	speciation.speciate(population, 0)
+	assert.Equals(t, speciation.CompatibilityThreshold, 1.5)
+
+}
+
+func TestOffspringQuotas(t *testing.T) {
+
+	speciation := NewSpeciation(1.0)
+	speciation.species = []*Species{
+		{Members: fitnessPopulation(6, 6)},
+		{Members: fitnessPopulation(4)},
+		{Members: fitnessPopulation(2)},
+	}
+
+	// measured from the least fit, the shared fitness is 4, 2 and 0
+	quotas := speciation.offspringQuotas(6)
+	assert.DeepEquals(t, quotas, []int{4, 2, 0})
+
+	// the places left over after rounding go to the largest remainders
+	quotas = speciation.offspringQuotas(7)
+	assert.DeepEquals(t, quotas, []int{5, 2, 0})
+
+}
+
+func TestTrainWithSpeciation(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   5,
+		CortexMutator:    NoOpMutator,
+		Speciation:       NewSpeciation(1.0),
+	}
+
+	population := []*ng.Cortex{
+		BasicCortex(),
+		BasicCortex(),
+		SingleNeuronCortex("single1"),
+		SingleNeuronCortex("single2"),
+		SingleNeuronCortex("single3"),
+	}
+
+	trainedPopulation, succeeded, err := pt.Train(population, FakeScapeBiasSum{}, NullRecorder{})
+	assert.True(t, err == nil)
+	assert.False(t, succeeded)
+	assert.Equals(t, len(trainedPopulation), 5)
+	assert.True(t, len(pt.Speciation.Species()) > 0)
+
+}
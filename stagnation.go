@@ -0,0 +1,341 @@
+package neurvolve
+
+import (
+	"fmt"
+	"github.com/couchbaselabs/logg"
+	ng "github.com/tleyden/neurgo"
+	"math"
+)
+
+// What a StagnationCriterion watches
+type StagnationMeasure int
+
+const (
+	// The fitness of the fittest cortex in each generation
+	BestFitnessStagnation StagnationMeasure = iota
+
+	// The mean fitness of each generation
+	MeanFitnessStagnation
+
+	// The mean compatibility distance of each cortex in a generation to
+	// the fittest one.  See CompatibilityDistance.
+	DiversityStagnation
+)
+
+func (measure StagnationMeasure) String() string {
+	switch measure {
+	case BestFitnessStagnation:
+		return "best fitness"
+	case MeanFitnessStagnation:
+		return "mean fitness"
+	case DiversityStagnation:
+		return "diversity"
+	}
+	return "unknown"
+}
+
+// What the trainer does when a StagnationCriterion is met
+type StagnationAction int
+
+const (
+	// Stop training, with StopReasonStagnated
+	StopOnStagnation StagnationAction = iota
+
+	// Replace part of the next generation with mutated copies of the
+	// initial population
+	ReseedOnStagnation
+
+	// Mutate each offspring more times, until the measure improves again
+	IncreaseMutationOnStagnation
+)
+
+func (action StagnationAction) String() string {
+	switch action {
+	case StopOnStagnation:
+		return "stop"
+	case ReseedOnStagnation:
+		return "reseed"
+	case IncreaseMutationOnStagnation:
+		return "increase mutation"
+	}
+	return "unknown"
+}
+
+// A condition under which a population counts as stagnant, and what to
+// do about it.  A fitness measure is stagnant once it has gone Generations
+// generations without improving on its best value by more than
+// MinImprovement.  Diversity is stagnant once it has been below
+// DiversityThreshold for Generations generations in a row.  After a
+// criterion is met, it has to be met over another Generations generations
+// before it triggers again.
+type StagnationCriterion struct {
+	Measure     StagnationMeasure
+	Generations int
+	Action      StagnationAction
+
+	MinImprovement     float64
+	DiversityThreshold float64
+
+	// The fraction of the next generation replaced by ReseedOnStagnation.
+	// Defaults to 0.5.  At least one cortex is always kept.
+	ReseedFraction float64
+
+	// How many more times each offspring is mutated each time
+	// IncreaseMutationOnStagnation triggers.  Defaults to 1.
+	ExtraMutations int
+}
+
+// Reported to a Recorder which implements StagnationRecorder whenever a
+// StagnationCriterion is met
+type StagnationEvent struct {
+	Generation int
+	Criterion  StagnationCriterion
+
+	// The value of the criterion's measure in this generation
+	Value float64
+
+	// The number of extra mutations each offspring gets after the event
+	ExtraMutations int
+}
+
+// Optionally implemented by a Recorder to be told about stagnation events
+type StagnationRecorder interface {
+	AddStagnationEvent(event StagnationEvent)
+}
+
+// The progress of a single criterion
+type stagnationState struct {
+	best             float64
+	lastImproved     int
+	started          bool
+	generationsBelow int
+	extraMutations   int
+}
+
+// Forget the progress of all the criteria, and remember the initial
+// population for reseeding
+func (pt *PopulationTrainer) resetStagnation(population []*ng.Cortex) {
+	pt.stagnation = nil
+	pt.pendingReseed = 0
+	pt.seeds = nil
+	for _, criterion := range pt.StagnationCriteria {
+		if criterion.Action == ReseedOnStagnation {
+			for _, cortex := range population {
+				pt.seeds = append(pt.seeds, cortex.Copy())
+			}
+			break
+		}
+	}
+}
+
+// Update each criterion with the evaluated population, which is fittest
+// first, and carry out the actions of those which are met.  Returns true
+// if training should stop.
+func (pt *PopulationTrainer) detectStagnation(evaluated []EvaluatedCortex, generation int, recorder Recorder) (stop bool) {
+
+	if len(pt.StagnationCriteria) == 0 || len(evaluated) == 0 {
+		return false
+	}
+	if len(pt.stagnation) != len(pt.StagnationCriteria) {
+		pt.stagnation = make([]stagnationState, len(pt.StagnationCriteria))
+	}
+
+	for i, criterion := range pt.StagnationCriteria {
+
+		state := &pt.stagnation[i]
+		value := pt.stagnationMeasure(criterion.Measure, evaluated)
+		if !pt.stagnant(criterion, state, value, generation) {
+			continue
+		}
+
+		switch criterion.Action {
+		case StopOnStagnation:
+			stop = true
+		case ReseedOnStagnation:
+			reseedFraction := criterion.ReseedFraction
+			if reseedFraction == 0 {
+				reseedFraction = 0.5
+			}
+			pt.pendingReseed = math.Max(pt.pendingReseed, reseedFraction)
+		case IncreaseMutationOnStagnation:
+			extraMutations := criterion.ExtraMutations
+			if extraMutations == 0 {
+				extraMutations = 1
+			}
+			state.extraMutations += extraMutations
+		}
+
+		event := StagnationEvent{
+			Generation:     generation,
+			Criterion:      criterion,
+			Value:          value,
+			ExtraMutations: pt.extraMutations(),
+		}
+		logg.LogTo("NEURVOLVE", "Generation %v: %v stagnated at %v, action: %v", generation, criterion.Measure, value, criterion.Action)
+		if stagnationRecorder, ok := recorder.(StagnationRecorder); ok {
+			stagnationRecorder.AddStagnationEvent(event)
+		}
+	}
+
+	return stop
+
+}
+
+// Update the state of the criterion with this generation's value, and
+// return true if the criterion is met
+func (pt *PopulationTrainer) stagnant(criterion StagnationCriterion, state *stagnationState, value float64, generation int) bool {
+
+	if criterion.Measure == DiversityStagnation {
+		if value >= criterion.DiversityThreshold {
+			state.generationsBelow = 0
+			state.extraMutations = 0
+			return false
+		}
+		state.generationsBelow += 1
+		if state.generationsBelow < criterion.Generations {
+			return false
+		}
+		state.generationsBelow = 0
+		return true
+	}
+
+	if !state.started || value > state.best+criterion.MinImprovement {
+		if state.started {
+			state.extraMutations = 0
+		}
+		state.started = true
+		state.best = value
+		state.lastImproved = generation
+		return false
+	}
+	if generation-state.lastImproved < criterion.Generations {
+		return false
+	}
+	state.lastImproved = generation
+	return true
+
+}
+
+func (pt *PopulationTrainer) stagnationMeasure(measure StagnationMeasure, evaluated []EvaluatedCortex) float64 {
+
+	switch measure {
+	case MeanFitnessStagnation:
+		total := 0.0
+		for _, evaldCortex := range evaluated {
+			total += evaldCortex.Fitness
+		}
+		return total / float64(len(evaluated))
+	case DiversityStagnation:
+		coefficients := DefaultCompatibilityCoefficients
+		if pt.Speciation != nil {
+			coefficients = pt.Speciation.Coefficients
+		}
+		if len(evaluated) < 2 {
+			return 0.0
+		}
+		fittest := evaluated[0].Cortex
+		total := 0.0
+		for _, evaldCortex := range evaluated[1:] {
+			total += CompatibilityDistance(fittest, evaldCortex.Cortex, coefficients)
+		}
+		return total / float64(len(evaluated)-1)
+	default:
+		return evaluated[0].Fitness
+	}
+
+}
+
+// The number of times each offspring is mutated on top of the usual one
+func (pt *PopulationTrainer) extraMutations() (extraMutations int) {
+	for _, state := range pt.stagnation {
+		extraMutations += state.extraMutations
+	}
+	return
+}
+
+// Replace the newest part of the next generation with mutated copies of
+// the initial population, if a criterion asked for it.  If the initial
+// population is not known, as when a checkpoint taken without a reseeding
+// criterion is resumed with one, the generation itself is used instead.
+func (pt *PopulationTrainer) reseed(nextGeneration []EvaluatedCortex) (reseeded []EvaluatedCortex, err error) {
+
+	reseeded = nextGeneration
+	if pt.pendingReseed == 0 {
+		return
+	}
+
+	numReseeded := int(math.Ceil(pt.pendingReseed * float64(len(nextGeneration))))
+	if numReseeded > len(nextGeneration)-1 {
+		numReseeded = len(nextGeneration) - 1
+	}
+	pt.pendingReseed = 0
+
+	seeds := pt.seeds
+	if len(seeds) == 0 {
+		for _, evaldCortex := range nextGeneration {
+			seeds = append(seeds, evaldCortex.Cortex)
+		}
+	}
+
+	// the newest offspring are at the end
+	reseeded = append([]EvaluatedCortex{}, nextGeneration...)
+	for i := len(reseeded) - numReseeded; i < len(reseeded); i++ {
+		seed := seeds[RandomIntInRange(pt.randomSource(), 0, len(seeds))]
+		cortex := seed.Copy()
+		cortex.NodeId = ng.NewCortexId(fmt.Sprintf("cortex-%s", newUuid(pt.randomSource())))
+		var mutations []*MutationRecord
+		if _, mutations, err = pt.mutate(cortex); err != nil {
+			return
+		}
+		reseeded[i] = EvaluatedCortex{
+			Cortex:              cortex,
+			ParentId:            seed.NodeId.UUID,
+			CreatedInGeneration: pt.CurrentGeneration,
+			StepSize:            pt.initialStepSize(),
+		}
+		pt.expectLineage(reseeded[i], "", mutations)
+	}
+	logg.LogTo("NEURVOLVE", "Reseeded %v cortexes in generation %v", numReseeded, pt.CurrentGeneration)
+	return
+
+}
+
+// Apply the CortexMutator, or an operator chosen by the MutationPolicy,
+// to the cortex, plus any extra mutations added by stagnation criteria.
+// Returns the MutationPolicy operators which were applied, and the
+// records of the mutations.
+func (pt *PopulationTrainer) mutate(cortex *ng.Cortex) (operators []int, mutations []*MutationRecord, err error) {
+	for i := 0; i <= pt.extraMutations(); i++ {
+		var record *MutationRecord
+		if pt.MutationPolicy == nil {
+			if record, err = ApplyMutator(pt.randomSource(), cortex, pt.CortexMutator); err != nil {
+				return
+			}
+		} else {
+			var operator int
+			if operator, record, err = pt.MutationPolicy.apply(pt.randomSource(), cortex); err != nil {
+				return
+			}
+			operators = append(operators, operator)
+		}
+		if record != nil {
+			mutations = append(mutations, record)
+		}
+	}
+	return
+}
+
+func (pt *PopulationTrainer) validateStagnationCriteria() error {
+	for _, criterion := range pt.StagnationCriteria {
+		if criterion.Generations < 1 {
+			return fmt.Errorf("%w: stagnation Generations must be at least 1", ErrInvalidConfig)
+		}
+		if criterion.ReseedFraction < 0 || criterion.ReseedFraction > 1 {
+			return fmt.Errorf("%w: ReseedFraction must be between 0 and 1", ErrInvalidConfig)
+		}
+		if criterion.ExtraMutations < 0 {
+			return fmt.Errorf("%w: ExtraMutations cannot be negative", ErrInvalidConfig)
+		}
+	}
+	return nil
+}
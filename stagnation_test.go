@@ -0,0 +1,112 @@
+package neurvolve
+
+import (
+	"context"
+	"github.com/couchbaselabs/go.assert"
+	ng "github.com/tleyden/neurgo"
+	"testing"
+)
+
+type FakeStagnationRecorder struct {
+	NullRecorder
+	events []StagnationEvent
+}
+
+func (r *FakeStagnationRecorder) AddStagnationEvent(event StagnationEvent) {
+	r.events = append(r.events, event)
+}
+
+func TestStagnationStop(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		FitnessThreshold: 1000,
+		MaxGenerations:   20,
+		CortexMutator:    NoOpMutator,
+		StagnationCriteria: []StagnationCriterion{
+			{Measure: BestFitnessStagnation, Generations: 3, Action: StopOnStagnation},
+		},
+	}
+	recorder := &FakeStagnationRecorder{}
+
+	population := biasPopulation("cortex", 1, 2, 3, 4)
+	_, stopReason, err := pt.TrainContext(context.Background(), population, FakeScapeBiasSum{}, recorder)
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonStagnated)
+	assert.Equals(t, len(recorder.events), 1)
+	assert.Equals(t, recorder.events[0].Generation, 3)
+	assert.Equals(t, recorder.events[0].Value, 4.0)
+
+}
+
+func TestStagnationIncreaseMutation(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		StagnationCriteria: []StagnationCriterion{
+			{Measure: MeanFitnessStagnation, Generations: 2, Action: IncreaseMutationOnStagnation},
+		},
+	}
+	recorder := &FakeStagnationRecorder{}
+
+	flat := pt.addEmptyFitnessScores(biasPopulation("cortex", 1, 2))
+	flat[0].Fitness, flat[1].Fitness = 2.0, 1.0
+	for generation := 0; generation < 5; generation++ {
+		assert.False(t, pt.detectStagnation(flat, generation, recorder))
+	}
+	assert.Equals(t, len(recorder.events), 2)
+	assert.Equals(t, recorder.events[1].ExtraMutations, 2)
+	assert.Equals(t, pt.extraMutations(), 2)
+
+	// an improvement takes the extra mutations away again
+	improved := pt.addEmptyFitnessScores(biasPopulation("cortex", 1, 2))
+	improved[0].Fitness, improved[1].Fitness = 5.0, 3.0
+	pt.detectStagnation(improved, 5, recorder)
+	assert.Equals(t, pt.extraMutations(), 0)
+
+}
+
+func TestStagnationDiversity(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		StagnationCriteria: []StagnationCriterion{
+			{Measure: DiversityStagnation, Generations: 1, DiversityThreshold: 0.1, Action: ReseedOnStagnation},
+		},
+	}
+	recorder := &FakeStagnationRecorder{}
+
+	// copies of the same cortex have no diversity at all
+	cortex := BasicCortex()
+	clones := pt.addEmptyFitnessScores([]*ng.Cortex{cortex, cortex.Copy(), cortex.Copy()})
+	assert.False(t, pt.detectStagnation(clones, 0, recorder))
+	assert.Equals(t, len(recorder.events), 1)
+	assert.Equals(t, recorder.events[0].Value, 0.0)
+	assert.Equals(t, pt.pendingReseed, 0.5)
+
+}
+
+func TestStagnationReseed(t *testing.T) {
+
+	pt := &PopulationTrainer{
+		CortexMutator: NoOpMutator,
+		StagnationCriteria: []StagnationCriterion{
+			{Measure: BestFitnessStagnation, Generations: 1, Action: ReseedOnStagnation, ReseedFraction: 0.5},
+		},
+	}
+	seeds := biasPopulation("seed", 10, 20)
+	pt.resetStagnation(seeds)
+	pt.pendingReseed = 0.5
+
+	nextGeneration := pt.addEmptyFitnessScores(biasPopulation("cortex", 1, 2, 3, 4))
+	reseeded, err := pt.reseed(nextGeneration)
+	assert.True(t, err == nil)
+	assert.Equals(t, len(reseeded), 4)
+	assert.Equals(t, pt.pendingReseed, 0.0)
+
+	// the oldest half is kept, and the newest half comes from the seeds
+	assert.Equals(t, reseeded[0].Cortex, nextGeneration[0].Cortex)
+	assert.Equals(t, reseeded[1].Cortex, nextGeneration[1].Cortex)
+	for _, evaldCortex := range reseeded[2:] {
+		assert.True(t, evaldCortex.ParentId == "seed-0" || evaldCortex.ParentId == "seed-1")
+		assert.True(t, evaldCortex.Cortex.Neurons[0].Bias >= 10)
+	}
+
+}
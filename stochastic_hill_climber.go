@@ -1,6 +1,8 @@
 package neurvolve
 
 import (
+	"context"
+	"fmt"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/tleyden/neurgo"
 	"math"
@@ -12,64 +14,152 @@ type StochasticHillClimber struct {
 	MaxIterationsBeforeRestart int
 	MaxAttempts                int
 	WeightSaturationRange      []float64
+
+	// If set, each cortex is evaluated with several fitness samples, for
+	// scapes whose fitness is noisy.  See EvaluationPolicy.
+	EvaluationPolicy *EvaluationPolicy
+
+	// If set, candidates are perturbed by SelfAdaptation's Mutator with a
+	// step size that adapts itself, as in a (1+1) evolution strategy,
+	// rather than by PerturbParameters with its fixed range of -2*pi to
+	// 2*pi.  Each candidate's step size is mutated from the incumbent's,
+	// and kept if the candidate is accepted.  A restart goes back to the
+	// InitialStepSize.  Parameters are still kept within the
+	// WeightSaturationRange.
+	SelfAdaptation *SelfAdaptation
+
+	// If set, all random choices are drawn from Rand, so that runs with
+	// the same seed are reproducible.  A TopologyMutatingTrainer runs its
+	// hill climber with its own Rand instead.
+	Rand *rand.Rand
 }
 
-func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (fittestNeuralNet *ng.Cortex, succeeded bool) {
+func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (fittestNeuralNet *ng.Cortex, succeeded bool, err error) {
+
+	fittestNeuralNet, stopReason, err := shc.TrainContext(context.Background(), cortex, scape)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
 
-	shc.validate()
+}
+
+// Same as Train, but stops at the next fitness evaluation once ctx is done.
+// Returns the fittest cortex found so far and the reason training stopped.
+func (shc *StochasticHillClimber) TrainContext(ctx context.Context, cortex *ng.Cortex, scape Scape) (fittestNeuralNet *ng.Cortex, stopReason StopReason, err error) {
+
+	fittestNeuralNet, _, stopReason, err = shc.train(ctx, randomOrNew(shc.Rand), cortex, scape)
+	return
+
+}
+
+func (shc *StochasticHillClimber) train(ctx context.Context, random *rand.Rand, cortex *ng.Cortex, scape Scape) (fittestNeuralNet *ng.Cortex, fitness float64, stopReason StopReason, err error) {
+
+	if err = shc.validate(); err != nil {
+		return
+	}
 
+	policy := shc.evaluationPolicy()
 	numAttempts := 0
 
 	fittestNeuralNet = cortex
 
+	// a restart scrambles the parameters of fittestNeuralNet, so keep a
+	// copy of it until something fitter comes along
+	var savedNeuralNet *ng.Cortex
+	defer func() {
+		if savedNeuralNet != nil {
+			fittestNeuralNet = savedNeuralNet
+		}
+	}()
+
+	stepSize := shc.initialStepSize()
+
 	// Apply NN to problem and save fitness
-	fitness := scape.Fitness(fittestNeuralNet)
+	samples, err := policy.sample(scape, fittestNeuralNet)
+	if err != nil {
+		return
+	}
+	fitness = policy.aggregate(samples)
 	logg.LogTo("MAIN", "Initial fitness: %v", fitness)
 
 	if fitness > shc.FitnessThreshold {
-		succeeded = true
+		stopReason = StopReasonThresholdReached
 		return
 	}
 
 	for i := 0; ; i++ {
 
+		if stopReason = contextStopReason(ctx); stopReason != 0 {
+			logg.LogTo("MAIN", "Stopping hill climber: %v.  fitness: %v", stopReason, fitness)
+			break
+		}
+
 		// Save the genotype
 		candidateNeuralNet := fittestNeuralNet.Copy()
 
 		// Perturb synaptic weights and biases
-		PerturbParameters(candidateNeuralNet, shc.WeightSaturationRange)
+		candidateStepSize, perturbErr := shc.perturb(random, candidateNeuralNet, stepSize)
+		if perturbErr != nil {
+			err = perturbErr
+			return
+		}
 
 		// Re-Apply NN to problem
-		candidateFitness := scape.Fitness(candidateNeuralNet)
-		logg.LogTo("DEBUG", "candidate fitness: %v", fitness)
+		candidateSamples, sampleErr := policy.sample(scape, candidateNeuralNet)
+		if sampleErr != nil {
+			err = sampleErr
+			return
+		}
+		candidateFitness := policy.aggregate(candidateSamples)
+		logg.LogTo("DEBUG", "candidate fitness: %v", candidateFitness)
+
+		// a single lucky evaluation shouldn't keep the incumbent in place.
+		// After a restart the incumbent is the saved cortex, rather than
+		// the scrambled one, until something fitter comes along.
+		if policy.ReevaluateIncumbent {
+			incumbent := fittestNeuralNet
+			if savedNeuralNet != nil {
+				incumbent = savedNeuralNet
+			}
+			if samples, err = policy.sample(scape, incumbent); err != nil {
+				return
+			}
+			fitness = policy.aggregate(samples)
+		}
 
 		// If fitness of perturbed NN is higher, discard original NN and keep new
 		// If fitness of original is higher, discard perturbed and keep old.
 
-		if candidateFitness > fitness {
+		if policy.better(candidateSamples, samples) {
 			logg.LogTo("MAIN", "i: %v candidateFitness: %v > fitness: %v", i, candidateFitness, fitness)
 			i = 0
 			fittestNeuralNet = candidateNeuralNet
 			fitness = candidateFitness
+			samples = candidateSamples
+			stepSize = candidateStepSize
+			savedNeuralNet = nil
+
+			// only a candidate which was accepted can meet the threshold
+			if fitness > shc.FitnessThreshold {
+				logg.LogTo("MAIN", "fitness: %v > Threshold.  Success at i=%v", fitness, i)
+				stopReason = StopReasonThresholdReached
+				break
+			}
 
 		}
 
-		if candidateFitness > shc.FitnessThreshold {
-			logg.LogTo("MAIN", "candidateFitness: %v > Threshold.  Success at i=%v", candidateFitness, i)
-			succeeded = true
-			break
-		}
-
 		if ng.IntModuloProper(i, shc.MaxIterationsBeforeRestart) {
 			logg.LogTo("MAIN", "** restart hill climber.  fitness: %f i/max: %d/%d", fitness, numAttempts, shc.MaxAttempts)
 			numAttempts += 1
 			i = 0
-			shc.resetParametersToRandom(fittestNeuralNet)
-			ng.SeedRandom()
+			if savedNeuralNet == nil {
+				savedNeuralNet = fittestNeuralNet.Copy()
+			}
+			shc.resetParametersToRandom(random, fittestNeuralNet)
+			stepSize = shc.initialStepSize()
 		}
 
 		if numAttempts >= shc.MaxAttempts {
-			succeeded = false
+			stopReason = StopReasonBudgetExhausted
 			break
 		}
 
@@ -79,7 +169,7 @@ func (shc *StochasticHillClimber) Train(cortex *ng.Cortex, scape Scape) (fittest
 
 }
 
-func (shc *StochasticHillClimber) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestNeuralNet *ng.Cortex, succeeded bool) {
+func (shc *StochasticHillClimber) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestNeuralNet *ng.Cortex, succeeded bool, err error) {
 
 	trainingSampleScape := &TrainingSampleScape{
 		examples: examples,
@@ -94,31 +184,65 @@ func (shc *StochasticHillClimber) TrainExamples(cortex *ng.Cortex, examples []*n
 //    with probability of 1/sqrt(parameters_size)
 // 3. The intensity of the parameter perturbation will chosen with uniform distribution
 //    of -pi and pi
-func PerturbParameters(cortex *ng.Cortex, saturationBounds []float64) {
+func PerturbParameters(random *rand.Rand, cortex *ng.Cortex, saturationBounds []float64) {
 
 	// pick the neurons to perturb (at least one)
-	neurons := chooseNeuronsToPerturb(cortex)
+	neurons := chooseNeuronsToPerturb(random, cortex)
 
 	for _, neuron := range neurons {
 		logg.LogTo("DEBUG", "Going to perturb neuron: %v", neuron.NodeId.UUID)
-		perturbNeuron(neuron, saturationBounds)
+		perturbNeuron(random, neuron, saturationBounds)
 	}
 
 }
 
-func (shc *StochasticHillClimber) resetParametersToRandom(cortex *ng.Cortex) {
+// Perturb the candidate with PerturbParameters, or with a step size
+// mutated from the incumbent's if the hill climber self-adapts, and
+// return the candidate's step size
+func (shc *StochasticHillClimber) perturb(random *rand.Rand, candidate *ng.Cortex, stepSize float64) (candidateStepSize float64, err error) {
+
+	if shc.SelfAdaptation == nil {
+		PerturbParameters(random, candidate, shc.WeightSaturationRange)
+		return
+	}
+
+	candidateStepSize = shc.SelfAdaptation.adapt(random, stepSize, candidate)
+	if _, err = shc.SelfAdaptation.mutate(random, candidate, candidateStepSize); err != nil {
+		return
+	}
+	for _, neuron := range candidate.Neurons {
+		for _, cxn := range neuron.Inbound {
+			for k, weight := range cxn.Weights {
+				cxn.Weights[k] = saturate(weight, shc.WeightSaturationRange)
+			}
+		}
+		neuron.Bias = saturate(neuron.Bias, shc.WeightSaturationRange)
+	}
+	return
+
+}
+
+// The step size the hill climber starts from, or 0 if it doesn't self-adapt
+func (shc *StochasticHillClimber) initialStepSize() float64 {
+	if shc.SelfAdaptation == nil {
+		return 0
+	}
+	return shc.SelfAdaptation.initialStepSize()
+}
+
+func (shc *StochasticHillClimber) resetParametersToRandom(random *rand.Rand, cortex *ng.Cortex) {
 
 	neurons := cortex.Neurons
 	for _, neuronNode := range neurons {
 		for _, cxn := range neuronNode.Inbound {
-			cxn.Weights = ng.RandomWeights(len(cxn.Weights))
+			cxn.Weights = randomWeights(random, len(cxn.Weights))
 		}
-		neuronNode.Bias = ng.RandomBias()
+		neuronNode.Bias = RandomBias(random)
 	}
 
 }
 
-func chooseNeuronsToPerturb(cortex *ng.Cortex) []*ng.Neuron {
+func chooseNeuronsToPerturb(random *rand.Rand, cortex *ng.Cortex) []*ng.Neuron {
 
 	neuronsToPerturb := make([]*ng.Neuron, 0)
 
@@ -130,7 +254,7 @@ func chooseNeuronsToPerturb(cortex *ng.Cortex) []*ng.Neuron {
 		probability := nodePerturbProbability(cortex)
 		neurons := cortex.Neurons
 		for _, neuronNode := range neurons {
-			if rand.Float64() < probability {
+			if random.Float64() < probability {
 				neuronsToPerturb = append(neuronsToPerturb, neuronNode)
 				didChooseNeuron = true
 			}
@@ -151,7 +275,7 @@ func nodePerturbProbability(cortex *ng.Cortex) float64 {
 	return 1 / math.Sqrt(float64(numNeurons))
 }
 
-func perturbNeuron(neuron *ng.Neuron, saturationBounds []float64) {
+func perturbNeuron(random *rand.Rand, neuron *ng.Neuron, saturationBounds []float64) {
 
 	probability := parameterPerturbProbability(neuron)
 
@@ -159,10 +283,10 @@ func perturbNeuron(neuron *ng.Neuron, saturationBounds []float64) {
 	for {
 		didPerturbWeight := false
 		for _, cxn := range neuron.Inbound {
-			didPerturbWeight = possiblyPerturbConnection(cxn, probability, saturationBounds)
+			didPerturbWeight = possiblyPerturbConnection(random, cxn, probability, saturationBounds)
 		}
 
-		didPerturbBias := possiblyPerturbBias(neuron, probability, saturationBounds)
+		didPerturbBias := possiblyPerturbBias(random, neuron, probability, saturationBounds)
 
 		// did we perturb anything?  if so, we're done
 		if didPerturbWeight || didPerturbBias {
@@ -181,12 +305,12 @@ func parameterPerturbProbability(neuron *ng.Neuron) float64 {
 	return 1 / math.Sqrt(float64(numWeights))
 }
 
-func possiblyPerturbConnection(cxn *ng.InboundConnection, probability float64, saturationBounds []float64) bool {
+func possiblyPerturbConnection(random *rand.Rand, cxn *ng.InboundConnection, probability float64, saturationBounds []float64) bool {
 
 	didPerturb := false
 	for j, weight := range cxn.Weights {
-		if rand.Float64() < probability {
-			perturbedWeight := perturbParameter(weight, saturationBounds)
+		if random.Float64() < probability {
+			perturbedWeight := perturbParameter(random, weight, saturationBounds)
 			logg.LogTo("DEBUG", "weight %v -> %v", weight, perturbedWeight)
 			cxn.Weights[j] = perturbedWeight
 			didPerturb = true
@@ -196,11 +320,11 @@ func possiblyPerturbConnection(cxn *ng.InboundConnection, probability float64, s
 
 }
 
-func possiblyPerturbBias(neuron *ng.Neuron, probability float64, saturationBounds []float64) bool {
+func possiblyPerturbBias(random *rand.Rand, neuron *ng.Neuron, probability float64, saturationBounds []float64) bool {
 	didPerturb := false
-	if rand.Float64() < probability {
+	if random.Float64() < probability {
 		bias := neuron.Bias
-		perturbedBias := perturbParameter(bias, saturationBounds)
+		perturbedBias := perturbParameter(random, bias, saturationBounds)
 		neuron.Bias = perturbedBias
 		logg.LogTo("DEBUG", "bias %v -> %v", bias, perturbedBias)
 		didPerturb = true
@@ -208,8 +332,17 @@ func possiblyPerturbBias(neuron *ng.Neuron, probability float64, saturationBound
 	return didPerturb
 }
 
-func (shc *StochasticHillClimber) validate() {
-	if len(shc.WeightSaturationRange) == 0 {
-		logg.LogPanic("Invalid (empty) WeightSaturationRange")
+func (shc *StochasticHillClimber) validate() error {
+	if len(shc.WeightSaturationRange) < 2 {
+		return fmt.Errorf("%w: WeightSaturationRange needs a lower and upper bound, got %v", ErrInvalidConfig, shc.WeightSaturationRange)
+	}
+	return shc.evaluationPolicy().validate()
+}
+
+// The EvaluationPolicy, or a single sample per evaluation if there is none
+func (shc *StochasticHillClimber) evaluationPolicy() EvaluationPolicy {
+	if shc.EvaluationPolicy == nil {
+		return EvaluationPolicy{Samples: 1}
 	}
+	return *shc.EvaluationPolicy
 }
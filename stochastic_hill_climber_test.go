@@ -1,12 +1,16 @@
 package neurvolve
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/couchbaselabs/go.assert"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/tleyden/neurgo"
 	"log"
+	"math/rand"
 	"testing"
+	"time"
 )
 
 func DisabledTestUnmarshalCortexFitness(t *testing.T) {
@@ -39,7 +43,8 @@ func DisabledTestUnmarshalCortexFitness(t *testing.T) {
 		MaxAttempts:                10,
 	}
 	examples := ng.XnorTrainingSamples()
-	cortexTrained, succeeded := shc.TrainExamples(cortex, examples)
+	cortexTrained, succeeded, err := shc.TrainExamples(cortex, examples)
+	assert.True(t, err == nil)
 	assert.True(t, succeeded)
 
 	// verify it can now solve the training set
@@ -50,3 +55,144 @@ func DisabledTestUnmarshalCortexFitness(t *testing.T) {
 	log.Printf("Final fitness: %v", fitness)
 
 }
+
+func TestTrainContextDeadlineExceeded(t *testing.T) {
+
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           1000,
+		MaxIterationsBeforeRestart: 10,
+		MaxAttempts:                1000000,
+		WeightSaturationRange:      []float64{-10, 10},
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	cortex := SingleNeuronCortex("cortex")
+	fittest, stopReason, err := shc.TrainContext(ctx, cortex, FakeScapeBiasSum{})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonDeadlineExceeded)
+	assert.True(t, fittest == cortex)
+
+}
+
+func TestTrainInvalidWeightSaturationRange(t *testing.T) {
+	shc := &StochasticHillClimber{
+		FitnessThreshold: 1000,
+		MaxAttempts:      10,
+	}
+	_, _, err := shc.Train(SingleNeuronCortex("cortex"), FakeScapeBiasSum{})
+	assert.True(t, errors.Is(err, ErrInvalidConfig))
+}
+
+// A scape which ignores the cortex and returns its samples in turn, over
+// and over
+type FakeSequenceScape struct {
+	samples []float64
+	calls   int
+}
+
+func (scape *FakeSequenceScape) Fitness(cortex *ng.Cortex) float64 {
+	fitness := scape.samples[scape.calls%len(scape.samples)]
+	scape.calls += 1
+	return fitness
+}
+
+func (scape *FakeSequenceScape) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.Fitness(cortex)
+}
+
+// The sum of the biases, plus uniform noise of up to noise either way
+type FakeNoisyScapeBiasSum struct {
+	noise  float64
+	random *rand.Rand
+}
+
+func (scape *FakeNoisyScapeBiasSum) Fitness(cortex *ng.Cortex) float64 {
+	return FakeScapeBiasSum{}.Fitness(cortex) + randomInRange(scape.random, -scape.noise, scape.noise)
+}
+
+func (scape *FakeNoisyScapeBiasSum) FitnessAgainst(cortex *ng.Cortex, opponent *ng.Cortex) float64 {
+	return scape.Fitness(cortex) - scape.Fitness(opponent)
+}
+
+func TestTrainConfidenceLevel(t *testing.T) {
+
+	// the incumbent always scores 0, and every other candidate has a
+	// slightly higher mean, which is too noisy to be sure of
+	samples := []float64{0, 0, 0, 0, 50, -49, 50, -49}
+
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           0.25,
+		MaxIterationsBeforeRestart: 5,
+		MaxAttempts:                3,
+		WeightSaturationRange:      []float64{-10, 10},
+		EvaluationPolicy:           &EvaluationPolicy{Samples: 4},
+		Rand:                       rand.New(rand.NewSource(42)),
+	}
+
+	// without a confidence level, the first noisy candidate is accepted
+	_, stopReason, err := shc.TrainContext(context.Background(), SingleNeuronCortex("cortex"), &FakeSequenceScape{samples: samples})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonThresholdReached)
+
+	// with one, no candidate is accepted, so the threshold is never met
+	// even though the noisy candidates' fitness is above it
+	shc.EvaluationPolicy.ConfidenceLevel = 0.95
+	cortex := SingleNeuronCortex("cortex")
+	fittest, stopReason, err := shc.TrainContext(context.Background(), cortex, &FakeSequenceScape{samples: samples})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonBudgetExhausted)
+	assert.Equals(t, ng.JsonString(fittest), ng.JsonString(SingleNeuronCortex("cortex")))
+
+}
+
+func TestTrainReevaluateIncumbentAfterRestart(t *testing.T) {
+
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           1000,
+		MaxIterationsBeforeRestart: 5,
+		MaxAttempts:                3,
+		WeightSaturationRange:      []float64{-10, 10},
+		EvaluationPolicy:           &EvaluationPolicy{Samples: 3, ReevaluateIncumbent: true},
+		Rand:                       rand.New(rand.NewSource(42)),
+	}
+
+	// the bias starts at the upper bound, so only noise can make a
+	// candidate look fitter, and a restart scrambles it to well below
+	cortex := SingleNeuronCortex("cortex")
+	cortex.Neurons[0].Bias = 10
+	scape := &FakeNoisyScapeBiasSum{noise: 1e-6, random: rand.New(rand.NewSource(42))}
+
+	// candidates made from the scrambled cortex are compared against the
+	// cortex from before the restart, so none of them replace it
+	fittest, stopReason, err := shc.TrainContext(context.Background(), cortex, scape)
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonBudgetExhausted)
+	assert.True(t, fittest.Neurons[0].Bias > 10-1e-3)
+
+}
+
+func TestTrainSelfAdaptation(t *testing.T) {
+
+	selfAdaptation := NewSelfAdaptation()
+	selfAdaptation.InitialStepSize = 0.1
+
+	shc := &StochasticHillClimber{
+		FitnessThreshold:           1000,
+		MaxIterationsBeforeRestart: 200,
+		MaxAttempts:                1,
+		WeightSaturationRange:      []float64{-10, 10},
+		SelfAdaptation:             selfAdaptation,
+		Rand:                       rand.New(rand.NewSource(42)),
+	}
+
+	// the bias climbs until it is held at the upper bound, after which
+	// nothing is fitter and the hill climber gives up
+	cortex := SingleNeuronCortex("cortex")
+	fittest, stopReason, err := shc.TrainContext(context.Background(), cortex, FakeScapeBiasSum{})
+	assert.True(t, err == nil)
+	assert.Equals(t, stopReason, StopReasonBudgetExhausted)
+	assert.Equals(t, fittest.Neurons[0].Bias, 10.0)
+
+}
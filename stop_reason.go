@@ -0,0 +1,53 @@
+package neurvolve
+
+import (
+	"context"
+)
+
+// The reason a training run stopped
+type StopReason int
+
+const (
+	// A cortex reached the fitness threshold
+	StopReasonThresholdReached StopReason = iota + 1
+
+	// The trainer used up its attempts, iterations or generations
+	StopReasonBudgetExhausted
+
+	// The context passed to the trainer was cancelled
+	StopReasonCancelled
+
+	// The deadline of the context passed to the trainer expired
+	StopReasonDeadlineExceeded
+
+	// A StagnationCriterion with StopOnStagnation was met
+	StopReasonStagnated
+)
+
+func (reason StopReason) String() string {
+	switch reason {
+	case StopReasonThresholdReached:
+		return "threshold reached"
+	case StopReasonBudgetExhausted:
+		return "budget exhausted"
+	case StopReasonCancelled:
+		return "cancelled"
+	case StopReasonDeadlineExceeded:
+		return "deadline exceeded"
+	case StopReasonStagnated:
+		return "stagnated"
+	}
+	return "unknown"
+}
+
+// The stop reason for a context which is done, or 0 if it is not done yet
+func contextStopReason(ctx context.Context) StopReason {
+	switch ctx.Err() {
+	case nil:
+		return 0
+	case context.DeadlineExceeded:
+		return StopReasonDeadlineExceeded
+	default:
+		return StopReasonCancelled
+	}
+}
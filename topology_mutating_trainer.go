@@ -1,25 +1,62 @@
 package neurvolve
 
 import (
+	"context"
 	"fmt"
 	"github.com/couchbaselabs/logg"
 	ng "github.com/tleyden/neurgo"
+	"math/rand"
 )
 
 type TopologyMutatingTrainer struct {
 	MaxIterationsBeforeRestart int
 	MaxAttempts                int
 	StochasticHillClimber      *StochasticHillClimber
+
+	// Chooses the topological mutation applied before each run of the
+	// hill climber, and is told which ones led to a fitter cortex.
+	// Defaults to choosing uniformly among the non recurrent topological
+	// mutators.
+	MutationPolicy *MutationPolicy
+
+	// If set, all random choices are drawn from Rand, so that runs with
+	// the same seed are reproducible.  The hill climber draws from it too,
+	// rather than from its own Rand.
+	Rand *rand.Rand
+}
+
+func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fittestCortex *ng.Cortex, succeeded bool, err error) {
+
+	fittestCortex, stopReason, err := tmt.TrainContext(context.Background(), cortex, scape)
+	succeeded = stopReason == StopReasonThresholdReached
+	return
+
 }
 
-func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fittestCortex *ng.Cortex, succeeded bool) {
+// Same as Train, but stops at the next fitness evaluation once ctx is done.
+// Returns the fittest cortex found so far and the reason training stopped.
+func (tmt *TopologyMutatingTrainer) TrainContext(ctx context.Context, cortex *ng.Cortex, scape Scape) (fittestCortex *ng.Cortex, stopReason StopReason, err error) {
 
-	ng.SeedRandom()
+	random := randomOrNew(tmt.Rand)
 
 	shc := tmt.StochasticHillClimber
+	if shc == nil {
+		err = fmt.Errorf("%w: no StochasticHillClimber", ErrInvalidConfig)
+		return
+	}
+	if err = shc.validate(); err != nil {
+		return
+	}
 
-	includeNonTopological := false
-	mutators := CortexMutatorsNonRecurrent(includeNonTopological)
+	policy := tmt.MutationPolicy
+	if policy == nil {
+		includeNonTopological := false
+		policy = NewMutationPolicy(CortexMutatorsNonRecurrent(includeNonTopological))
+		policy.MaxAttempts = 1
+	}
+	if err = policy.validate(); err != nil {
+		return
+	}
 
 	originalCortex := cortex.Copy()
 
@@ -27,16 +64,28 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 
 	// Apply NN to problem and save fitness
 	logg.LogTo("MAIN", "Get initial fitness")
-	fitness := scape.Fitness(currentCortex)
+	fitness, err := tryFitness(scape, currentCortex)
+	if err != nil {
+		return
+	}
 	logg.LogTo("MAIN", "Initial fitness: %v", fitness)
 
+	// currentCortex is mutated in place, so keep copies of the fittest
+	fittestCortex = originalCortex
+	fittestFitness := fitness
+
 	if fitness > shc.FitnessThreshold {
-		succeeded = true
+		stopReason = StopReasonThresholdReached
 		return
 	}
 
 	for i := 0; ; i++ {
 
+		if stopReason = contextStopReason(ctx); stopReason != 0 {
+			logg.LogTo("MAIN", "Stopping topology mutating trainer: %v", stopReason)
+			break
+		}
+
 		logg.LogTo("MAIN", "Before mutate.  i/max: %d/%d", i, tmt.MaxAttempts)
 
 		// before we mutate the cortex, we need to init it,
@@ -45,17 +94,16 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 		currentCortex.Init()
 
 		// mutate the network
-		randInt := RandomIntInRange(0, len(mutators))
-		mutator := mutators[randInt]
-		ok, _ := mutator(currentCortex)
-		if !ok {
-			logg.LogTo("MAIN", "Mutate didn't work, retrying...")
+		operator, _, mutateErr := policy.apply(random, currentCortex)
+		if mutateErr != nil {
+			logg.LogTo("MAIN", "Mutate didn't work, retrying... %v", mutateErr)
 			continue
 		}
 
 		isValid := currentCortex.Validate()
 		if !isValid {
-			logg.LogPanic("Cortex did not validate")
+			err = fmt.Errorf("%w: %v after mutation", ErrInvalidCortex, currentCortex.NodeId.UUID)
+			return
 		}
 
 		filenameJson := fmt.Sprintf("cortex-%v.json", i)
@@ -67,16 +115,27 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 		logg.LogTo("MAIN", "Run stochastic hill climber..")
 
 		// memetic step: call stochastic hill climber and see if it can solve it
-		fittestCortex, succeeded = shc.Train(currentCortex, scape)
-		logg.LogTo("MAIN", "stochastic hill climber finished.  succeeded: %v", succeeded)
+		shcCortex, shcFitness, shcStopReason, shcErr := shc.train(ctx, random, currentCortex, scape)
+		if shcErr != nil {
+			err = shcErr
+			return
+		}
+		logg.LogTo("MAIN", "stochastic hill climber finished: %v", shcStopReason)
+
+		policy.credit(operator, shcFitness > fittestFitness)
+
+		if shcFitness > fittestFitness || shcStopReason == StopReasonThresholdReached {
+			fittestCortex = shcCortex.Copy()
+			fittestFitness = shcFitness
+		}
 
-		if succeeded {
-			succeeded = true
+		if shcStopReason != StopReasonBudgetExhausted {
+			stopReason = shcStopReason
 			break
 		}
 
 		if i >= tmt.MaxAttempts {
-			succeeded = false
+			stopReason = StopReasonBudgetExhausted
 			break
 		}
 
@@ -89,7 +148,8 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 				currentCortex.Repair() // TODO: remove workaround
 				isValid = currentCortex.Validate()
 				if !isValid {
-					logg.LogPanic("Cortex could not be repaired")
+					err = fmt.Errorf("%w: %v could not be repaired", ErrInvalidCortex, currentCortex.NodeId.UUID)
+					return
 				}
 			}
 
@@ -101,7 +161,7 @@ func (tmt *TopologyMutatingTrainer) Train(cortex *ng.Cortex, scape Scape) (fitte
 
 }
 
-func (tmt *TopologyMutatingTrainer) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestCortex *ng.Cortex, succeeded bool) {
+func (tmt *TopologyMutatingTrainer) TrainExamples(cortex *ng.Cortex, examples []*ng.TrainingSample) (fittestCortex *ng.Cortex, succeeded bool, err error) {
 
 	trainingSampleScape := &TrainingSampleScape{
 		examples: examples,
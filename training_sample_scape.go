@@ -13,8 +13,20 @@ func (scape TrainingSampleScape) Fitness(cortex *ng.Cortex) float64 {
 	return cortex.Fitness(scape.examples)
 }
 
+// Training samples have no notion of an opponent, so this always returns 0.
+// Trainers check SupportsFitnessAgainst and never call it.
 func (scape TrainingSampleScape) FitnessAgainst(cortex *ng.Cortex, opponentCortex *ng.Cortex) (fitness float64) {
 	// return cortex.Fitness(scape.examples) - opponentCortex.Fitness(scape.examples)
-	logg.LogPanic("Cannot calculate fitness against another cortex")
+	logg.LogTo("NEURVOLVE", "Cannot calculate fitness against another cortex")
 	return 0.0
 }
+
+func (scape TrainingSampleScape) SupportsFitnessAgainst() bool {
+	return false
+}
+
+// The training samples never change, so neither does the fitness of a
+// cortex
+func (scape TrainingSampleScape) Deterministic() bool {
+	return true
+}
@@ -1,21 +1,159 @@
 package neurvolve
 
 import (
+	"encoding/json"
+	"fmt"
 	ng "github.com/tleyden/neurgo"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
 )
 
-func randomWeights(length int) []float64 {
-	return ng.RandomWeights(length)
+// A new random source seeded from the clock, for trainers which are not
+// given a Rand.  Runs which draw from it cannot be reproduced.
+func newRandom() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
-func RandomBias() float64 {
-	return ng.RandomBias()
+// The given random source, or a new one seeded from the clock if it is nil
+func randomOrNew(random *rand.Rand) *rand.Rand {
+	if random == nil {
+		return newRandom()
+	}
+	return random
 }
 
-func RandomWeight() float64 {
-	return ng.RandomWeight()
+// The random source for a trainer run by another trainer: its own, if it
+// has one, or otherwise a new source seeded from the other trainer's, so
+// that a single seed reproduces the whole run
+func trainerRandom(random *rand.Rand, own *rand.Rand) *rand.Rand {
+	if own != nil {
+		return own
+	}
+	return rand.New(rand.NewSource(random.Int63()))
 }
 
-func RandomIntInRange(min, max int) int {
-	return ng.RandomIntInRange(min, max)
+// A random source which counts the values drawn from it, so that its
+// state can be saved as its seed and the number of draws since
+type countingSource struct {
+	source rand.Source
+	seed   int64
+	draws  int64
+}
+
+// A source with the given seed, advanced past the given number of draws
+func newCountingSource(seed int64, draws int64) *countingSource {
+	s := &countingSource{source: rand.NewSource(seed), seed: seed}
+	for s.draws < draws {
+		s.Int63()
+	}
+	return s
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws += 1
+	return s.source.Int63()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.source.Seed(seed)
+	s.seed = seed
+	s.draws = 0
+}
+
+// Unmarshal a cortex saved in json, and link its sensors, neurons and
+// actuators back to it, which json.Unmarshal on its own leaves unset, so
+// that the cortex can be run and mutated
+func unmarshalCortex(jsonBytes []byte) (cortex *ng.Cortex, err error) {
+	cortex = &ng.Cortex{}
+	if err = json.Unmarshal(jsonBytes, cortex); err != nil {
+		return
+	}
+	cortex.SetSensors(cortex.Sensors)
+	cortex.SetNeurons(cortex.Neurons)
+	cortex.SetActuators(cortex.Actuators)
+	return
+}
+
+func randomWeights(random *rand.Rand, length int) []float64 {
+	weights := make([]float64, length)
+	for i := range weights {
+		weights[i] = RandomWeight(random)
+	}
+	return weights
+}
+
+func RandomBias(random *rand.Rand) float64 {
+	return randomInRange(random, -math.Pi, math.Pi)
+}
+
+func RandomWeight(random *rand.Rand) float64 {
+	return randomInRange(random, -math.Pi, math.Pi)
+}
+
+func RandomIntInRange(random *rand.Rand, min, max int) int {
+	return min + random.Intn(max-min)
+}
+
+func randomInRange(random *rand.Rand, min, max float64) float64 {
+	return min + random.Float64()*(max-min)
+}
+
+// A new unique id, drawn from the random source so that runs with the
+// same seed produce the same ids
+func newUuid(random *rand.Rand) string {
+	return fmt.Sprintf("%016x", random.Int63())
+}
+
+// Create a new neuron in the given layer, giving it an id drawn from the
+// random source rather than the one neurgo generates.
+func createNeuronInLayer(random *rand.Rand, cortex *ng.Cortex, layerIndex float64) *ng.Neuron {
+	neuron := cortex.CreateNeuronInLayer(layerIndex)
+	neuron.NodeId.UUID = newUuid(random)
+	return neuron
+}
+
+// Choose one of the layers that contains a neuron
+func chooseRandomNeuronLayer(random *rand.Rand, cortex *ng.Cortex) float64 {
+	layerSet := make(map[float64]bool)
+	layers := make([]float64, 0)
+	for _, neuron := range cortex.Neurons {
+		layerIndex := neuron.NodeId.LayerIndex
+		if !layerSet[layerIndex] {
+			layerSet[layerIndex] = true
+			layers = append(layers, layerIndex)
+		}
+	}
+	sort.Float64s(layers)
+	return layers[RandomIntInRange(random, 0, len(layers))]
+}
+
+// Choose a node in any layer before the given one, or nil if there are none
+func chooseNodeIdPrecedingLayer(random *rand.Rand, layerMap ng.LayerToNodeIdMap, layerIndex float64) *ng.NodeId {
+	candidates := make([]*ng.NodeId, 0)
+	for _, key := range layerMap.Keys() {
+		if key < layerIndex {
+			candidates = append(candidates, layerMap[key]...)
+		}
+	}
+	return chooseRandomNodeId(random, candidates)
+}
+
+// Choose a node in any layer after the given one, or nil if there are none
+func chooseNodeIdFollowingLayer(random *rand.Rand, layerMap ng.LayerToNodeIdMap, layerIndex float64) *ng.NodeId {
+	candidates := make([]*ng.NodeId, 0)
+	for _, key := range layerMap.Keys() {
+		if key > layerIndex {
+			candidates = append(candidates, layerMap[key]...)
+		}
+	}
+	return chooseRandomNodeId(random, candidates)
+}
+
+func chooseRandomNodeId(random *rand.Rand, nodeIds []*ng.NodeId) *ng.NodeId {
+	if len(nodeIds) == 0 {
+		return nil
+	}
+	return nodeIds[RandomIntInRange(random, 0, len(nodeIds))]
 }